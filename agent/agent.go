@@ -0,0 +1,471 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// agent keeps the membership of the node in a network in sync
+// with the controller
+type agent struct {
+	// client talks to the controller of the current sync
+	client      proto.WireguardServiceClient
+	controllers *controllerPool
+	controller  string
+	dp          dataplane
+	keys        *keyManager
+	network     string
+	iface       string
+	bridge      bool
+	port        int
+	publicIP    string
+	netns       string
+	routes      []string
+	tags        []string
+	store       *stateStore
+	state       NetworkState
+	// exitNode is the node the internet traffic goes through, if any
+	exitNode          string
+	advertiseExitNode bool
+	resolver          *resolver
+	// dns answers the queries on dnsAddress, hostDNS is what the
+	// resolver of the host was pointed to
+	dns        io.Closer
+	dnsAddress string
+	hostDNS    string
+	// dhcp serves the addresses of the pool on the bridge, if enabled
+	dhcpEnabled bool
+	dhcp        io.Closer
+	dhcpServer  *dhcpServer
+	// poolRange is the lease range the pool was made for
+	poolRange string
+	// connected is true if the last sync reached the controller
+	connected bool
+
+	// statusLock protects what the status API reads
+	statusLock sync.Mutex
+	syncs      syncStatus
+	lease      *proto.Lease
+	config     *proto.ConfigurationResponse
+	// pool hands out the addresses of the bridge
+	pool *addressPool
+}
+
+// log returns a logger for the membership
+func (a *agent) log() *logrus.Entry {
+	return logrus.WithField("network", a.network)
+}
+
+// saveState saves the state of the membership, if the agent has a store
+func (a *agent) saveState() error {
+	if a.store == nil {
+		return nil
+	}
+	return a.store.update(a.network, a.state)
+}
+
+func (a *agent) bridgeName() string {
+	return fmt.Sprintf("br-%s", a.iface)
+}
+
+// reconcile renews the lease, fetches the configuration of the network and
+// applies it to the host
+func (a *agent) reconcile() error {
+	var publicInfo *proto.PublicPeer
+	if a.publicIP != "" {
+		publicInfo = &proto.PublicPeer{
+			Address: a.publicIP,
+			Port:    int32(a.port),
+		}
+	}
+
+	a.connected = false
+	lease, err := getOrRenewLease(a.client, a.network, a.keys.key(a.network).PublicKey().String(), publicInfo, a.advertisedRoutes(), a.tags, &a.state)
+	if err != nil {
+		a.log().WithError(err).Error("Could not renew lease")
+		return err
+	}
+
+	lease, err = a.rotateKey(lease)
+	if err != nil {
+		a.log().WithError(err).Warning("Could not rotate the key of the lease, will try again on the next sync")
+	}
+
+	err = a.saveState()
+	if err != nil {
+		a.log().WithError(err).Error("Could not save the state")
+		return err
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+	config, err := a.client.FetchConfiguration(ctx, &proto.ConfigurationRequest{
+		NetworkName: lease.Network,
+		LeaseUuid:   lease.Uuid,
+	})
+	if err != nil {
+		a.log().WithError(err).Error("Could not fetch configuration")
+		return err
+	}
+
+	a.connected = true
+	a.state.Lease = lease
+	a.state.Configuration = config
+	err = a.saveState()
+	if err != nil {
+		a.log().WithError(err).Warning("Could not cache the configuration")
+	}
+
+	err = a.apply(lease, config)
+	if err != nil {
+		return err
+	}
+
+	// The next range is configured now, the controller can cut over
+	// once every lease says so
+	if lease.NextIpRange != "" {
+		err = acknowledgeRenumber(a.client, lease)
+		if err != nil {
+			a.log().WithError(err).Warningf("Could not acknowledge the next range %s, will try again on the next sync", lease.NextIpRange)
+		}
+	}
+
+	err = a.reportStatus(lease)
+	if err != nil {
+		a.log().WithError(err).Warning("Could not report the status of the peers")
+	}
+
+	return nil
+}
+
+// applyCached applies the last configuration we got from the controller,
+// so that the mesh comes up at boot even if the controller cannot be reached
+func (a *agent) applyCached() {
+	if a.state.Lease == nil || a.state.Configuration == nil {
+		return
+	}
+
+	a.log().Infof("Applying the cached configuration of lease %s", a.state.Lease.Uuid)
+	a.switchKey(a.state.Lease)
+	a.statusLock.Lock()
+	a.lease = a.state.Lease
+	a.config = a.state.Configuration
+	a.statusLock.Unlock()
+
+	err := a.apply(a.state.Lease, a.state.Configuration)
+	if err != nil {
+		a.log().WithError(err).Warning("Could not apply the cached configuration")
+	}
+}
+
+// apply configures the interfaces of the host for the lease
+func (a *agent) apply(lease *proto.Lease, config *proto.ConfigurationResponse) error {
+	err := a.dp.EnsureInterface(a.iface, int(config.Network.GetSettings().GetMtu()))
+	if err != nil {
+		a.log().WithError(err).Errorf("Could not ensure the interface %s", a.iface)
+		return err
+	}
+
+	if a.bridge {
+		err = a.dp.EnsureBridge(a.bridgeName())
+		if err != nil {
+			a.log().WithError(err).Errorf("Could not ensure the bridge %s", a.bridgeName())
+			return err
+		}
+	}
+
+	addresses, routes, err := interfaceAddresses(lease, config)
+	if err != nil {
+		return err
+	}
+
+	err = a.dp.EnsureAddresses(a.iface, addresses)
+	if err != nil {
+		a.log().WithError(err).Errorf("Could not configure interface %s with its addresses", a.iface)
+		return err
+	}
+
+	err = a.dp.EnsureRoutes(a.iface, routes)
+	if err != nil {
+		a.log().WithError(err).Errorf("Could not configure interface %s with the wireguard routes", a.iface)
+		return err
+	}
+
+	if a.bridge {
+		addresses, err := bridgeAddresses(lease)
+		if err == nil {
+			err = a.dp.EnsureAddresses(a.bridgeName(), addresses)
+		}
+		if err != nil {
+			a.log().WithError(err).Warningf("Could not configure bridge %s", a.bridgeName())
+		}
+
+		err = a.ensurePool(lease)
+		if err != nil {
+			a.log().WithError(err).Errorf("Could not hand out the addresses of the bridge %s", a.bridgeName())
+			return err
+		}
+		if a.dhcpEnabled {
+			err = a.ensureDHCP(lease, config)
+			if err != nil {
+				a.log().WithError(err).Errorf("Could not serve DHCP on the bridge %s", a.bridgeName())
+				return err
+			}
+		}
+	}
+
+	listenPort := a.port
+	if listenPort == 0 {
+		listenPort = int(config.Network.GetSettings().GetListenPort())
+	}
+
+	// Stop routing through the exit node before the tunnel loses its
+	// mark, otherwise its own packets would loop
+	exitNode := a.availableExitNode(config)
+	if a.exitNode != "" && exitNode == "" {
+		err = a.dp.EnsureExitRoutes(a.iface, false)
+		if err != nil {
+			a.log().WithError(err).Error("Could not remove the routes through the exit node")
+			return err
+		}
+	}
+
+	err = configureWireguardInterface(a.dp, a.iface, a.keys.key(a.network), listenPort, exitNode, config)
+	if err != nil {
+		a.log().WithError(err).Error("Could not apply wireguard configuration")
+		return err
+	}
+
+	if exitNode != "" {
+		err = a.dp.EnsureExitRoutes(a.iface, true)
+		if err != nil {
+			a.log().WithError(err).Errorf("Could not route the traffic through the exit node %s", exitNode)
+			return err
+		}
+	}
+
+	if a.advertiseExitNode {
+		ranges, err := networkRanges(config)
+		if err == nil {
+			err = a.dp.EnsureMasquerade(a.iface, ranges, true)
+		}
+		if err != nil {
+			a.log().WithError(err).Error("Could not masquerade the traffic of the peers")
+			return err
+		}
+	}
+
+	err = a.ensureDNS(lease, config)
+	if err != nil {
+		a.log().WithError(err).Error("Could not answer the names of the nodes")
+		return err
+	}
+
+	return nil
+}
+
+// advertisedRoutes returns the routes of the node, with the default
+// routes if it is an exit node
+func (a *agent) advertisedRoutes() []string {
+	if a.advertiseExitNode {
+		return append(append([]string{}, a.routes...), defaultRoutes...)
+	}
+	return a.routes
+}
+
+// availableExitNode returns the exit node the membership uses if the
+// controller approved it as one, or nothing
+func (a *agent) availableExitNode(config *proto.ConfigurationResponse) string {
+	if a.exitNode == "" {
+		return ""
+	}
+
+	for _, endpoint := range config.Network.GetEndpoints() {
+		if endpoint.NodeName == a.exitNode && endpoint.ExitNode {
+			return a.exitNode
+		}
+	}
+
+	a.log().Warningf("Node %s is not an approved exit node, the internet traffic does not go through it", a.exitNode)
+	return ""
+}
+
+// shutdown releases the lease and removes the interfaces the agent
+// created, unless we were asked to keep them
+func (a *agent) shutdown(keep bool) {
+	// Nothing answers on the address once the agent is gone
+	a.stopDNS()
+	a.stopDHCP()
+
+	if keep {
+		a.log().Info("Keeping the lease and the interfaces")
+	} else {
+		client, _, err := a.controllers.get()
+		if err == nil {
+			err = releaseLease(client, &a.state)
+		}
+		if err != nil {
+			a.log().WithError(err).Warning("Could not release the lease, it will expire on its own")
+		}
+
+		if a.exitNode != "" {
+			err = a.dp.EnsureExitRoutes(a.iface, false)
+			if err != nil {
+				a.log().WithError(err).Warning("Could not remove the routes through the exit node")
+			}
+		}
+		if a.advertiseExitNode && a.state.Configuration != nil {
+			ranges, err := networkRanges(a.state.Configuration)
+			if err == nil {
+				err = a.dp.EnsureMasquerade(a.iface, ranges, false)
+			}
+			if err != nil {
+				a.log().WithError(err).Warning("Could not stop masquerading the traffic of the peers")
+			}
+		}
+
+		if a.bridge {
+			err = a.dp.RemoveInterface(a.bridgeName())
+			if err != nil {
+				a.log().WithError(err).Warningf("Could not remove the bridge %s", a.bridgeName())
+			}
+		}
+
+		err = a.dp.RemoveInterface(a.iface)
+		if err != nil {
+			a.log().WithError(err).Warningf("Could not remove the interface %s", a.iface)
+		}
+	}
+
+	err := a.saveState()
+	if err != nil {
+		a.log().WithError(err).Error("Could not save the state")
+	}
+}
+
+// leaseRanges returns the ranges allocated to the lease, there are two
+// of them while the network is being renumbered
+func leaseRanges(lease *proto.Lease) ([]*net.IPNet, error) {
+	ranges := []string{lease.IpRange}
+	if lease.NextIpRange != "" {
+		ranges = append(ranges, lease.NextIpRange)
+	}
+
+	var result []*net.IPNet
+	for _, r := range ranges {
+		_, network, err := net.ParseCIDR(r)
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not parse lease address %s", r)
+			return nil, err
+		}
+		result = append(result, network)
+	}
+
+	return result, nil
+}
+
+// networkRanges returns the ranges of the network, there are two of them
+// while it is being renumbered
+func networkRanges(config *proto.ConfigurationResponse) ([]*net.IPNet, error) {
+	wgAddresses := []string{config.Network.Address}
+	if config.Network.NextAddress != "" {
+		wgAddresses = append(wgAddresses, config.Network.NextAddress)
+	}
+
+	var wgNetworks []*net.IPNet
+	for _, address := range wgAddresses {
+		_, wgNetwork, err := net.ParseCIDR(address)
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not parse wireguard network address %s", address)
+			return nil, err
+		}
+		wgNetworks = append(wgNetworks, wgNetwork)
+	}
+
+	return wgNetworks, nil
+}
+
+// interfaceAddresses returns the addresses of the wireguard interface, and
+// the routes that go through it
+func interfaceAddresses(lease *proto.Lease, config *proto.ConfigurationResponse) ([]*net.IPNet, []*net.IPNet, error) {
+	selfNetworks, err := leaseRanges(lease)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wgNetworks, err := networkRanges(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The routes the peers advertise go through the interface as well,
+	// the ones we advertise are reachable from here already
+	routes := wgNetworks
+	for _, endpoint := range config.Network.Endpoints {
+		if ownEndpoint(lease, endpoint) {
+			continue
+		}
+		for _, nw := range endpoint.Networks {
+			_, route, err := net.ParseCIDR(nw)
+			if err != nil || containedIn(route, wgNetworks) {
+				continue
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	for _, selfNetwork := range selfNetworks {
+		selfNetwork.Mask = net.IPv4Mask(255, 255, 255, 255)
+	}
+
+	return selfNetworks, routes, nil
+}
+
+// ownEndpoint tells if the endpoint is the one of the lease
+func ownEndpoint(lease *proto.Lease, endpoint *proto.Endpoint) bool {
+	for _, nw := range endpoint.Networks {
+		if nw == lease.IpRange {
+			return true
+		}
+	}
+	return false
+}
+
+// containedIn tells if the prefix is inside one of the networks
+func containedIn(prefix *net.IPNet, networks []*net.IPNet) bool {
+	ones, _ := prefix.Mask.Size()
+	for _, network := range networks {
+		networkOnes, _ := network.Mask.Size()
+		if network.Contains(prefix.IP) && networkOnes <= ones {
+			return true
+		}
+	}
+	return false
+}
+
+// bridgeAddresses returns the addresses of the bridge, the second
+// address of each range of the lease
+func bridgeAddresses(lease *proto.Lease) ([]*net.IPNet, error) {
+	selfNetworks, err := leaseRanges(lease)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []*net.IPNet
+	for _, selfNetwork := range selfNetworks {
+		if ones, _ := selfNetwork.Mask.Size(); ones > 31 {
+			logrus.Warningf("Cannot assign an IP address to the bridge for network %s, network is too small", selfNetwork.String())
+			continue
+		}
+		selfNetwork.IP[3]++
+		addresses = append(addresses, selfNetwork)
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// fakeController hands out a single lease and serves the endpoints it is
+// given as the configuration of network lab. The calls the agent does not
+// make during a sync are left to the embedded nil interface.
+type fakeController struct {
+	proto.WireguardServiceClient
+	lease     *proto.Lease
+	endpoints []*proto.Endpoint
+	released  bool
+}
+
+func (c *fakeController) AcquireLease(ctx context.Context, in *proto.AcquireLeaseRequest, opts ...grpc.CallOption) (*proto.AcquireLeaseResponse, error) {
+	c.lease = &proto.Lease{
+		Uuid:      "lease-self",
+		Network:   in.NetworkName,
+		IpRange:   "10.60.0.0/20",
+		PublicKey: in.PublicKey,
+		NodeName:  in.NodeName,
+	}
+	c.released = false
+	return &proto.AcquireLeaseResponse{Lease: c.lease}, nil
+}
+
+func (c *fakeController) RenewLease(ctx context.Context, in *proto.RenewLeaseRequest, opts ...grpc.CallOption) (*proto.RenewLeaseResponse, error) {
+	if c.lease == nil || c.lease.Uuid != in.Uuid {
+		return &proto.RenewLeaseResponse{Lease: &proto.Lease{Uuid: in.Uuid, Expired: true}}, nil
+	}
+	return &proto.RenewLeaseResponse{Lease: c.lease}, nil
+}
+
+func (c *fakeController) ReleaseLease(ctx context.Context, in *proto.ReleaseLeaseRequest, opts ...grpc.CallOption) (*proto.ReleaseLeaseResponse, error) {
+	if c.lease == nil || c.lease.Uuid != in.Uuid {
+		return nil, fmt.Errorf("no lease %s", in.Uuid)
+	}
+	c.lease = nil
+	c.released = true
+	return &proto.ReleaseLeaseResponse{Uuid: in.Uuid}, nil
+}
+
+func (c *fakeController) FetchConfiguration(ctx context.Context, in *proto.ConfigurationRequest, opts ...grpc.CallOption) (*proto.ConfigurationResponse, error) {
+	self := &proto.Endpoint{
+		NodeName:  c.lease.NodeName,
+		PublicKey: c.lease.PublicKey,
+		Networks:  []string{c.lease.IpRange},
+	}
+	return testConfig(append([]*proto.Endpoint{self}, c.endpoints...)...), nil
+}
+
+func (c *fakeController) ReportStatus(ctx context.Context, in *proto.ReportStatusRequest, opts ...grpc.CallOption) (*proto.ReportStatusResponse, error) {
+	return &proto.ReportStatusResponse{}, nil
+}
+
+// newTestMembership returns an agent synced through a fake controller
+func newTestMembership(t *testing.T) (*agent, *fakeDataplane, *fakeController) {
+	a, dp := newTestAgent(t)
+	controller := &fakeController{}
+	a.client = controller
+	a.controllers = &controllerPool{
+		addresses: []string{"fake"},
+		clients:   []proto.WireguardServiceClient{controller},
+	}
+	return a, dp, controller
+}
+
+// peerKeys returns the public keys of the peers of the device
+func peerKeys(t *testing.T, dp *fakeDataplane, name string) map[string]bool {
+	t.Helper()
+	device, err := dp.Device(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]bool)
+	for _, peer := range device.Peers {
+		keys[peer.PublicKey.String()] = true
+	}
+	return keys
+}
+
+func TestReconcileJoins(t *testing.T) {
+	a, dp, controller := newTestMembership(t)
+	peer := testEndpoint(t, "peer1", "10.60.16.0/20", "192.0.2.1")
+	controller.endpoints = []*proto.Endpoint{peer}
+
+	err := a.reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.state.LeaseUUID != "lease-self" {
+		t.Errorf("expected the membership to hold lease-self, got %q", a.state.LeaseUUID)
+	}
+	link, ok := dp.links["wg-test"]
+	if !ok {
+		t.Fatal("expected the wireguard interface to be created")
+	}
+	if link.mtu != 1380 {
+		t.Errorf("expected the MTU of the network, got %d", link.mtu)
+	}
+	if len(link.addresses) != 1 || link.addresses[0].String() != "10.60.0.0/32" {
+		t.Errorf("expected the address of the lease, got %v", link.addresses)
+	}
+	if len(link.routes) != 1 || link.routes[0].String() != "10.60.0.0/16" {
+		t.Errorf("expected the route of the network, got %v", link.routes)
+	}
+
+	device := dp.devices["wg-test"]
+	if device.PrivateKey != a.keys.key("lab") || device.ListenPort != 51820 {
+		t.Errorf("expected the key and the port of the membership, got %s and %d", device.PublicKey, device.ListenPort)
+	}
+	keys := peerKeys(t, dp, "wg-test")
+	if len(keys) != 1 || !keys[peer.PublicKey] {
+		t.Errorf("expected peer1 only, got %v", keys)
+	}
+}
+
+func TestReconcileAddsAndRemovesPeers(t *testing.T) {
+	a, dp, controller := newTestMembership(t)
+	peer1 := testEndpoint(t, "peer1", "10.60.16.0/20", "192.0.2.1")
+	peer2 := testEndpoint(t, "peer2", "10.60.32.0/20", "")
+	controller.endpoints = []*proto.Endpoint{peer1}
+
+	err := a.reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller.endpoints = []*proto.Endpoint{peer1, peer2}
+	err = a.reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := peerKeys(t, dp, "wg-test")
+	if len(keys) != 2 || !keys[peer1.PublicKey] || !keys[peer2.PublicKey] {
+		t.Fatalf("expected peer1 and peer2, got %v", keys)
+	}
+	for _, p := range dp.devices["wg-test"].Peers {
+		if p.PublicKey.String() == peer2.PublicKey && (len(p.AllowedIPs) != 1 || p.AllowedIPs[0].String() != "10.60.32.0/20") {
+			t.Errorf("expected peer2 to get its range, got %v", p.AllowedIPs)
+		}
+	}
+
+	controller.endpoints = []*proto.Endpoint{peer2}
+	err = a.reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = peerKeys(t, dp, "wg-test")
+	if len(keys) != 1 || !keys[peer2.PublicKey] {
+		t.Fatalf("expected peer2 only, got %v", keys)
+	}
+}
+
+func TestShutdownCleansUp(t *testing.T) {
+	a, dp, controller := newTestMembership(t)
+	a.bridge = true
+	controller.endpoints = []*proto.Endpoint{testEndpoint(t, "peer1", "10.60.16.0/20", "192.0.2.1")}
+
+	err := a.reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dp.links[a.bridgeName()]; !ok {
+		t.Fatal("expected the bridge to be created")
+	}
+
+	a.shutdown(false)
+	if !controller.released {
+		t.Error("expected the lease to be released")
+	}
+	if a.state.LeaseUUID != "" || a.state.Lease != nil {
+		t.Errorf("expected the state to forget the lease, got %+v", a.state)
+	}
+	if len(dp.links) != 0 || len(dp.devices) != 0 {
+		t.Errorf("expected every interface to be removed, got %v", dp.links)
+	}
+}
+
+func TestShutdownKeeps(t *testing.T) {
+	a, dp, controller := newTestMembership(t)
+
+	err := a.reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.shutdown(true)
+	if controller.released {
+		t.Error("expected the lease to be kept")
+	}
+	if _, ok := dp.links["wg-test"]; !ok {
+		t.Error("expected the interface to be kept")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff computes how long to wait after a failure, the delay doubles
+// with each consecutive failure up to max, and half of it is random so
+// that agents failing together do not retry together
+type backoff struct {
+	min      time.Duration
+	max      time.Duration
+	failures int
+}
+
+func (b *backoff) next() time.Duration {
+	d := b.min
+	for i := 0; i < b.failures && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	b.failures++
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (b *backoff) reset() {
+	b.failures = 0
+}
@@ -2,32 +2,107 @@ package main
 
 import (
 	"context"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/thomas-maurice/wgnw/common"
 	"github.com/thomas-maurice/wgnw/proto"
 )
 
-func getClient() (proto.WireguardServiceClient, error) {
+func getClient(addr string) (proto.WireguardServiceClient, error) {
 	if useTLS {
 		tlsConfig, err := common.GetTLSConfig(caCert, certFile, certKeyFile, insecureSkipVerify)
 
 		if err != nil {
 			logrus.WithError(err).Fatal("Could not setup TLS listener")
 		}
-		return common.GetClient(svcAddr, !useTLS, tlsConfig)
+		return common.GetClient(addr, useTLS, tlsConfig)
 	} else {
-		return common.GetClient(svcAddr, useTLS, nil)
+		return common.GetClient(addr, useTLS, nil)
 	}
 }
 
-func getContext() context.Context {
-	ctx := context.Background()
+// callTimeout bounds every call to the controller, so that a controller that
+// accepts connections but does not answer does not hang the sync
+const callTimeout = 30 * time.Second
+
+func getContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
 
 	return metadata.NewOutgoingContext(
 		ctx,
-		metadata.Pairs("auth-token", authToken),
-	)
+		metadata.Pairs("auth-token", currentAuthToken()),
+	), cancel
+}
+
+// controllerPool hands out a client for the current controller, and moves
+// on to the next one when the current one cannot be reached
+type controllerPool struct {
+	lock      sync.Mutex
+	addresses []string
+	clients   []proto.WireguardServiceClient
+	current   int
+}
+
+// newControllerPool takes a comma separated list of addresses
+func newControllerPool(addresses string) *controllerPool {
+	var addrs []string
+	for _, addr := range strings.Split(addresses, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return &controllerPool{
+		addresses: addrs,
+		clients:   make([]proto.WireguardServiceClient, len(addrs)),
+	}
+}
+
+// get returns a client for the current controller, and its address
+func (p *controllerPool) get() (proto.WireguardServiceClient, string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	addr := p.addresses[p.current]
+	if p.clients[p.current] == nil {
+		client, err := getClient(addr)
+		if err != nil {
+			p.next(addr)
+			return nil, addr, err
+		}
+		p.clients[p.current] = client
+	}
+
+	return p.clients[p.current], addr, nil
+}
+
+// failed moves on to the next controller if the error means that the
+// controller at addr could not be reached
+func (p *controllerPool) failed(addr string, err error) {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+	default:
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.next(addr)
+}
+
+func (p *controllerPool) next(addr string) {
+	// Another membership may already have moved on
+	if len(p.addresses) < 2 || p.addresses[p.current] != addr {
+		return
+	}
+
+	p.current = (p.current + 1) % len(p.addresses)
+	logrus.Warningf("Controller %s is unreachable, switching to %s", addr, p.addresses[p.current])
 }
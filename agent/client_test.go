@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// selfSignedCert returns a certificate for 127.0.0.1, and the path of its
+// PEM file to use as the CA
+func selfSignedCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "wgnw"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+// serveController serves a controller that implements nothing, with TLS if
+// a configuration is given, and returns its address
+func serveController(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opts []grpc.ServerOption
+	if config != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	srv := grpc.NewServer(opts...)
+	proto.RegisterWireguardServiceServer(srv, &proto.UnimplementedWireguardServiceServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// checkReachable makes a call to the controller, an unimplemented method
+// means the connection itself went through
+func checkReachable(t *testing.T, addr string) {
+	t.Helper()
+	client, err := getClient(addr)
+	if err != nil {
+		t.Fatalf("could not dial %s: %s", addr, err)
+	}
+	ctx, cancel := getContext()
+	defer cancel()
+	_, err = client.ListNetworks(ctx, &empty.Empty{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected the controller to be reached, got %v", err)
+	}
+}
+
+func TestGetClient(t *testing.T) {
+	defer func(enabled bool, ca string) { useTLS, caCert = enabled, ca }(useTLS, caCert)
+
+	t.Run("plain", func(t *testing.T) {
+		useTLS, caCert = false, ""
+		checkReachable(t, serveController(t, nil))
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		cert, caFile := selfSignedCert(t)
+		useTLS, caCert = true, caFile
+		checkReachable(t, serveController(t, &tls.Config{Certificates: []tls.Certificate{cert}}))
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/thomas-maurice/wgnw/common"
+)
+
+// reloadableFlags are the settings that are re-read on SIGHUP,
+// changing the others requires a restart
+var reloadableFlags = []string{"auth-token", "log-level", "interval", "keep-on-exit"}
+
+// configLock protects the reloadable settings
+var configLock sync.RWMutex
+
+// fileConfig holds the settings of the configuration file that are not flags
+type fileConfig struct {
+	Networks []membership `yaml:"networks"`
+}
+
+// loadConfig applies the configuration file and the environment to the flags
+func loadConfig(config *common.Config) (*fileConfig, error) {
+	var fc fileConfig
+	err := config.Load(&fc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fc, applyLogLevel()
+}
+
+// reloadConfig re-reads the reloadable settings
+func reloadConfig(config *common.Config) error {
+	configLock.Lock()
+	err := config.Load(&fileConfig{}, reloadableFlags...)
+	configLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Reloaded %v", reloadableFlags)
+	return applyLogLevel()
+}
+
+func applyLogLevel() error {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	level, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(level)
+	return nil
+}
+
+func currentAuthToken() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return authToken
+}
+
+func syncInterval() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return interval
+}
+
+func keepInterfaces() bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return keepOnExit
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/krolaw/dhcp4"
+	"github.com/krolaw/dhcp4/conn"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/common"
+)
+
+// dataplane is everything the agent needs to configure the host. The kernel
+// implementation talks to netlink and wgctrl, the fake one keeps the desired
+// state in memory so that the agent logic can run without privileges.
+type dataplane interface {
+	// EnsureSysctl enables forwarding on the host
+	EnsureSysctl() error
+	// EnsureInterface makes sure the wireguard interface exists and is up
+	EnsureInterface(name string, mtu int) error
+	// EnsureBridge makes sure the bridge exists and is up
+	EnsureBridge(name string) error
+	// RemoveInterface removes the interface, along with its routes
+	RemoveInterface(name string) error
+	// EnsureAddresses makes sure the interface has exactly the given addresses
+	EnsureAddresses(name string, addresses []*net.IPNet) error
+	// EnsureRoutes makes sure exactly the given routes go through the interface
+	EnsureRoutes(name string, routes []*net.IPNet) error
+	// EnsureExitRoutes sends everything but the traffic of the tunnel itself
+	// through the interface, using policy routing, or stops doing it
+	EnsureExitRoutes(name string, enabled bool) error
+	// EnsureMasquerade masquerades the traffic of the sources leaving the
+	// host through its default route, or stops doing it
+	EnsureMasquerade(name string, sources []*net.IPNet, enabled bool) error
+	// ServeDNS answers DNS queries on the address until the closer is closed
+	ServeDNS(address string, handler dns.Handler) (io.Closer, error)
+	// ServeDHCP answers DHCP requests on the interface until the closer is closed
+	ServeDHCP(name string, handler dhcp4.Handler) (io.Closer, error)
+	// Device returns the wireguard configuration of the interface
+	Device(name string) (*wgtypes.Device, error)
+	// ConfigureDevice applies a wireguard configuration to the interface
+	ConfigureDevice(name string, config wgtypes.Config) error
+	Close() error
+}
+
+// kernelDataplane configures the host through netlink and wgctrl. The
+// wireguard interfaces are kernel links, or userspace devices if the mode
+// asks for it or if the kernel module is missing in auto mode.
+//
+// If a namespace is given the wireguard interfaces are created in the
+// namespace of the agent, so that their UDP sockets stay on the host, and
+// moved to the namespace where everything else is configured.
+type kernelDataplane struct {
+	wg   *wgctrl.Client
+	mode string
+
+	namespace string
+	netns     netns.NsHandle
+	// host is the namespace of the agent, nl the namespace of the interfaces
+	host *netlink.Handle
+	nl   *netlink.Handle
+
+	lock      sync.Mutex
+	userspace map[string]*userspaceDevice
+}
+
+func newKernelDataplane(mode string, namespace string) (*kernelDataplane, error) {
+	switch mode {
+	case wireguardModeAuto, wireguardModeKernel, wireguardModeUserspace:
+	default:
+		return nil, fmt.Errorf("unknown wireguard mode %s, should be auto, kernel or userspace", mode)
+	}
+
+	k := &kernelDataplane{
+		mode:      mode,
+		namespace: namespace,
+		netns:     netns.None(),
+		userspace: make(map[string]*userspaceDevice),
+	}
+
+	var err error
+	k.host, err = netlink.NewHandle()
+	if err != nil {
+		return nil, err
+	}
+	k.nl = k.host
+
+	if namespace != "" {
+		k.netns, err = openNetns(namespace)
+		if err != nil {
+			k.Close()
+			return nil, fmt.Errorf("could not open namespace %s: %s", namespace, err)
+		}
+
+		k.nl, err = netlink.NewHandleAt(k.netns)
+		if err != nil {
+			k.Close()
+			return nil, err
+		}
+	}
+
+	// The wireguard netlink socket has to live where the interfaces are
+	err = inNetns(k.netns, func() error {
+		k.wg, err = wgctrl.New()
+		return err
+	})
+	if err != nil {
+		k.Close()
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// openNetns opens a namespace by path, or by name from /var/run/netns
+func openNetns(namespace string) (netns.NsHandle, error) {
+	if strings.Contains(namespace, "/") {
+		return netns.GetFromPath(namespace)
+	}
+	return netns.GetFromName(namespace)
+}
+
+// inNetns runs fn with the current thread in the namespace, if there is one
+func inNetns(ns netns.NsHandle, fn func() error) error {
+	if !ns.IsOpen() {
+		return fn()
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		return err
+	}
+	defer origin.Close()
+
+	err = netns.Set(ns)
+	if err != nil {
+		return err
+	}
+	defer netns.Set(origin)
+
+	return fn()
+}
+
+func (k *kernelDataplane) namespaced() bool {
+	return k.netns.IsOpen()
+}
+
+func (k *kernelDataplane) EnsureSysctl() error {
+	return inNetns(k.netns, ensureSysctl)
+}
+
+func (k *kernelDataplane) EnsureInterface(name string, mtu int) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.mode == wireguardModeUserspace || k.userspace[name] != nil {
+		return k.ensureUserspaceInterface(name, mtu)
+	}
+
+	err := k.ensureKernelInterface(name, mtu)
+	if k.mode == wireguardModeAuto && errors.Is(err, syscall.EOPNOTSUPP) {
+		logrus.Warningf("The kernel does not support wireguard, falling back to a userspace device for %s", name)
+		return k.ensureUserspaceInterface(name, mtu)
+	}
+	return err
+}
+
+// ensureKernelInterface makes sure the kernel wireguard link exists
+// in the namespace of the interfaces
+func (k *kernelDataplane) ensureKernelInterface(name string, mtu int) error {
+	if !k.namespaced() {
+		return ensureInterface(k.host, name, mtu)
+	}
+
+	link, _ := k.nl.LinkByName(name)
+	if link != nil && link.Type() == "wireguard" {
+		return setLinkUp(k.nl, name, mtu)
+	}
+
+	err := removeInterface(k.nl, name)
+	if err != nil {
+		return err
+	}
+
+	err = ensureInterface(k.host, name, mtu)
+	if err != nil {
+		return err
+	}
+
+	return k.moveToNetns(name, mtu)
+}
+
+// ensureUserspaceInterface makes sure the userspace device of the
+// interface runs, and that its TUN interface is up
+func (k *kernelDataplane) ensureUserspaceInterface(name string, mtu int) error {
+	if k.userspace[name] == nil {
+		// Whatever has the name is not ours, a kernel link or a leftover
+		err := removeInterface(k.nl, name)
+		if err == nil && k.namespaced() {
+			err = removeInterface(k.host, name)
+		}
+		if err != nil {
+			return err
+		}
+
+		dev, err := newUserspaceDevice(name, mtu)
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not create userspace interface %s", name)
+			return err
+		}
+		k.userspace[name] = dev
+
+		if k.namespaced() {
+			// Bring it up on the host first so that the device starts
+			err = setLinkUp(k.host, name, mtu)
+			if err != nil {
+				return err
+			}
+			return k.moveToNetns(name, mtu)
+		}
+	}
+
+	return setLinkUp(k.nl, name, mtu)
+}
+
+// moveToNetns moves the interface from the host to the namespace and
+// brings it back up there
+func (k *kernelDataplane) moveToNetns(name string, mtu int) error {
+	link, err := k.host.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	err = k.host.LinkSetNsFd(link, int(k.netns))
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not move %s to namespace %s", name, k.namespace)
+		return err
+	}
+
+	logrus.Infof("Moved %s to namespace %s", name, k.namespace)
+	return setLinkUp(k.nl, name, mtu)
+}
+
+func (k *kernelDataplane) EnsureBridge(name string) error {
+	return ensureBridge(k.nl, name)
+}
+
+func (k *kernelDataplane) RemoveInterface(name string) error {
+	k.lock.Lock()
+	if dev := k.userspace[name]; dev != nil {
+		dev.Close()
+		delete(k.userspace, name)
+	}
+	k.lock.Unlock()
+
+	return removeInterface(k.nl, name)
+}
+
+func (k *kernelDataplane) EnsureAddresses(name string, addresses []*net.IPNet) error {
+	return ensureIPAddresses(k.nl, name, addresses)
+}
+
+func (k *kernelDataplane) EnsureRoutes(name string, routes []*net.IPNet) error {
+	return ensureInterfaceRoutes(k.nl, name, routes)
+}
+
+func (k *kernelDataplane) EnsureExitRoutes(name string, enabled bool) error {
+	return ensureExitRoutes(k.nl, name, enabled)
+}
+
+func (k *kernelDataplane) EnsureMasquerade(name string, sources []*net.IPNet, enabled bool) error {
+	// iptables runs in the namespace of the calling thread
+	return inNetns(k.netns, func() error {
+		return ensureMasquerade(name, sources, enabled)
+	})
+}
+
+func (k *kernelDataplane) ServeDNS(address string, handler dns.Handler) (io.Closer, error) {
+	var sockets dnsSockets
+	// The sockets stay in the namespace they are created in
+	err := inNetns(k.netns, func() error {
+		var err error
+		sockets.pc, sockets.l, err = common.ListenDNS(address)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	common.ServeDNS(sockets.pc, sockets.l, handler)
+	return sockets, nil
+}
+
+func (k *kernelDataplane) ServeDHCP(name string, handler dhcp4.Handler) (io.Closer, error) {
+	var pc net.PacketConn
+	err := inNetns(k.netns, func() error {
+		var err error
+		pc, err = conn.NewUDP4BoundListener(name, ":67")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		err := dhcp4.Serve(pc, handler)
+		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			logrus.WithError(err).Errorf("DHCP server of %s stopped", name)
+		}
+	}()
+	return pc, nil
+}
+
+func (k *kernelDataplane) Device(name string) (*wgtypes.Device, error) {
+	return k.wg.Device(name)
+}
+
+func (k *kernelDataplane) ConfigureDevice(name string, config wgtypes.Config) error {
+	return k.wg.ConfigureDevice(name, config)
+}
+
+// Close stops the userspace devices, their interfaces go away with them
+func (k *kernelDataplane) Close() error {
+	k.lock.Lock()
+	for name, dev := range k.userspace {
+		dev.Close()
+		delete(k.userspace, name)
+	}
+	k.lock.Unlock()
+
+	if k.nl != nil && k.nl != k.host {
+		k.nl.Delete()
+	}
+	if k.host != nil {
+		k.host.Delete()
+	}
+	k.netns.Close()
+
+	if k.wg == nil {
+		return nil
+	}
+	return k.wg.Close()
+}
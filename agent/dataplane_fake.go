@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/krolaw/dhcp4"
+	"github.com/miekg/dns"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// fakeLink is the in-memory state of an interface
+type fakeLink struct {
+	linkType  string
+	mtu       int
+	addresses []*net.IPNet
+	routes    []*net.IPNet
+	// exitRoutes is set if the default route goes through the link
+	exitRoutes bool
+	masquerade []*net.IPNet
+}
+
+// fakeDataplane records the desired state of the host in memory
+// instead of applying it, nothing it does requires privileges
+type fakeDataplane struct {
+	sysctl  bool
+	links   map[string]*fakeLink
+	devices map[string]*wgtypes.Device
+	// writes counts the calls to ConfigureDevice, linkWrites and routeWrites
+	// the changes to the links and the routes, like netlink calls would
+	writes      int
+	linkWrites  int
+	routeWrites int
+	// dns are the addresses DNS queries are answered on
+	dns map[string]bool
+	// dhcp are the interfaces DHCP requests are answered on
+	dhcp map[string]dhcp4.Handler
+}
+
+func newFakeDataplane() *fakeDataplane {
+	return &fakeDataplane{
+		links:   make(map[string]*fakeLink),
+		devices: make(map[string]*wgtypes.Device),
+		dns:     make(map[string]bool),
+		dhcp:    make(map[string]dhcp4.Handler),
+	}
+}
+
+func (f *fakeDataplane) EnsureSysctl() error {
+	f.sysctl = true
+	return nil
+}
+
+func (f *fakeDataplane) EnsureExitRoutes(name string, enabled bool) error {
+	link, ok := f.links[name]
+	if !ok {
+		return fmt.Errorf("no such link %s", name)
+	}
+	if link.exitRoutes != enabled {
+		f.routeWrites++
+	}
+	link.exitRoutes = enabled
+	return nil
+}
+
+func (f *fakeDataplane) EnsureMasquerade(name string, sources []*net.IPNet, enabled bool) error {
+	link, ok := f.links[name]
+	if !ok {
+		return fmt.Errorf("no such link %s", name)
+	}
+	var masquerade []*net.IPNet
+	if enabled {
+		masquerade = sources
+	}
+	if !sameNetworks(link.masquerade, masquerade) {
+		f.routeWrites++
+	}
+	link.masquerade = masquerade
+	return nil
+}
+
+func (f *fakeDataplane) ServeDNS(address string, handler dns.Handler) (io.Closer, error) {
+	f.dns[address] = true
+	return fakeDNS{f, address}, nil
+}
+
+// fakeDNS forgets the address when closed
+type fakeDNS struct {
+	f       *fakeDataplane
+	address string
+}
+
+func (d fakeDNS) Close() error {
+	delete(d.f.dns, d.address)
+	return nil
+}
+
+func (f *fakeDataplane) ServeDHCP(name string, handler dhcp4.Handler) (io.Closer, error) {
+	f.dhcp[name] = handler
+	return fakeDHCP{f, name}, nil
+}
+
+// fakeDHCP forgets the interface when closed
+type fakeDHCP struct {
+	f    *fakeDataplane
+	name string
+}
+
+func (d fakeDHCP) Close() error {
+	delete(d.f.dhcp, d.name)
+	return nil
+}
+
+func (f *fakeDataplane) EnsureInterface(name string, mtu int) error {
+	link, ok := f.links[name]
+	if !ok || link.linkType != "wireguard" {
+		link = &fakeLink{linkType: "wireguard"}
+		f.links[name] = link
+		f.devices[name] = &wgtypes.Device{Name: name}
+		f.linkWrites++
+	}
+	if link.mtu != mtu {
+		link.mtu = mtu
+		f.linkWrites++
+	}
+	return nil
+}
+
+func (f *fakeDataplane) EnsureBridge(name string) error {
+	link, ok := f.links[name]
+	if !ok || link.linkType != "bridge" {
+		f.links[name] = &fakeLink{linkType: "bridge"}
+		delete(f.devices, name)
+		f.linkWrites++
+	}
+	return nil
+}
+
+func (f *fakeDataplane) RemoveInterface(name string) error {
+	if _, ok := f.links[name]; ok {
+		f.linkWrites++
+	}
+	delete(f.links, name)
+	delete(f.devices, name)
+	return nil
+}
+
+func (f *fakeDataplane) EnsureAddresses(name string, addresses []*net.IPNet) error {
+	link, ok := f.links[name]
+	if !ok {
+		return fmt.Errorf("no such interface %s", name)
+	}
+	if !sameNetworks(link.addresses, addresses) {
+		f.linkWrites++
+	}
+	link.addresses = addresses
+	return nil
+}
+
+func (f *fakeDataplane) EnsureRoutes(name string, routes []*net.IPNet) error {
+	link, ok := f.links[name]
+	if !ok {
+		return fmt.Errorf("no such interface %s", name)
+	}
+	if !sameNetworks(link.routes, routes) {
+		f.routeWrites++
+	}
+	link.routes = routes
+	return nil
+}
+
+func (f *fakeDataplane) Device(name string) (*wgtypes.Device, error) {
+	device, ok := f.devices[name]
+	if !ok {
+		return nil, fmt.Errorf("no such device %s", name)
+	}
+	return device, nil
+}
+
+// ConfigureDevice applies the configuration the same way the kernel would
+func (f *fakeDataplane) ConfigureDevice(name string, config wgtypes.Config) error {
+	device, ok := f.devices[name]
+	if !ok {
+		return fmt.Errorf("no such device %s", name)
+	}
+	f.writes++
+
+	if config.PrivateKey != nil {
+		device.PrivateKey = *config.PrivateKey
+		device.PublicKey = config.PrivateKey.PublicKey()
+	}
+	if config.ListenPort != nil {
+		device.ListenPort = *config.ListenPort
+	}
+	if config.FirewallMark != nil {
+		device.FirewallMark = *config.FirewallMark
+	}
+	if config.ReplacePeers {
+		device.Peers = nil
+	}
+
+	for _, peerConfig := range config.Peers {
+		index := -1
+		for i, peer := range device.Peers {
+			if peer.PublicKey == peerConfig.PublicKey {
+				index = i
+			}
+		}
+
+		if peerConfig.Remove {
+			if index >= 0 {
+				device.Peers = append(device.Peers[:index], device.Peers[index+1:]...)
+			}
+			continue
+		}
+		if index < 0 {
+			if peerConfig.UpdateOnly {
+				continue
+			}
+			device.Peers = append(device.Peers, wgtypes.Peer{PublicKey: peerConfig.PublicKey})
+			index = len(device.Peers) - 1
+		}
+
+		peer := &device.Peers[index]
+		if peerConfig.PresharedKey != nil {
+			peer.PresharedKey = *peerConfig.PresharedKey
+		}
+		if peerConfig.Endpoint != nil {
+			peer.Endpoint = peerConfig.Endpoint
+		}
+		if peerConfig.PersistentKeepaliveInterval != nil {
+			peer.PersistentKeepaliveInterval = *peerConfig.PersistentKeepaliveInterval
+		}
+		if peerConfig.ReplaceAllowedIPs {
+			peer.AllowedIPs = nil
+		}
+		peer.AllowedIPs = append(peer.AllowedIPs, peerConfig.AllowedIPs...)
+	}
+
+	return nil
+}
+
+func (f *fakeDataplane) Close() error {
+	return nil
+}
+
+// sameNetworks tells if both lists hold the same networks, in any order
+func sameNetworks(a, b []*net.IPNet) bool {
+	var as, bs []net.IPNet
+	for _, n := range a {
+		as = append(as, *n)
+	}
+	for _, n := range b {
+		bs = append(bs, *n)
+	}
+	return sameAllowedIPs(as, bs)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/krolaw/dhcp4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+const (
+	// dhcpLeaseDuration is how long the addresses handed out on the bridge
+	// are valid, the clients renew them halfway through
+	dhcpLeaseDuration = time.Hour
+	// dhcpOfferDuration is how long an offered address is kept for the
+	// client before it can be offered to another one
+	dhcpOfferDuration = time.Minute
+)
+
+// dhcpServer hands out the addresses of the pool to the clients of the
+// bridge, with the bridge as their gateway
+type dhcpServer struct {
+	lock    sync.Mutex
+	log     *logrus.Entry
+	pool    *addressPool
+	options dhcp4.Options
+}
+
+func newDHCPServer(log *logrus.Entry, pool *addressPool) *dhcpServer {
+	return &dhcpServer{
+		log:  log,
+		pool: pool,
+		options: dhcp4.Options{
+			dhcp4.OptionSubnetMask: []byte(pool.ipRange.Mask),
+			dhcp4.OptionRouter:     []byte(pool.gateway),
+		},
+	}
+}
+
+// setOptions updates the options that follow the settings of the network,
+// the MTU of the mesh and the resolver of the agent if DNS is enabled
+func (s *dhcpServer) setOptions(lease *proto.Lease, config *proto.ConfigurationResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	mtu := make([]byte, 2)
+	binary.BigEndian.PutUint16(mtu, uint16(config.Network.GetSettings().GetMtu()))
+	s.options[dhcp4.OptionInterfaceMTU] = mtu
+
+	delete(s.options, dhcp4.OptionDomainNameServer)
+	ip, _, err := net.ParseCIDR(lease.IpRange)
+	if err == nil && config.Network.GetSettings().GetDns() == proto.DNS_DNS_ENABLED {
+		s.options[dhcp4.OptionDomainNameServer] = []byte(ip.To4())
+	}
+}
+
+func (s *dhcpServer) ServeDHCP(req dhcp4.Packet, msgType dhcp4.MessageType, options dhcp4.Options) dhcp4.Packet {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	serverIP := s.pool.gateway
+	mac := req.CHAddr().String()
+	switch msgType {
+	case dhcp4.Discover:
+		ip := s.pool.offer(mac, net.IP(options[dhcp4.OptionRequestedIPAddress]))
+		if ip == nil {
+			s.log.Warningf("No address left on the bridge for %s", mac)
+			return nil
+		}
+		// Keep the address for the client until it requests it, so that it
+		// is not offered to another client in the meantime
+		s.pool.reserve(mac, ip, dhcpOfferDuration)
+		return dhcp4.ReplyPacket(req, dhcp4.Offer, serverIP, ip, dhcpLeaseDuration,
+			s.options.SelectOrderOrAll(options[dhcp4.OptionParameterRequestList]))
+
+	case dhcp4.Request:
+		if server, ok := options[dhcp4.OptionServerIdentifier]; ok && !net.IP(server).Equal(serverIP) {
+			// The client picked another server
+			return nil
+		}
+		requested := net.IP(options[dhcp4.OptionRequestedIPAddress])
+		if requested == nil {
+			requested = req.CIAddr()
+		}
+
+		renewal := requested.Equal(s.pool.lookup(mac))
+		if !s.pool.reserve(mac, requested, dhcpLeaseDuration) {
+			return dhcp4.ReplyPacket(req, dhcp4.NAK, serverIP, nil, 0, nil)
+		}
+		if !renewal {
+			s.log.Infof("Gave %s to %s on the bridge", requested, mac)
+		}
+		return dhcp4.ReplyPacket(req, dhcp4.ACK, serverIP, requested, dhcpLeaseDuration,
+			s.options.SelectOrderOrAll(options[dhcp4.OptionParameterRequestList]))
+
+	case dhcp4.Release, dhcp4.Decline:
+		s.pool.release(mac)
+	}
+	return nil
+}
+
+// ensureDHCP serves the addresses of the pool on the bridge, the server
+// starts over when the pool changes
+func (a *agent) ensureDHCP(lease *proto.Lease, config *proto.ConfigurationResponse) error {
+	if a.dhcpServer == nil || a.dhcpServer.pool != a.pool {
+		a.stopDHCP()
+		if a.pool == nil {
+			return nil
+		}
+
+		server := newDHCPServer(a.log(), a.pool)
+		var err error
+		a.dhcp, err = a.dp.ServeDHCP(a.bridgeName(), server)
+		if err != nil {
+			return err
+		}
+		a.dhcpServer = server
+		a.log().Infof("Handing out the addresses of %s on %s", lease.IpRange, a.bridgeName())
+	}
+
+	a.dhcpServer.setOptions(lease, config)
+	return nil
+}
+
+func (a *agent) stopDHCP() {
+	if a.dhcp == nil {
+		return
+	}
+
+	err := a.dhcp.Close()
+	if err != nil {
+		a.log().WithError(err).Warningf("Could not stop the DHCP server of %s", a.bridgeName())
+	}
+	a.dhcp = nil
+	a.dhcpServer = nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/krolaw/dhcp4"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestDHCPServer(t *testing.T, cidr string) *dhcpServer {
+	t.Helper()
+	_, ipRange, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newDHCPServer(logrus.NewEntry(logrus.New()), newAddressPool(ipRange))
+}
+
+// discover sends a DHCPDISCOVER for the mac and returns the offered
+// address, nil if there is no offer
+func discover(t *testing.T, s *dhcpServer, mac string) net.IP {
+	t.Helper()
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := dhcp4.RequestPacket(dhcp4.Discover, hw, nil, []byte{1, 2, 3, 4}, false, nil)
+	reply := s.ServeDHCP(req, dhcp4.Discover, req.ParseOptions())
+	if reply == nil {
+		return nil
+	}
+	return reply.YIAddr()
+}
+
+func TestOfferReservesTheAddress(t *testing.T) {
+	s := newTestDHCPServer(t, "10.60.0.0/29")
+
+	first := discover(t, s, "aa:aa:aa:aa:aa:aa")
+	if !first.Equal(net.ParseIP("10.60.0.2")) {
+		t.Fatalf("expected the first address of the pool, got %s", first)
+	}
+	second := discover(t, s, "bb:bb:bb:bb:bb:bb")
+	if second == nil || second.Equal(first) {
+		t.Fatalf("expected another address than the one offered to the first client, got %s", second)
+	}
+	if again := discover(t, s, "aa:aa:aa:aa:aa:aa"); !again.Equal(first) {
+		t.Errorf("expected the first client to get its offer again, got %s", again)
+	}
+}
+
+func TestOfferExpires(t *testing.T) {
+	s := newTestDHCPServer(t, "10.60.0.0/30")
+
+	offered := discover(t, s, "aa:aa:aa:aa:aa:aa")
+	if offered == nil {
+		t.Fatal("expected an address")
+	}
+	if other := discover(t, s, "bb:bb:bb:bb:bb:bb"); other != nil {
+		t.Fatalf("expected the pool to be exhausted while the offer holds, got %s", other)
+	}
+
+	offset := s.pool.offset(offered)
+	lease := s.pool.leases[offset]
+	lease.expires = time.Now().Add(-time.Second)
+	s.pool.leases[offset] = lease
+	if other := discover(t, s, "bb:bb:bb:bb:bb:bb"); !other.Equal(offered) {
+		t.Errorf("expected the expired offer to be given to another client, got %s", other)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/thomas-maurice/wgnw/common"
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+const (
+	// dnsConfigNone leaves the resolver of the host alone
+	dnsConfigNone = "none"
+	// dnsConfigResolved routes the mesh domains to the agent with
+	// systemd-resolved, the other queries are not affected
+	dnsConfigResolved = "systemd-resolved"
+	// dnsConfigResolvConf puts the agent first in resolv.conf, the queries
+	// outside of the mesh go to the original nameservers
+	dnsConfigResolvConf = "resolv.conf"
+)
+
+// resolver answers the names of the nodes of every membership, on the mesh
+// address of the memberships that have DNS enabled
+type resolver struct {
+	agents  []*agent
+	mode    string
+	conf    *resolvConf
+	handler *common.DNSHandler
+}
+
+// newResolver forwards the queries of the host outside of the mesh to the
+// original nameservers if forward is set, which needs resolv.conf
+func newResolver(agents []*agent, mode string, resolvConfFile string, forward bool) (*resolver, error) {
+	if forward && mode != dnsConfigResolvConf {
+		return nil, fmt.Errorf("forwarding the DNS queries needs the %s DNS configuration", dnsConfigResolvConf)
+	}
+
+	r := &resolver{
+		agents: agents,
+		mode:   mode,
+	}
+	r.handler = &common.DNSHandler{Zones: r.zones}
+
+	switch mode {
+	case dnsConfigNone, dnsConfigResolved:
+	case dnsConfigResolvConf:
+		conf, err := newResolvConf(resolvConfFile)
+		if err != nil {
+			return nil, err
+		}
+		r.conf = conf
+		if forward {
+			r.handler.Upstream = conf.upstream()
+		}
+	default:
+		return nil, fmt.Errorf("unknown DNS configuration %s, should be %s, %s or %s", mode, dnsConfigNone, dnsConfigResolved, dnsConfigResolvConf)
+	}
+
+	for _, a := range agents {
+		a.resolver = r
+	}
+	return r, nil
+}
+
+// Close reverts the resolver of the host, even for the memberships that
+// did not stop their DNS server
+func (r *resolver) Close() error {
+	if r.conf == nil {
+		return nil
+	}
+	return r.conf.restore()
+}
+
+func (r *resolver) zones() ([]common.DNSZone, error) {
+	var zones []common.DNSZone
+	for _, a := range r.agents {
+		zone, ok := a.dnsZone()
+		if ok {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// dnsZone returns the names of the nodes of the network, if it has DNS enabled
+func (a *agent) dnsZone() (common.DNSZone, bool) {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	settings := a.config.GetNetwork().GetSettings()
+	if settings.GetDns() != proto.DNS_DNS_ENABLED {
+		return common.DNSZone{}, false
+	}
+	ranges, err := networkRanges(a.config)
+	if err != nil {
+		return common.DNSZone{}, false
+	}
+
+	zone := common.NewDNSZone(common.NetworkDomain(a.network, settings.DnsDomain))
+	for _, endpoint := range a.config.Network.Endpoints {
+		for _, nw := range endpoint.Networks {
+			// The routes of the node are not its addresses
+			ip, n, err := net.ParseCIDR(nw)
+			if err == nil && containedIn(n, ranges) {
+				zone.Add(endpoint.NodeName, ip)
+			}
+		}
+	}
+	return zone, true
+}
+
+// ensureDNS answers DNS queries on the address of the lease, and points the
+// resolver of the host to it, if the network has DNS enabled
+func (a *agent) ensureDNS(lease *proto.Lease, config *proto.ConfigurationResponse) error {
+	settings := config.Network.GetSettings()
+	if settings.GetDns() != proto.DNS_DNS_ENABLED {
+		a.stopDNS()
+		return nil
+	}
+
+	ip, _, err := net.ParseCIDR(lease.IpRange)
+	if err != nil {
+		return err
+	}
+	address := net.JoinHostPort(ip.String(), "53")
+	domain := common.NetworkDomain(a.network, settings.DnsDomain)
+
+	if a.dnsAddress != address {
+		a.stopDNS()
+		a.dns, err = a.dp.ServeDNS(address, a.resolver.handler)
+		if err != nil {
+			return err
+		}
+		a.dnsAddress = address
+		a.log().Infof("Answering the names of %s on %s", domain, address)
+	}
+
+	hostDNS := ip.String() + " " + domain
+	if a.hostDNS == hostDNS {
+		return nil
+	}
+	switch a.resolver.mode {
+	case dnsConfigResolved:
+		if a.netns != "" {
+			a.log().Warningf("Not configuring systemd-resolved, %s is not in the namespace of the host", a.iface)
+			return nil
+		}
+		err = resolvectl("dns", a.iface, ip.String())
+		if err == nil {
+			err = resolvectl("domain", a.iface, "~"+strings.TrimSuffix(domain, "."))
+		}
+	case dnsConfigResolvConf:
+		if a.netns != "" {
+			a.log().Warningf("Not adding %s to %s, it is not in the namespace of the host", ip, a.resolver.conf.filename)
+			return nil
+		}
+		err = a.resolver.conf.set(a.iface, ip.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	a.hostDNS = hostDNS
+	return nil
+}
+
+// stopDNS stops answering DNS queries and reverts the resolver of the host
+func (a *agent) stopDNS() {
+	if a.dns == nil {
+		return
+	}
+
+	var err error
+	switch a.resolver.mode {
+	case dnsConfigResolved:
+		if a.hostDNS != "" {
+			err = resolvectl("revert", a.iface)
+		}
+	case dnsConfigResolvConf:
+		if a.hostDNS != "" {
+			err = a.resolver.conf.set(a.iface, "")
+		}
+	}
+	if err != nil {
+		a.log().WithError(err).Warning("Could not revert the DNS configuration of the host")
+	}
+	a.hostDNS = ""
+
+	err = a.dns.Close()
+	if err != nil {
+		a.log().WithError(err).Warningf("Could not stop answering DNS queries on %s", a.dnsAddress)
+	}
+	a.dns = nil
+	a.dnsAddress = ""
+}
+
+// dnsSockets are the sockets of a DNS server
+type dnsSockets struct {
+	pc net.PacketConn
+	l  net.Listener
+}
+
+func (s dnsSockets) Close() error {
+	err := s.pc.Close()
+	lerr := s.l.Close()
+	if err == nil {
+		err = lerr
+	}
+	return err
+}
+
+func resolvectl(args ...string) error {
+	out, err := exec.Command("resolvectl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("resolvectl %s failed: %s %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+const (
+	resolvConfBegin = "# Added by wgnwd, removed when it stops"
+	resolvConfEnd   = "# End of the wgnwd nameservers"
+)
+
+// resolvConf puts the nameservers of the agent on top of resolv.conf.
+// The file is rewritten in place so that bind mounts keep working.
+type resolvConf struct {
+	lock     sync.Mutex
+	filename string
+	// original is the content of the file without the nameservers of the agent
+	original    string
+	nameservers map[string]string
+}
+
+func newResolvConf(filename string) (*resolvConf, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// An agent that did not stop cleanly leaves its nameservers behind
+	original := string(b)
+	begin := strings.Index(original, resolvConfBegin+"\n")
+	end := strings.Index(original, resolvConfEnd+"\n")
+	if begin >= 0 && end > begin {
+		original = original[:begin] + original[end+len(resolvConfEnd)+1:]
+	}
+
+	return &resolvConf{
+		filename:    filename,
+		original:    original,
+		nameservers: make(map[string]string),
+	}, nil
+}
+
+// upstream returns the nameservers of the original file
+func (r *resolvConf) upstream() []string {
+	var upstream []string
+	scanner := bufio.NewScanner(strings.NewReader(r.original))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			upstream = append(upstream, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return upstream
+}
+
+// set adds the nameserver of the interface, or removes it if empty
+func (r *resolvConf) set(iface string, nameserver string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if nameserver == "" {
+		delete(r.nameservers, iface)
+	} else {
+		r.nameservers[iface] = nameserver
+	}
+
+	content := r.original
+	if len(r.nameservers) != 0 {
+		var ifaces []string
+		for iface := range r.nameservers {
+			ifaces = append(ifaces, iface)
+		}
+		sort.Strings(ifaces)
+
+		var b strings.Builder
+		b.WriteString(resolvConfBegin + "\n")
+		for _, iface := range ifaces {
+			fmt.Fprintf(&b, "nameserver %s\n", r.nameservers[iface])
+		}
+		b.WriteString(resolvConfEnd + "\n")
+		content = b.String() + content
+	}
+
+	return ioutil.WriteFile(r.filename, []byte(content), 0644)
+}
+
+// restore writes the file back without the nameservers of the agent
+func (r *resolvConf) restore() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nameservers = make(map[string]string)
+	return ioutil.WriteFile(r.filename, []byte(r.original), 0644)
+}
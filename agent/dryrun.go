@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thomas-maurice/wgnw/common"
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// dryRunInterface is an interface the agent would configure
+type dryRunInterface struct {
+	Name      string   `json:"name" yaml:"name"`
+	Type      string   `json:"type" yaml:"type"`
+	MTU       int      `json:"mtu,omitempty" yaml:"mtu,omitempty"`
+	Addresses []string `json:"addresses" yaml:"addresses"`
+	Routes    []string `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// ExitRoutes is set if the default route goes through the interface
+	ExitRoutes bool     `json:"exit_routes,omitempty" yaml:"exit_routes,omitempty"`
+	DHCP       bool     `json:"dhcp,omitempty" yaml:"dhcp,omitempty"`
+	Masquerade []string `json:"masquerade,omitempty" yaml:"masquerade,omitempty"`
+}
+
+// dryRunPeer is a wireguard peer the agent would configure
+type dryRunPeer struct {
+	PublicKey           string   `json:"public_key" yaml:"public_key"`
+	PresharedKey        string   `json:"preshared_key,omitempty" yaml:"preshared_key,omitempty"`
+	Endpoint            string   `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	AllowedIPs          []string `json:"allowed_ips" yaml:"allowed_ips"`
+	PersistentKeepalive int      `json:"persistent_keepalive" yaml:"persistent_keepalive"`
+}
+
+// dryRunPlan is everything the agent would configure on the host
+type dryRunPlan struct {
+	Network    string            `json:"network" yaml:"network"`
+	Lease      string            `json:"lease" yaml:"lease"`
+	Interfaces []dryRunInterface `json:"interfaces" yaml:"interfaces"`
+	PublicKey  string            `json:"public_key" yaml:"public_key"`
+	ListenPort int               `json:"listen_port" yaml:"listen_port"`
+	FwMark     int               `json:"fwmark,omitempty" yaml:"fwmark,omitempty"`
+	DNS        []string          `json:"dns,omitempty" yaml:"dns,omitempty"`
+	Peers      []dryRunPeer      `json:"peers" yaml:"peers"`
+}
+
+// dryRunKey reads the private key, or generates a throwaway one
+// without writing it if there is none yet
+func dryRunKey(filename string) (*wgtypes.Key, error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		logrus.Infof("No private key in %s, using a throwaway one", filename)
+		key, err := wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		return &key, nil
+	}
+
+	return getWireguardKey(filename)
+}
+
+// dryRunLease returns the lease from the state if it is still valid,
+// otherwise the one the controller would hand out, without acquiring it
+func (a *agent) dryRunLease() (*proto.Lease, error) {
+	if a.state.LeaseUUID != "" {
+		ctx, cancel := getContext()
+		defer cancel()
+		resp, err := a.client.GetLease(ctx, &proto.GetLeaseRequest{Uuid: a.state.LeaseUUID})
+		if err == nil && !resp.Lease.Expired {
+			return resp.Lease, nil
+		}
+		logrus.Infof("Lease %s from the state is not usable, showing the one the controller would hand out", a.state.LeaseUUID)
+	}
+
+	var publicInfo *proto.PublicPeer
+	if a.publicIP != "" {
+		publicInfo = &proto.PublicPeer{
+			Address: a.publicIP,
+			Port:    int32(a.port),
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+	resp, err := a.client.AcquireLease(ctx, &proto.AcquireLeaseRequest{
+		PublicKey:   a.keys.key(a.network).PublicKey().String(),
+		NetworkName: a.network,
+		NodeName:    hostname,
+		Peer:        publicInfo,
+		Routes:      a.advertisedRoutes(),
+		Tags:        a.tags,
+		DryRun:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Lease, nil
+}
+
+// dryRun applies the configuration to a fake dataplane, and writes
+// what would have been done to the host
+func (a *agent) dryRun(w io.Writer, format string, keyFile string) error {
+	fake := newFakeDataplane()
+	a.dp = fake
+
+	lease, err := a.dryRunLease()
+	if err != nil {
+		logrus.WithError(err).Error("Could not get a lease")
+		return err
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+	config, err := a.client.FetchConfiguration(ctx, &proto.ConfigurationRequest{
+		NetworkName: lease.Network,
+		LeaseUuid:   lease.Uuid,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Could not fetch configuration")
+		return err
+	}
+
+	err = a.apply(lease, config)
+	if err != nil {
+		return err
+	}
+
+	plan := dryRunPlan{
+		Network:   lease.Network,
+		Lease:     lease.Uuid,
+		PublicKey: a.keys.key(a.network).PublicKey().String(),
+	}
+
+	var names []string
+	for name := range fake.links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		link := fake.links[name]
+		iface := dryRunInterface{
+			Name:       name,
+			Type:       link.linkType,
+			MTU:        link.mtu,
+			ExitRoutes: link.exitRoutes,
+			DHCP:       fake.dhcp[name] != nil,
+		}
+		for _, address := range link.addresses {
+			iface.Addresses = append(iface.Addresses, address.String())
+		}
+		for _, route := range link.routes {
+			iface.Routes = append(iface.Routes, route.String())
+		}
+		for _, source := range link.masquerade {
+			iface.Masquerade = append(iface.Masquerade, source.String())
+		}
+		plan.Interfaces = append(plan.Interfaces, iface)
+	}
+
+	device := fake.devices[a.iface]
+	plan.ListenPort = device.ListenPort
+	plan.FwMark = device.FirewallMark
+	for address := range fake.dns {
+		plan.DNS = append(plan.DNS, address)
+	}
+	sort.Strings(plan.DNS)
+	for _, peer := range device.Peers {
+		p := dryRunPeer{
+			PublicKey:           peer.PublicKey.String(),
+			PersistentKeepalive: int(peer.PersistentKeepaliveInterval.Seconds()),
+		}
+		if peer.PresharedKey != (wgtypes.Key{}) {
+			p.PresharedKey = peer.PresharedKey.String()
+		}
+		if peer.Endpoint != nil {
+			p.Endpoint = peer.Endpoint.String()
+		}
+		for _, allowed := range peer.AllowedIPs {
+			p.AllowedIPs = append(p.AllowedIPs, allowed.String())
+		}
+		plan.Peers = append(plan.Peers, p)
+	}
+
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(&plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(b))
+	case "yaml":
+		b, err := yaml.Marshal(&plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(b))
+	case "wg-quick":
+		fmt.Fprint(w, plan.wgQuick(a.iface, keyFile).String())
+	default:
+		return fmt.Errorf("unknown dry run format %s, should be yaml, json or wg-quick", format)
+	}
+
+	return nil
+}
+
+// wgQuick renders the plan as a wg-quick configuration for the interface
+func (p *dryRunPlan) wgQuick(iface string, keyFile string) *common.WgQuickConfig {
+	config := &common.WgQuickConfig{
+		Interface: common.WgQuickInterface{
+			Comment:    fmt.Sprintf("Network %s, lease %s", p.Network, p.Lease),
+			ListenPort: p.ListenPort,
+			// Routes are added explicitly like the agent does
+			Table:  "off",
+			PostUp: []string{fmt.Sprintf("wg set %%i private-key %s", keyFile)},
+		},
+	}
+
+	for _, i := range p.Interfaces {
+		if i.Name == iface {
+			config.Interface.Address = i.Addresses
+			config.Interface.MTU = i.MTU
+			for _, route := range i.Routes {
+				config.Interface.PostUp = append(config.Interface.PostUp, fmt.Sprintf("ip route replace %s dev %%i", route))
+			}
+			if p.FwMark != 0 {
+				config.Interface.PostUp = append(config.Interface.PostUp, fmt.Sprintf("wg set %%i fwmark %d", p.FwMark))
+			}
+			if i.ExitRoutes {
+				config.Interface.PostUp = append(config.Interface.PostUp,
+					fmt.Sprintf("ip route replace default dev %%i table %d", exitTable),
+					fmt.Sprintf("ip rule add table main suppress_prefixlength 0 priority %d", exitRulePriority-1),
+					fmt.Sprintf("ip rule add not fwmark %d table %d priority %d", exitTable, exitTable, exitRulePriority),
+				)
+				config.Interface.PostDown = append(config.Interface.PostDown,
+					fmt.Sprintf("ip rule del priority %d", exitRulePriority),
+					fmt.Sprintf("ip rule del priority %d", exitRulePriority-1),
+				)
+			}
+			for _, source := range i.Masquerade {
+				command := "iptables"
+				if strings.Contains(source, ":") {
+					command = "ip6tables"
+				}
+				rule := fmt.Sprintf("POSTROUTING -s %s ! -o %%i -j MASQUERADE", source)
+				config.Interface.PostUp = append(config.Interface.PostUp, command+" -t nat -A "+rule)
+				config.Interface.PostDown = append(config.Interface.PostDown, command+" -t nat -D "+rule)
+			}
+			continue
+		}
+
+		config.Interface.PostUp = append(config.Interface.PostUp,
+			fmt.Sprintf("ip link add %s type %s", i.Name, i.Type),
+			fmt.Sprintf("ip link set %s up", i.Name),
+		)
+		for _, address := range i.Addresses {
+			config.Interface.PostUp = append(config.Interface.PostUp, fmt.Sprintf("ip address add %s dev %s", address, i.Name))
+		}
+		config.Interface.PostDown = append(config.Interface.PostDown, fmt.Sprintf("ip link del %s", i.Name))
+	}
+
+	for _, peer := range p.Peers {
+		config.Peers = append(config.Peers, common.WgQuickPeer{
+			PublicKey:           peer.PublicKey,
+			PresharedKey:        peer.PresharedKey,
+			Endpoint:            peer.Endpoint,
+			AllowedIPs:          peer.AllowedIPs,
+			PersistentKeepalive: peer.PersistentKeepalive,
+		})
+	}
+
+	return config
+}
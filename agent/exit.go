@@ -0,0 +1,278 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// exitTable is the routing table of the default route through the
+	// exit node, and the firewall mark of the packets of the tunnel
+	// itself, which keep using the main table
+	exitTable = 51820
+	// exitRulePriority is the priority of the rule sending everything
+	// unmarked to the exit table, the one that lets the more specific
+	// routes of the main table win comes right before it
+	exitRulePriority = 31820
+)
+
+// defaultRoutes are the routes an exit node advertises
+var defaultRoutes = []string{"0.0.0.0/0", "::/0"}
+
+// exitRules are the policy routing rules of the exit table for the family
+func exitRules(family int) []*netlink.Rule {
+	suppress := netlink.NewRule()
+	suppress.Family = family
+	suppress.Table = unix.RT_TABLE_MAIN
+	suppress.SuppressPrefixlen = 0
+	suppress.Priority = exitRulePriority - 1
+
+	exit := netlink.NewRule()
+	exit.Family = family
+	exit.Table = exitTable
+	exit.Mark = exitTable
+	exit.Invert = true
+	exit.Priority = exitRulePriority
+
+	return []*netlink.Rule{suppress, exit}
+}
+
+// ensureExitRoutes sends the traffic that is not for the tunnel itself
+// through the interface, or stops doing it
+func ensureExitRoutes(h *netlink.Handle, name string, enabled bool) error {
+	link, err := h.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		err = ensureExitFamily(h, link, family, enabled)
+		// Hosts without IPv6 keep the IPv4 default route
+		if err != nil && family == netlink.FAMILY_V6 {
+			logrus.WithError(err).Warningf("Could not route IPv6 through %s", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ensureExitFamily(h *netlink.Handle, link netlink.Link, family int, enabled bool) error {
+	_, dst, _ := net.ParseCIDR(defaultRoutes[0])
+	if family == netlink.FAMILY_V6 {
+		_, dst, _ = net.ParseCIDR(defaultRoutes[1])
+	}
+	route := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       dst,
+		Table:     exitTable,
+		Scope:     netlink.SCOPE_LINK,
+	}
+
+	existing, err := h.RuleList(family)
+	if err != nil {
+		return err
+	}
+	hasRule := func(rule *netlink.Rule) bool {
+		for _, r := range existing {
+			// The rules are listed by family, which is not set on them
+			r.Family = family
+			if sameRule(&r, rule) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !enabled {
+		for _, rule := range exitRules(family) {
+			if hasRule(rule) {
+				err = h.RuleDel(rule)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		err = h.RouteDel(route)
+		if err != nil && err != syscall.ESRCH {
+			return err
+		}
+		return nil
+	}
+
+	err = h.RouteReplace(route)
+	if err != nil {
+		return err
+	}
+	for _, rule := range exitRules(family) {
+		if hasRule(rule) {
+			continue
+		}
+		err = h.RuleAdd(rule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameRule tells if two rules match the same packets and send them to the
+// same table at the same priority
+func sameRule(a *netlink.Rule, b *netlink.Rule) bool {
+	return a.Family == b.Family &&
+		a.Priority == b.Priority &&
+		a.Table == b.Table &&
+		a.Mark == b.Mark &&
+		a.Invert == b.Invert &&
+		a.SuppressPrefixlen == b.SuppressPrefixlen
+}
+
+// defaultInterface returns the interface of the default route of the family
+// in the main table, empty if there is none
+func defaultInterface(family int) (string, error) {
+	routes, err := netlink.RouteListFiltered(family, &netlink.Route{Table: unix.RT_TABLE_MAIN}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return "", err
+	}
+	for _, route := range routes {
+		if route.Dst != nil {
+			if ones, _ := route.Dst.Mask.Size(); ones != 0 {
+				continue
+			}
+		}
+		if route.LinkIndex == 0 {
+			continue
+		}
+		link, err := netlink.LinkByIndex(route.LinkIndex)
+		if err != nil {
+			return "", err
+		}
+		return link.Attrs().Name, nil
+	}
+	return "", nil
+}
+
+// masqueradeRule is a rule of ensureMasquerade, its spec is written the
+// way iptables -S lists it so that the listed rules can be compared to it
+type masqueradeRule struct {
+	table string
+	chain string
+	spec  []string
+}
+
+// masqueradeRules returns the rules letting the traffic of the source
+// through the mesh interface out of the uplink and back, masqueraded
+func masqueradeRules(name string, source *net.IPNet, uplink string) []masqueradeRule {
+	tag := []string{"-m", "comment", "--comment", "wgnw:" + name}
+	return []masqueradeRule{
+		{"nat", "POSTROUTING", append([]string{"-s", source.String(), "-o", uplink}, append(tag, "-j", "MASQUERADE")...)},
+		{"filter", "FORWARD", append([]string{"-s", source.String(), "-i", name, "-o", uplink}, append(tag, "-j", "ACCEPT")...)},
+		{"filter", "FORWARD", append([]string{"-d", source.String(), "-i", uplink, "-o", name, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED"}, append(tag, "-j", "ACCEPT")...)},
+	}
+}
+
+// ensureMasquerade masquerades the traffic of the sources going out of the
+// interface of the default route, and only lets that traffic through, or
+// stops doing it. The rules are tagged with the mesh interface, the ones
+// of a previous default route are removed.
+func ensureMasquerade(name string, sources []*net.IPNet, enabled bool) error {
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		protocol := iptables.ProtocolIPv4
+		if family == netlink.FAMILY_V6 {
+			protocol = iptables.ProtocolIPv6
+		}
+
+		var familySources []*net.IPNet
+		for _, source := range sources {
+			if (source.IP.To4() == nil) == (family == netlink.FAMILY_V6) {
+				familySources = append(familySources, source)
+			}
+		}
+		if len(familySources) == 0 {
+			continue
+		}
+
+		ipt, err := iptables.NewWithProtocol(protocol)
+		if err != nil {
+			return err
+		}
+
+		var wanted []masqueradeRule
+		if enabled {
+			uplink, err := defaultInterface(family)
+			if err != nil {
+				return err
+			}
+			if uplink == "" {
+				logrus.Warningf("No default route, the traffic of the peers of %s cannot leave the host", name)
+			}
+			for _, source := range familySources {
+				if uplink != "" {
+					wanted = append(wanted, masqueradeRules(name, source, uplink)...)
+				}
+			}
+		}
+
+		err = removeStaleRules(ipt, name, wanted)
+		if err != nil {
+			return err
+		}
+		for _, rule := range wanted {
+			err = ipt.AppendUnique(rule.table, rule.chain, rule.spec...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeStaleRules removes the rules tagged with the mesh interface that
+// are not wanted anymore
+func removeStaleRules(ipt *iptables.IPTables, name string, wanted []masqueradeRule) error {
+	keep := make(map[string]bool)
+	for _, rule := range wanted {
+		keep[rule.table+" "+rule.chain+" "+strings.Join(rule.spec, " ")] = true
+	}
+
+	for _, chain := range []struct{ table, chain string }{{"nat", "POSTROUTING"}, {"filter", "FORWARD"}} {
+		listed, err := ipt.List(chain.table, chain.chain)
+		if err != nil {
+			return err
+		}
+		for _, line := range listed {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] != "-A" || !hasTag(fields, "wgnw:"+name) {
+				continue
+			}
+			spec := fields[2:]
+			if keep[chain.table+" "+chain.chain+" "+strings.Join(spec, " ")] {
+				continue
+			}
+			err = ipt.Delete(chain.table, chain.chain, spec...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasTag(fields []string, tag string) bool {
+	for i, field := range fields {
+		if field == "--comment" && i+1 < len(fields) && fields[i+1] == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krolaw/dhcp4"
+
+	"github.com/thomas-maurice/wgnw/common"
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// errNoAllocation is returned when a container interface has no address
+var errNoAllocation = errors.New("the container interface has no address")
+
+// poolLease is an address of the pool given to an owner, until it is
+// released if it does not expire
+type poolLease struct {
+	owner   string
+	expires time.Time
+}
+
+func (l poolLease) expired(now time.Time) bool {
+	return !l.expires.IsZero() && now.After(l.expires)
+}
+
+// addressPool hands out the addresses of the lease range to the DHCP
+// clients and the containers plugged into the bridge. The first address
+// of the range is the mesh interface and the second one the bridge, the
+// gateway of the pool.
+type addressPool struct {
+	lock    sync.Mutex
+	ipRange *net.IPNet
+	gateway net.IP
+	// start and size are the first address and length of the pool
+	start  net.IP
+	size   int
+	leases map[int]poolLease
+}
+
+// newAddressPool returns a pool for the range, nil if it is too small to
+// have addresses left once the interface and the bridge took theirs
+func newAddressPool(ipRange *net.IPNet) *addressPool {
+	ones, bits := ipRange.Mask.Size()
+	size := 1<<uint(bits-ones) - 3
+	if bits != 32 || size <= 0 {
+		return nil
+	}
+
+	network := ipRange.IP.To4().Mask(ipRange.Mask)
+	return &addressPool{
+		ipRange: &net.IPNet{IP: network, Mask: ipRange.Mask},
+		gateway: dhcp4.IPAdd(network, 1),
+		start:   dhcp4.IPAdd(network, 2),
+		size:    size,
+		leases:  make(map[int]poolLease),
+	}
+}
+
+// offset returns the position of the address in the pool, -1 if it is not in it
+func (p *addressPool) offset(ip net.IP) int {
+	if ip.To4() == nil {
+		return -1
+	}
+	offset := dhcp4.IPRange(p.start, ip) - 1
+	if offset < 0 || offset >= p.size {
+		return -1
+	}
+	return offset
+}
+
+// lookup returns the address of the owner, nil if it has none
+func (p *addressPool) lookup(owner string) net.IP {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for offset, lease := range p.leases {
+		if lease.owner == owner {
+			return dhcp4.IPAdd(p.start, offset)
+		}
+	}
+	return nil
+}
+
+// offer returns the address the owner already has, the one it asks for if
+// it is free, or the first free one, nil if the pool is exhausted
+func (p *addressPool) offer(owner string, requested net.IP) net.IP {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	offset := p.free(owner, requested)
+	if offset < 0 {
+		return nil
+	}
+	return dhcp4.IPAdd(p.start, offset)
+}
+
+func (p *addressPool) free(owner string, requested net.IP) int {
+	now := time.Now()
+	for offset, lease := range p.leases {
+		if lease.owner == owner {
+			return offset
+		}
+	}
+
+	free := func(offset int) bool {
+		lease, taken := p.leases[offset]
+		return !taken || lease.expired(now)
+	}
+	if offset := p.offset(requested); offset >= 0 && free(offset) {
+		return offset
+	}
+	for offset := 0; offset < p.size; offset++ {
+		if free(offset) {
+			return offset
+		}
+	}
+	return -1
+}
+
+// reserve gives the address to the owner for the duration, or until it is
+// released if the duration is zero. It fails if another owner has it.
+func (p *addressPool) reserve(owner string, ip net.IP, duration time.Duration) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	offset := p.offset(ip)
+	lease, taken := p.leases[offset]
+	if offset < 0 || (taken && lease.owner != owner && !lease.expired(time.Now())) {
+		return false
+	}
+
+	for other, lease := range p.leases {
+		if lease.owner == owner && other != offset {
+			delete(p.leases, other)
+		}
+	}
+	lease = poolLease{owner: owner}
+	if duration > 0 {
+		lease.expires = time.Now().Add(duration)
+	}
+	p.leases[offset] = lease
+	return true
+}
+
+// acquire gives the owner an address until it is released, the one it
+// already has if any, nil if the pool is exhausted
+func (p *addressPool) acquire(owner string) net.IP {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	offset := p.free(owner, nil)
+	if offset < 0 {
+		return nil
+	}
+	p.leases[offset] = poolLease{owner: owner}
+	return dhcp4.IPAdd(p.start, offset)
+}
+
+// release gives the address of the owner back to the pool, it returns
+// false if the owner had none
+func (p *addressPool) release(owner string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	released := false
+	for offset, lease := range p.leases {
+		if lease.owner == owner {
+			delete(p.leases, offset)
+			released = true
+		}
+	}
+	return released
+}
+
+// ensurePool makes a new pool when the range of the lease changes, with the
+// addresses of the containers that are still in the range
+func (a *agent) ensurePool(lease *proto.Lease) error {
+	if a.poolRange == lease.IpRange {
+		return nil
+	}
+
+	_, ipRange, err := net.ParseCIDR(lease.IpRange)
+	if err != nil {
+		return err
+	}
+	pool := newAddressPool(ipRange)
+	if pool == nil {
+		a.log().Warningf("Range %s is too small to hand out addresses on the bridge", lease.IpRange)
+	} else if a.store != nil {
+		for owner, address := range a.store.containers(a.network) {
+			if !pool.reserve(owner, net.ParseIP(address), 0) {
+				a.log().Warningf("Address %s of container interface %s is not in %s anymore", address, owner, lease.IpRange)
+			}
+		}
+	}
+
+	a.statusLock.Lock()
+	a.pool = pool
+	a.statusLock.Unlock()
+	a.poolRange = lease.IpRange
+	return nil
+}
+
+// containerOwner is the owner of the address of a container interface
+func containerOwner(req common.IPAMRequest) string {
+	return fmt.Sprintf("%s/%s", req.ContainerID, req.IfName)
+}
+
+// allocation describes the address of a container interface, with what the
+// CNI plugin needs to plug it into the bridge
+func (a *agent) allocation(ip net.IP) (*common.IPAMAllocation, error) {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	if a.pool == nil || a.config == nil || a.lease == nil {
+		return nil, fmt.Errorf("network %s has no address to hand out yet", a.network)
+	}
+
+	ones, _ := a.pool.ipRange.Mask.Size()
+	allocation := &common.IPAMAllocation{
+		Network: a.network,
+		Address: fmt.Sprintf("%s/%d", ip, ones),
+		Gateway: a.pool.gateway.String(),
+		MTU:     int(a.config.Network.GetSettings().GetMtu()),
+		Bridge:  a.bridgeName(),
+		Netns:   a.netns,
+	}
+
+	settings := a.config.Network.GetSettings()
+	meshIP, _, err := net.ParseCIDR(a.lease.IpRange)
+	if err == nil && settings.GetDns() == proto.DNS_DNS_ENABLED {
+		allocation.Nameservers = []string{meshIP.String()}
+		allocation.Domain = strings.TrimSuffix(common.NetworkDomain(a.network, settings.DnsDomain), ".")
+	}
+	return allocation, nil
+}
+
+func (a *agent) addressPool() (*addressPool, error) {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	if !a.bridge {
+		return nil, fmt.Errorf("network %s has no bridge to plug containers into", a.network)
+	}
+	if a.pool == nil {
+		return nil, fmt.Errorf("network %s has no address to hand out yet", a.network)
+	}
+	return a.pool, nil
+}
+
+// allocateContainer gives an address to the container interface, the one
+// it already has if any
+func (a *agent) allocateContainer(req common.IPAMRequest) (*common.IPAMAllocation, error) {
+	pool, err := a.addressPool()
+	if err != nil {
+		return nil, err
+	}
+
+	owner := containerOwner(req)
+	ip := pool.acquire(owner)
+	if ip == nil {
+		return nil, fmt.Errorf("no address left in network %s", a.network)
+	}
+	if a.store != nil {
+		err = a.store.setContainer(a.network, owner, ip.String())
+		if err != nil {
+			pool.release(owner)
+			return nil, err
+		}
+	}
+
+	a.log().Infof("Gave %s to container interface %s", ip, owner)
+	return a.allocation(ip)
+}
+
+// checkContainer returns the address of the container interface
+func (a *agent) checkContainer(req common.IPAMRequest) (*common.IPAMAllocation, error) {
+	pool, err := a.addressPool()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := pool.lookup(containerOwner(req))
+	if ip == nil {
+		return nil, errNoAllocation
+	}
+	return a.allocation(ip)
+}
+
+// releaseContainer gives the address of the container interface back, it
+// does nothing if it has none
+func (a *agent) releaseContainer(req common.IPAMRequest) error {
+	owner := containerOwner(req)
+	a.statusLock.Lock()
+	pool := a.pool
+	a.statusLock.Unlock()
+
+	if pool != nil && pool.release(owner) {
+		a.log().Infof("Released the address of container interface %s", owner)
+	}
+	if a.store == nil {
+		return nil
+	}
+	return a.store.setContainer(a.network, owner, "")
+}
+
+// ipamAgent returns the membership of the network, or the only one if the
+// network is empty
+func ipamAgent(agents []*agent, network string) (*agent, error) {
+	if network == "" {
+		if len(agents) != 1 {
+			return nil, errors.New("the agent joined several networks, the request needs one")
+		}
+		return agents[0], nil
+	}
+	for _, a := range agents {
+		if a.network == network {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("the agent did not join network %s", network)
+}
+
+// serveIPAM answers the CNI plugin: /ipam/add gives an address to a
+// container interface, /ipam/check returns it and /ipam/del releases it
+func serveIPAM(w http.ResponseWriter, r *http.Request, agents []*agent) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req common.IPAMRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err == nil && (req.ContainerID == "" || req.IfName == "") {
+		err = errors.New("the request needs a container ID and an interface name")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a, err := ipamAgent(agents, req.Network)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var result interface{}
+	switch r.URL.Path {
+	case "/ipam/add":
+		result, err = a.allocateContainer(req)
+	case "/ipam/check":
+		result, err = a.checkContainer(req)
+	case "/ipam/del":
+		result, err = struct{}{}, a.releaseContainer(req)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err == errNoAllocation {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.log().WithError(err).Errorf("Could not serve %s for container interface %s", r.URL.Path, containerOwner(req))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -42,3 +44,133 @@ func getWireguardKey(filename string) (*wgtypes.Key, error) {
 		return &key, nil
 	}
 }
+
+// keyManager holds the private key shared by the memberships, and the
+// next one while it is being rotated. The next key is kept in a file
+// next to the current one until every membership switched to it.
+type keyManager struct {
+	lock     sync.Mutex
+	filename string
+	current  wgtypes.Key
+	next     *wgtypes.Key
+	networks []string
+	switched map[string]bool
+}
+
+func newKeyManager(filename string, key wgtypes.Key, networks []string) (*keyManager, error) {
+	k := &keyManager{
+		filename: filename,
+		current:  key,
+		networks: networks,
+		switched: make(map[string]bool),
+	}
+
+	// A rotation was in progress when the agent stopped
+	b, err := ioutil.ReadFile(k.nextFilename())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	} else if err == nil {
+		next, err := wgtypes.ParseKey(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the next private key from %s: %s", k.nextFilename(), err)
+		}
+		k.next = &next
+		logrus.Infof("Resuming the rotation to %s", next.PublicKey().String())
+	}
+
+	return k, nil
+}
+
+func (k *keyManager) nextFilename() string {
+	return k.filename + ".next"
+}
+
+// key returns the key the membership of the network uses
+func (k *keyManager) key(network string) wgtypes.Key {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.next != nil && k.switched[network] {
+		return *k.next
+	}
+	return k.current
+}
+
+// pending returns the next key if a rotation is in progress
+func (k *keyManager) pending() *wgtypes.Key {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return k.next
+}
+
+// rotate starts a rotation, unless one is already in progress
+func (k *keyManager) rotate() error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.next != nil {
+		logrus.Infof("Already rotating to %s", k.next.PublicKey().String())
+		return nil
+	}
+
+	next, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(k.nextFilename(), []byte(next.String()), 0600)
+	if err != nil {
+		return err
+	}
+
+	k.next = &next
+	logrus.Infof("Rotating the private key, the next public key is %s", next.PublicKey().String())
+	return nil
+}
+
+// rotation returns the rotation in progress, if any
+func (k *keyManager) rotation() keyRotation {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	r := keyRotation{PublicKey: k.current.PublicKey().String()}
+	if k.next != nil {
+		r.NextPublicKey = k.next.PublicKey().String()
+		for _, n := range k.networks {
+			if k.switched[n] {
+				r.Switched = append(r.Switched, n)
+			}
+		}
+	}
+	return r
+}
+
+// switchKey moves the membership of the network to the next key. Once
+// every membership did, the next key replaces the current one on disk.
+func (k *keyManager) switchKey(network string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.next == nil || k.switched[network] {
+		return nil
+	}
+	k.switched[network] = true
+	logrus.Infof("Network %s switched to the public key %s", network, k.next.PublicKey().String())
+
+	for _, n := range k.networks {
+		if !k.switched[n] {
+			return nil
+		}
+	}
+
+	err := os.Rename(k.nextFilename(), k.filename)
+	if err != nil {
+		return err
+	}
+
+	k.current = *k.next
+	k.next = nil
+	k.switched = make(map[string]bool)
+	logrus.Infof("Key rotation done, using public key %s", k.current.PublicKey().String())
+	return nil
+}
@@ -12,17 +12,23 @@ func newLease(client proto.WireguardServiceClient,
 	network string,
 	pubkey string,
 	publicPeer *proto.PublicPeer,
+	routes []string,
+	tags []string,
 ) (*proto.Lease, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
 		return nil, err
 	}
 
-	leaseRequest, err := client.AcquireLease(getContext(), &proto.AcquireLeaseRequest{
+	ctx, cancel := getContext()
+	defer cancel()
+	leaseRequest, err := client.AcquireLease(ctx, &proto.AcquireLeaseRequest{
 		PublicKey:   pubkey,
 		NetworkName: network,
 		NodeName:    hostname,
 		Peer:        publicPeer,
+		Routes:      routes,
+		Tags:        tags,
 	})
 	if err != nil {
 		logrus.WithError(err).Error("Could not acquire lease")
@@ -35,11 +41,13 @@ func getOrRenewLease(client proto.WireguardServiceClient,
 	network string,
 	pubkey string,
 	publicPeer *proto.PublicPeer,
-	state *State, // State will be modified
+	routes []string,
+	tags []string,
+	state *NetworkState, // State will be modified
 ) (*proto.Lease, error) {
 	if state.LeaseUUID == "" {
 		// Create a new lease if we don't have any
-		lease, err := newLease(client, network, pubkey, publicPeer)
+		lease, err := newLease(client, network, pubkey, publicPeer, routes, tags)
 		if err != nil {
 			logrus.WithError(err).Error("Could not acquire lease")
 			return nil, err
@@ -48,8 +56,12 @@ func getOrRenewLease(client proto.WireguardServiceClient,
 		return lease, nil
 	}
 
-	renewedLease, err := client.RenewLease(getContext(), &proto.RenewLeaseRequest{
-		Uuid: state.LeaseUUID,
+	ctx, cancel := getContext()
+	defer cancel()
+	renewedLease, err := client.RenewLease(ctx, &proto.RenewLeaseRequest{
+		Uuid:   state.LeaseUUID,
+		Routes: routes,
+		Tags:   tags,
 	})
 
 	if err != nil {
@@ -59,7 +71,7 @@ func getOrRenewLease(client proto.WireguardServiceClient,
 
 	if err != nil || renewedLease.Lease.Expired {
 		// We have to get a new lease
-		lease, err := newLease(client, network, pubkey, publicPeer)
+		lease, err := newLease(client, network, pubkey, publicPeer, routes, tags)
 		if err != nil {
 			logrus.WithError(err).Error("Could not acquire lease")
 			return nil, err
@@ -71,3 +83,39 @@ func getOrRenewLease(client proto.WireguardServiceClient,
 	state.LeaseUUID = renewedLease.Lease.Uuid
 	return renewedLease.Lease, nil
 }
+
+// releaseLease gives the lease back to the controller so its
+// subnet can be reused right away
+func releaseLease(client proto.WireguardServiceClient, state *NetworkState) error {
+	if state.LeaseUUID == "" {
+		return nil
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+	_, err := client.ReleaseLease(ctx, &proto.ReleaseLeaseRequest{
+		Uuid: state.LeaseUUID,
+	})
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not release lease %s", state.LeaseUUID)
+		return err
+	}
+
+	logrus.Infof("Released lease %s", state.LeaseUUID)
+	state.LeaseUUID = ""
+	state.Lease = nil
+	state.Configuration = nil
+	return nil
+}
+
+// acknowledgeRenumber tells the controller the next range of the
+// lease is configured on the host
+func acknowledgeRenumber(client proto.WireguardServiceClient, lease *proto.Lease) error {
+	ctx, cancel := getContext()
+	defer cancel()
+	_, err := client.AcknowledgeRenumber(ctx, &proto.AcknowledgeRenumberRequest{
+		Uuid:        lease.Uuid,
+		NextIpRange: lease.NextIpRange,
+	})
+	return err
+}
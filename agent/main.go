@@ -2,37 +2,63 @@ package main
 
 import (
 	"flag"
-	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/thomas-maurice/wgnw/proto"
+	"github.com/thomas-maurice/wgnw/common"
 )
 
 var (
-	ifaceName          string
-	createBridge       bool
-	networkName        string
-	publicIP           string
-	port               int
-	svcAddr            string
-	stateFile          string
-	keyFile            string
-	authToken          string
-	useTLS             bool
-	insecureSkipVerify bool
-	caCert             string
-	certFile           string
-	certKeyFile        string
+	ifaceName           string
+	createBridge        bool
+	networkName         string
+	publicIP            string
+	port                int
+	svcAddr             string
+	stateFile           string
+	keyFile             string
+	authToken           string
+	useTLS              bool
+	insecureSkipVerify  bool
+	caCert              string
+	certFile            string
+	certKeyFile         string
+	keepOnExit          bool
+	dryRun              bool
+	dryRunFormat        string
+	extraMemberships    membershipFlags
+	configFile          string
+	logLevel            string
+	interval            time.Duration
+	socketPath          string
+	promListenAddress   string
+	wireguardMode       string
+	maxBackoff          time.Duration
+	namespace           string
+	keyRotationInterval time.Duration
+	advertiseRoutes     string
+	exitNode            string
+	advertiseExitNode   bool
+	dnsConfig           string
+	dnsForward          bool
+	dhcp                bool
+	resolvConfFile      string
+	tags                string
 )
 
 func init() {
 	flag.StringVar(&ifaceName, "iface", "wg-0", "Name of the interface")
 	flag.StringVar(&networkName, "net", "", "Name of the network")
 	flag.StringVar(&publicIP, "public", "", "Public IP")
-	flag.IntVar(&port, "port", 6666, "Port to use")
-	flag.StringVar(&svcAddr, "controller", "localhost:10000", "Address of the controller")
+	flag.IntVar(&port, "port", 0, "Port to use, defaults to the port set on the network")
+	flag.StringVar(&svcAddr, "controller", "localhost:10000", "Addresses of the controllers, comma separated, the next one is used when one cannot be reached")
 	flag.StringVar(&stateFile, "state", "/tmp/wgagent.state", "Statefile location")
 	flag.StringVar(&keyFile, "key-file", "/tmp/wgagent.key", "Private key file location")
 	flag.StringVar(&authToken, "auth-token", "", "Auth token to talk to the API")
@@ -42,17 +68,99 @@ func init() {
 	flag.StringVar(&certFile, "cert", "", "Cert file to use")
 	flag.StringVar(&certKeyFile, "key", "", "Key file to use")
 	flag.BoolVar(&createBridge, "bridge", false, "Create also a bridge")
+	flag.BoolVar(&keepOnExit, "keep-on-exit", false, "Keep the lease and the interfaces on exit, for quick restarts")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the configuration the agent would apply and exit")
+	flag.StringVar(&dryRunFormat, "dry-run-format", "yaml", "Format of the dry run output, yaml, json or wg-quick")
+	flag.StringVar(&configFile, "config", "", "YAML configuration file, its keys are the names of the flags")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level")
+	flag.DurationVar(&interval, "interval", 10*time.Second, "Interval between two syncs with the controller")
+	flag.StringVar(&socketPath, "socket", "/tmp/wgagent.sock", "Unix socket of the status API")
+	flag.BoolVar(&dhcp, "dhcp", false, "Hand out the addresses of the lease to the clients of the bridge of the -net network, needs -bridge")
+	flag.StringVar(&dnsConfig, "dns-config", dnsConfigNone, "How to point the host to the names of the nodes: none, systemd-resolved or resolv.conf")
+	flag.BoolVar(&dnsForward, "dns-forward", false, "Forward the queries of the host outside of the mesh to the original nameservers, needs -dns-config resolv.conf")
+	flag.StringVar(&resolvConfFile, "resolv-conf", "/etc/resolv.conf", "resolv.conf file to add the agent to with -dns-config resolv.conf")
+	flag.StringVar(&promListenAddress, "listen-prometheus", "", "Address to expose the prometheus metrics on, disabled if empty")
+	flag.StringVar(&wireguardMode, "wireguard-mode", wireguardModeAuto, "auto, kernel or userspace, auto falls back to userspace when the kernel does not support wireguard")
+	flag.DurationVar(&maxBackoff, "max-backoff", 2*time.Minute, "Longest delay between two attempts to sync after failures")
+	flag.StringVar(&namespace, "netns", "", "Name or path of the network namespace to move the interfaces to, the namespace of the agent if empty")
+	flag.DurationVar(&keyRotationInterval, "key-rotation-interval", 0, "Interval between two rotations of the private key, disabled if 0, wgnwd rotate-key rotates it on demand")
+	flag.StringVar(&advertiseRoutes, "advertise-routes", "", "Prefixes reachable through this node in the -net network, comma separated, the peers use them once approved")
+	flag.StringVar(&exitNode, "exit-node", "", "Name of the node to send the internet traffic of the -net network through")
+	flag.BoolVar(&advertiseExitNode, "advertise-exit-node", false, "Offer this node as an exit node of the -net network, it masquerades the traffic of the peers")
+	flag.StringVar(&tags, "tags", "", "Tags of the node in every network, comma separated, the controller exposes them to service discovery")
+	flag.Var(&extraMemberships, "membership", "Additional network to join, as net=<name>[,iface=<name>][,port=<port>][,bridge=<bool>][,public=<ip>][,netns=<name or path>][,route=<prefix>]...[,exit-node=<node>][,advertise-exit-node=<bool>][,dhcp=<bool>][,tag=<tag>]..., can be repeated")
+}
+
+// wait sleeps for the given duration, it returns false if the
+// agent has been asked to stop in the meantime
+func wait(done <-chan struct{}, d time.Duration) bool {
+	select {
+	case <-done:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// splitList splits a comma separated list, ignoring the empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func main() {
+	rand.Seed(time.Now().UnixNano())
 	flag.Parse()
 
-	if networkName == "" {
-		logrus.Fatal("'-net' flag is mandatory")
+	config := common.NewConfig(flag.CommandLine, configFile, "WGNWD_")
+	fc, err := loadConfig(config)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not load the configuration")
+	}
+
+	// wgnwd status, wgnwd peers and wgnwd rotate-key query a running agent
+	if flag.NArg() > 0 {
+		err = queryStatus(socketPath, flag.Arg(0), os.Stdout)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not query the agent")
+		}
+		return
+	}
+
+	var memberships []membership
+	if networkName != "" {
+		memberships = append(memberships, membership{
+			Network: networkName,
+			Iface:   ifaceName,
+			Port:    port,
+			Bridge:  createBridge,
+			Routes:  splitList(advertiseRoutes),
+
+			ExitNode:          exitNode,
+			AdvertiseExitNode: advertiseExitNode,
+			DHCP:              dhcp,
+		})
+	}
+	memberships = append(memberships, extraMemberships...)
+	memberships = append(memberships, fc.Networks...)
+
+	if len(memberships) == 0 {
+		logrus.Fatal("'-net' or '-membership' flag is mandatory")
 	}
 
-	logrus.Infof("Network name: %s", networkName)
-	logrus.Infof("Interface name: %s", ifaceName)
+	err = checkMemberships(memberships)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid memberships")
+	}
+
+	for _, mb := range memberships {
+		logrus.Infof("Network name: %s, interface name: %s", mb.Network, mb.Iface)
+	}
 	logrus.Infof("State file: %s", stateFile)
 	logrus.Infof("Key file: %s", keyFile)
 
@@ -60,8 +168,14 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Warningf("Could not load the statefile %s", stateFile)
 	}
+	store := newStateStore(stateFile, state)
+	store.migrate(memberships[0].Network)
 
-	key, err := getWireguardKey(keyFile)
+	getKey := getWireguardKey
+	if dryRun {
+		getKey = dryRunKey
+	}
+	key, err := getKey(keyFile)
 
 	if err != nil {
 		logrus.WithError(err).Fatal("Could not get private key")
@@ -69,78 +183,135 @@ func main() {
 
 	logrus.Infof("Using public key: %s", key.PublicKey().String())
 
-	c, err := getClient()
+	var networks []string
+	for _, mb := range memberships {
+		networks = append(networks, mb.Network)
+	}
+	keys, err := newKeyManager(keyFile, *key, networks)
 	if err != nil {
-		logrus.WithError(err).Fatal("Could not get a client")
+		logrus.WithError(err).Fatal("Could not load the next private key")
 	}
 
-	var lease *proto.Lease
-	var publicInfo *proto.PublicPeer
+	controllers := newControllerPool(svcAddr)
+	if len(controllers.addresses) == 0 {
+		logrus.Fatal("'-controller' flag is mandatory")
+	}
 
-	if publicIP != "" {
-		publicInfo = &proto.PublicPeer{
-			Address: publicIP,
-			Port:    int32(port),
+	var agents []*agent
+	for _, mb := range memberships {
+		publicAddress := mb.PublicIP
+		if publicAddress == "" {
+			publicAddress = publicIP
+		}
+		ns := mb.Netns
+		if ns == "" {
+			ns = namespace
 		}
+
+		agents = append(agents, &agent{
+			controllers: controllers,
+			keys:        keys,
+			network:     mb.Network,
+			iface:       mb.Iface,
+			bridge:      mb.Bridge,
+			port:        mb.Port,
+			publicIP:    publicAddress,
+			netns:       ns,
+			routes:      mb.Routes,
+			tags:        append(splitList(tags), mb.Tags...),
+			store:       store,
+			state:       store.get(mb.Network),
+
+			exitNode:          mb.ExitNode,
+			advertiseExitNode: mb.AdvertiseExitNode,
+			dhcpEnabled:       mb.DHCP,
+		})
 	}
 
-	err = ensureSysctl()
+	if dryRun {
+		// The host is not touched
+		dnsConfig = dnsConfigNone
+	}
+	dnsResolver, err := newResolver(agents, dnsConfig, resolvConfFile, dnsForward)
 	if err != nil {
-		logrus.WithError(err).Fatal("Could not setup sysctls")
+		logrus.WithError(err).Fatal("Could not set up the DNS resolver")
 	}
+	defer dnsResolver.Close()
 
-	for {
-		lease, err = getOrRenewLease(c, networkName, key.PublicKey().String(), publicInfo, &state)
-		if err != nil || lease == nil {
-			logrus.WithError(err).Error("Could not renew lease, sleeping 10s")
-			time.Sleep(time.Second * 10)
-			continue
-		}
-		err = saveState(stateFile, lease)
+	if dryRun {
+		c, _, err := controllers.get()
 		if err != nil {
-			logrus.WithError(err).Fatal("Could not save the state")
+			logrus.WithError(err).Fatal("Could not get a client")
 		}
 
-		err = ensureInterface(ifaceName)
-		if err != nil {
-			logrus.WithError(err).Fatalf("Could not ensure the interface %s", ifaceName)
+		for i, a := range agents {
+			a.client = c
+			if i > 0 && dryRunFormat == "yaml" {
+				os.Stdout.WriteString("---\n")
+			}
+			err = a.dryRun(os.Stdout, dryRunFormat, keyFile)
+			if err != nil {
+				logrus.WithError(err).Fatalf("Dry run failed for network %s", a.network)
+			}
 		}
+		return
+	}
 
-		if createBridge {
-			err = ensureBridge(fmt.Sprintf("br-%s", ifaceName))
+	// One dataplane per namespace the interfaces live in
+	dataplanes := make(map[string]*kernelDataplane)
+	for _, a := range agents {
+		if dataplanes[a.netns] == nil {
+			dp, err := newKernelDataplane(wireguardMode, a.netns)
 			if err != nil {
-				logrus.WithError(err).Fatalf("Could not ensure the bridge %s", fmt.Sprintf("br-%s", ifaceName))
+				logrus.WithError(err).Fatal("Could not get a wireguard client")
 			}
-		}
+			defer dp.Close()
 
-		config, err := c.FetchConfiguration(getContext(), &proto.ConfigurationRequest{NetworkName: lease.Network})
-		if err != nil {
-			logrus.WithError(err).Error("Could not fetch configuration, will retry in 10s")
-			time.Sleep(10 * time.Second)
-			continue
+			err = dp.EnsureSysctl()
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not setup sysctls")
+			}
+			dataplanes[a.netns] = dp
 		}
+		a.dp = dataplanes[a.netns]
+	}
 
-		err = configureInterface(ifaceName, lease, config)
-		if err != nil {
-			logrus.WithError(err).Error("Could not configure interface, will retry in 10s")
-			time.Sleep(10 * time.Second)
-			continue
-		}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, a := range agents {
+		wg.Add(1)
+		go a.run(done, &wg)
+	}
 
-		if createBridge {
-			err = configureBridge(fmt.Sprintf("br-%s", ifaceName), lease, config)
+	if keyRotationInterval > 0 {
+		go rotateKeys(keys, keyRotationInterval, done)
+	}
+
+	statusServer, err := serveStatus(socketPath, agents, keys)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not start the status API")
+	}
+	defer os.Remove(socketPath)
+	defer statusServer.Close()
+
+	if promListenAddress != "" {
+		serveMetrics(promListenAddress, agents)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			err := reloadConfig(config)
 			if err != nil {
-				logrus.WithError(err).Warning("Could not configure bridge, will retry in 10s")
+				logrus.WithError(err).Error("Could not reload the configuration")
 			}
-		}
-
-		err = configureWireguardInterface(ifaceName, *key, port, config)
-		if err != nil {
-			logrus.WithError(err).Error("Could not apply wireguard configuration, will retry in 10s")
-			time.Sleep(10 * time.Second)
 			continue
 		}
 
-		time.Sleep(time.Second * 10)
+		logrus.Infof("Received %s, shutting down", sig)
+		break
 	}
+	close(done)
+	wg.Wait()
 }
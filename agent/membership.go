@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// membership is a network the agent is a member of
+type membership struct {
+	Network  string `yaml:"network"`
+	Iface    string `yaml:"iface"`
+	Port     int    `yaml:"port"`
+	Bridge   bool   `yaml:"bridge"`
+	PublicIP string `yaml:"public"`
+	Netns    string `yaml:"netns"`
+	// Prefixes reachable through the node, used by the peers once approved
+	Routes []string `yaml:"routes"`
+	// Node to send the internet traffic through
+	ExitNode string `yaml:"exit_node"`
+	// Advertise the default routes and masquerade the traffic of the peers
+	AdvertiseExitNode bool `yaml:"advertise_exit_node"`
+	// Hand out the addresses of the lease on the bridge
+	DHCP bool `yaml:"dhcp"`
+	// Tags of the node in the network, on top of -tags
+	Tags []string `yaml:"tags"`
+}
+
+// membershipFlags is a repeatable flag, each value looks like
+// net=<name>[,iface=<name>][,port=<port>][,bridge=<bool>][,public=<ip>][,netns=<name or path>][,route=<prefix>]...[,exit-node=<node>][,advertise-exit-node=<bool>][,dhcp=<bool>][,tag=<tag>]...
+type membershipFlags []membership
+
+func (m *membershipFlags) String() string {
+	var nets []string
+	for _, mb := range *m {
+		nets = append(nets, mb.Network)
+	}
+	return strings.Join(nets, ",")
+}
+
+func (m *membershipFlags) Set(value string) error {
+	var mb membership
+	for _, field := range strings.Split(value, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid membership field %q, should be key=value", field)
+		}
+
+		var err error
+		switch kv[0] {
+		case "net":
+			mb.Network = kv[1]
+		case "iface":
+			mb.Iface = kv[1]
+		case "port":
+			mb.Port, err = strconv.Atoi(kv[1])
+		case "bridge":
+			mb.Bridge, err = strconv.ParseBool(kv[1])
+		case "public":
+			mb.PublicIP = kv[1]
+		case "netns":
+			mb.Netns = kv[1]
+		case "route":
+			mb.Routes = append(mb.Routes, kv[1])
+		case "tag":
+			mb.Tags = append(mb.Tags, kv[1])
+		case "exit-node":
+			mb.ExitNode = kv[1]
+		case "advertise-exit-node":
+			mb.AdvertiseExitNode, err = strconv.ParseBool(kv[1])
+		case "dhcp":
+			mb.DHCP, err = strconv.ParseBool(kv[1])
+		default:
+			err = fmt.Errorf("unknown membership field %s", kv[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if mb.Network == "" {
+		return fmt.Errorf("membership %q has no network", value)
+	}
+
+	*m = append(*m, mb)
+	return nil
+}
+
+// checkMemberships fills in the default interface names, and makes sure
+// the memberships do not step on each other
+func checkMemberships(memberships []membership) error {
+	networks := make(map[string]bool)
+	ifaces := make(map[string]bool)
+	ports := make(map[int]bool)
+	exitNetwork := ""
+
+	for i := range memberships {
+		mb := &memberships[i]
+		if mb.Iface == "" {
+			mb.Iface = fmt.Sprintf("wg-%d", i)
+		}
+
+		if networks[mb.Network] {
+			return fmt.Errorf("network %s is joined more than once", mb.Network)
+		}
+		if ifaces[mb.Iface] {
+			return fmt.Errorf("interface %s is used by more than one network", mb.Iface)
+		}
+		if mb.Port != 0 && ports[mb.Port] {
+			return fmt.Errorf("port %d is used by more than one network", mb.Port)
+		}
+		if mb.DHCP && !mb.Bridge {
+			return fmt.Errorf("network %s needs a bridge to serve DHCP on", mb.Network)
+		}
+		if mb.ExitNode != "" && mb.AdvertiseExitNode {
+			return fmt.Errorf("network %s cannot both use an exit node and be one", mb.Network)
+		}
+		// There is only one default route
+		if mb.ExitNode != "" && exitNetwork != "" {
+			return fmt.Errorf("networks %s and %s both use an exit node", exitNetwork, mb.Network)
+		} else if mb.ExitNode != "" {
+			exitNetwork = mb.Network
+		}
+		for _, route := range mb.Routes {
+			if _, _, err := net.ParseCIDR(route); err != nil {
+				return fmt.Errorf("invalid route %s for network %s: %s", route, mb.Network, err)
+			}
+		}
+		networks[mb.Network] = true
+		ifaces[mb.Iface] = true
+		ports[mb.Port] = true
+	}
+
+	return nil
+}
+
+// run keeps the membership in sync until done is closed, each membership
+// runs on its own so that a failing network does not hold the others back
+func (a *agent) run(done <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	a.applyCached()
+	retry := backoff{min: time.Second, max: maxBackoff}
+	for {
+		start := time.Now()
+		err := a.syncOnce()
+		a.recordSync(err)
+		a.observeSync(err, time.Since(start))
+
+		delay := syncInterval()
+		if err != nil {
+			delay = retry.next()
+			a.log().WithError(err).Errorf("Could not sync with the controller, will retry in %s", delay.Round(time.Millisecond))
+		} else {
+			retry.reset()
+			// Peers switch keys at the end of the rotation overlap
+			if next := a.nextRotation(); !next.IsZero() && time.Until(next) < delay {
+				delay = time.Until(next) + time.Second
+			}
+		}
+
+		if !wait(done, delay) {
+			a.shutdown(keepInterfaces())
+			return
+		}
+	}
+}
+
+// syncOnce reconciles with the current controller, and moves on to the
+// next one if it could not be reached
+func (a *agent) syncOnce() error {
+	client, addr, err := a.controllers.get()
+	a.controller = addr
+	if err != nil {
+		return err
+	}
+
+	a.client = client
+	err = a.reconcile()
+	if err != nil {
+		a.controllers.failed(addr, err)
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	syncsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wgnwd_syncs_total",
+		Help: "Number of syncs with the controller",
+	}, []string{"network", "result"})
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "wgnwd_sync_duration_seconds",
+		Help: "Duration of the syncs with the controller",
+	}, []string{"network"})
+
+	leaseExpiryDesc = prometheus.NewDesc(
+		"wgnwd_lease_expiry_timestamp_seconds",
+		"Unix timestamp the lease expires at",
+		[]string{"network", "lease"}, nil,
+	)
+	peersDesc = prometheus.NewDesc(
+		"wgnwd_peers",
+		"Number of peers of the interface",
+		[]string{"network", "iface"}, nil,
+	)
+	handshakeTimestampDesc = prometheus.NewDesc(
+		"wgnwd_peer_last_handshake_timestamp_seconds",
+		"Unix timestamp of the last handshake with the peer, 0 if there never was one",
+		[]string{"network", "public_key", "node"}, nil,
+	)
+	handshakeAgeDesc = prometheus.NewDesc(
+		"wgnwd_peer_last_handshake_age_seconds",
+		"Seconds since the last handshake with the peer, only set once a handshake completed",
+		[]string{"network", "public_key", "node"}, nil,
+	)
+	receiveBytesDesc = prometheus.NewDesc(
+		"wgnwd_peer_receive_bytes_total",
+		"Bytes received from the peer",
+		[]string{"network", "public_key", "node"}, nil,
+	)
+	transmitBytesDesc = prometheus.NewDesc(
+		"wgnwd_peer_transmit_bytes_total",
+		"Bytes sent to the peer",
+		[]string{"network", "public_key", "node"}, nil,
+	)
+)
+
+// observeSync records the outcome of a sync
+func (a *agent) observeSync(err error, d time.Duration) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	syncsTotal.WithLabelValues(a.network, result).Inc()
+	syncDuration.WithLabelValues(a.network).Observe(d.Seconds())
+}
+
+// agentCollector reads the lease and the peers of the agents at scrape time
+type agentCollector struct {
+	agents []*agent
+}
+
+func (c *agentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- leaseExpiryDesc
+	ch <- peersDesc
+	ch <- handshakeTimestampDesc
+	ch <- handshakeAgeDesc
+	ch <- receiveBytesDesc
+	ch <- transmitBytesDesc
+}
+
+func (c *agentCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, a := range c.agents {
+		a.statusLock.Lock()
+		lease := a.lease
+		nodes := make(map[string]string)
+		if a.config != nil {
+			for _, endpoint := range a.config.Network.GetEndpoints() {
+				nodes[endpoint.PublicKey] = endpoint.NodeName
+			}
+		}
+		a.statusLock.Unlock()
+
+		if lease != nil {
+			ch <- prometheus.MustNewConstMetric(leaseExpiryDesc, prometheus.GaugeValue, float64(lease.Expires), a.network, lease.Uuid)
+		}
+
+		device, err := a.dp.Device(a.iface)
+		if err != nil {
+			a.log().WithError(err).Debugf("Could not get the peers of %s", a.iface)
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(len(device.Peers)), a.network, a.iface)
+		for _, peer := range device.Peers {
+			labels := []string{a.network, peer.PublicKey.String(), nodes[peer.PublicKey.String()]}
+
+			var handshake float64
+			if !peer.LastHandshakeTime.IsZero() {
+				handshake = float64(peer.LastHandshakeTime.Unix())
+				ch <- prometheus.MustNewConstMetric(handshakeAgeDesc, prometheus.GaugeValue, time.Since(peer.LastHandshakeTime).Seconds(), labels...)
+			}
+			ch <- prometheus.MustNewConstMetric(handshakeTimestampDesc, prometheus.GaugeValue, handshake, labels...)
+			ch <- prometheus.MustNewConstMetric(receiveBytesDesc, prometheus.CounterValue, float64(peer.ReceiveBytes), labels...)
+			ch <- prometheus.MustNewConstMetric(transmitBytesDesc, prometheus.CounterValue, float64(peer.TransmitBytes), labels...)
+		}
+	}
+}
+
+// serveMetrics exposes the metrics of the agents for prometheus
+func serveMetrics(address string, agents []*agent) {
+	prometheus.MustRegister(syncsTotal, syncDuration, &agentCollector{agents: agents})
+
+	http.Handle("/metrics", promhttp.Handler())
+	go func() {
+		logrus.Fatal(http.ListenAndServe(address, nil))
+	}()
+
+	logrus.Infof("Metrics listening on %s", address)
+}
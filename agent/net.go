@@ -5,25 +5,20 @@ import (
 	"net"
 	"os"
 	"syscall"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
-	"golang.zx2c4.com/wireguard/wgctrl"
-	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
-
-	"github.com/thomas-maurice/wgnw/proto"
 )
 
 // ensureInterface makes sure the interface exists and is of the correct type.
 // if not the interface will be destroyed and re-created
-func ensureInterface(name string) error {
-	link, _ := netlink.LinkByName(name)
+func ensureInterface(h *netlink.Handle, name string, mtu int) error {
+	link, _ := h.LinkByName(name)
 
 	if link != nil {
 		if link.Type() != "wireguard" {
 			logrus.Infof("Link %s is not of type 'wireguard', recreating", name)
-			err := netlink.LinkDel(link)
+			err := h.LinkDel(link)
 			if err != nil {
 				logrus.WithError(err).Errorf("Could not remove interface %s", name)
 				return err
@@ -33,21 +28,26 @@ func ensureInterface(name string) error {
 		logrus.Warningf("No such device %s", name)
 	}
 
-	err := netlink.LinkAdd(&wireguard{LinkAttrs: netlink.LinkAttrs{Name: name}})
+	err := h.LinkAdd(&wireguard{LinkAttrs: netlink.LinkAttrs{Name: name}})
 	if err != nil && !os.IsExist(err) {
 		logrus.WithError(err).Errorf("Could not create interface %s", name)
 		return err
 	}
 
-	link, _ = netlink.LinkByName(name)
+	return setLinkUp(h, name, mtu)
+}
+
+// setLinkUp sets the MTU of the interface and brings it up
+func setLinkUp(h *netlink.Handle, name string, mtu int) error {
+	link, _ := h.LinkByName(name)
 	if link == nil {
 		return fmt.Errorf("Could not get a handle on %s", name)
 	}
-	if err := netlink.LinkSetMTU(link, 1420); err != nil {
+	if err := h.LinkSetMTU(link, mtu); err != nil {
 		logrus.WithError(err).Errorf("Could not set MTU for %s", name)
 		return err
 	}
-	if err := netlink.LinkSetUp(link); err != nil {
+	if err := h.LinkSetUp(link); err != nil {
 		logrus.WithError(err).Errorf("Could bring interface %s up", name)
 		return err
 	}
@@ -57,13 +57,13 @@ func ensureInterface(name string) error {
 
 // ensureBridge makes sure the bridge exists and is of the correct type.
 // if not the bridge will be destroyed and re-created
-func ensureBridge(name string) error {
-	link, _ := netlink.LinkByName(name)
+func ensureBridge(h *netlink.Handle, name string) error {
+	link, _ := h.LinkByName(name)
 
 	if link != nil {
 		if link.Type() != "bridge" {
 			logrus.Infof("Link %s is not of type 'bridge', recreating", name)
-			err := netlink.LinkDel(link)
+			err := h.LinkDel(link)
 			if err != nil {
 				logrus.WithError(err).Errorf("Could not remove bridge %s", name)
 				return err
@@ -73,17 +73,17 @@ func ensureBridge(name string) error {
 		logrus.Warningf("No such device %s", name)
 	}
 
-	err := netlink.LinkAdd(&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: name}})
+	err := h.LinkAdd(&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: name}})
 	if err != nil && !os.IsExist(err) {
 		logrus.WithError(err).Errorf("Could not create bridge %s", name)
 		return err
 	}
 
-	link, _ = netlink.LinkByName(name)
+	link, _ = h.LinkByName(name)
 	if link == nil {
 		return fmt.Errorf("Could not get a handle on %s", name)
 	}
-	if err := netlink.LinkSetUp(link); err != nil {
+	if err := h.LinkSetUp(link); err != nil {
 		logrus.WithError(err).Errorf("Could bring bridge %s up", name)
 		return err
 	}
@@ -91,148 +91,136 @@ func ensureBridge(name string) error {
 	return nil
 }
 
-func ensureIPAddress(name string, address *net.IPNet) error {
-	link, err := netlink.LinkByName(name)
-	if err != nil {
-		logrus.Errorf("Could not get a handle on interface %s", name)
-		return err
+// removeInterface deletes the interface if it exists, the routes
+// going through it are removed along with it
+func removeInterface(h *netlink.Handle, name string) error {
+	link, _ := h.LinkByName(name)
+	if link == nil {
+		return nil
 	}
 
-	addrs, err := netlink.AddrList(link, syscall.AF_INET)
+	err := h.LinkDel(link)
 	if err != nil {
-		logrus.Errorf("Could not get addresses on interface %s", name)
+		logrus.WithError(err).Errorf("Could not remove interface %s", name)
 		return err
 	}
-	for _, addr := range addrs {
-		if !addr.IP.Equal(address.IP) || addr.Mask.String() != address.Mask.String() {
-			logrus.Infof("Found address %s attached to %s, we only want %s, removing", addr.IPNet.String(), name, address.String())
-			err = netlink.AddrDel(link, &addr)
-			if err != nil {
-				logrus.WithError(err).Errorf("Could not remove address %s from %s", addr.IPNet.String(), name)
-			}
-		}
-	}
-
-	err = netlink.AddrReplace(link, &netlink.Addr{
-		IPNet: address,
-	})
-
-	if err != nil {
-		logrus.WithError(err).Fatalf("Could not set address %s for %s", address.String(), name)
-	}
 
+	logrus.Infof("Removed interface %s", name)
 	return nil
 }
 
-func configureInterfaceRoute(name string, route *net.IPNet) error {
-	link, err := netlink.LinkByName(name)
+// ensureIPAddresses makes sure the interface has exactly the given addresses
+func ensureIPAddresses(h *netlink.Handle, name string, addresses []*net.IPNet) error {
+	link, err := h.LinkByName(name)
 	if err != nil {
 		logrus.Errorf("Could not get a handle on interface %s", name)
 		return err
 	}
 
-	return netlink.RouteReplace(&netlink.Route{
-		LinkIndex: link.Attrs().Index,
-		Dst:       route,
-		Scope:     netlink.SCOPE_LINK,
-	})
-}
-
-func configureInterface(name string, lease *proto.Lease, config *proto.ConfigurationResponse) error {
-	_, selfNetwork, err := net.ParseCIDR(lease.IpRange)
+	addrs, err := h.AddrList(link, syscall.AF_INET)
 	if err != nil {
-		logrus.WithError(err).Errorf("Could not parse lease address %s", lease.IpRange)
-		return err
-	}
-	_, wgNetwork, err := net.ParseCIDR(config.Network.Address)
-	if err != nil {
-		logrus.WithError(err).Errorf("Could not parse wireguard network address %s", lease.IpRange)
+		logrus.Errorf("Could not get addresses on interface %s", name)
 		return err
 	}
-
-	selfNetCopy := selfNetwork
-	selfNetCopy.Mask = net.IPv4Mask(255, 255, 255, 255)
-	err = ensureIPAddress(name, selfNetCopy)
-	if err != nil {
-		logrus.WithError(err).Errorf("Could not configure interface %s with its address %s", name, selfNetwork.String())
+	for _, addr := range addrs {
+		if !containsIPNet(addresses, addr.IPNet) {
+			logrus.Infof("Found address %s attached to %s, we do not want it, removing", addr.IPNet.String(), name)
+			err = h.AddrDel(link, &addr)
+			if err != nil {
+				logrus.WithError(err).Errorf("Could not remove address %s from %s", addr.IPNet.String(), name)
+			}
+		}
 	}
 
-	err = configureInterfaceRoute(name, wgNetwork)
-	if err != nil {
-		logrus.WithError(err).Errorf("Could not configure interface %s with the wireguard route %s", name, wgNetwork.String())
+	for _, address := range addresses {
+		err = h.AddrReplace(link, &netlink.Addr{
+			IPNet: address,
+		})
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not set address %s for %s", address.String(), name)
+			return err
+		}
 	}
 
 	return nil
 }
 
-func configureBridge(name string, lease *proto.Lease, config *proto.ConfigurationResponse) error {
-	_, selfNetwork, err := net.ParseCIDR(lease.IpRange)
+// ensureInterfaceRoutes makes sure the given routes go through the interface, and
+// removes the routes we previously added that are not wanted anymore
+func ensureInterfaceRoutes(h *netlink.Handle, name string, routes []*net.IPNet) error {
+	link, err := h.LinkByName(name)
 	if err != nil {
-		logrus.WithError(err).Errorf("Could not parse lease address %s", lease.IpRange)
+		logrus.Errorf("Could not get a handle on interface %s", name)
 		return err
 	}
-	if ones, _ := selfNetwork.Mask.Size(); ones > 31 {
-		logrus.Warningf("Cannot assign an IP address to the bridge for network %s, network is too small", selfNetwork.String())
-		return nil
-	}
-	selfNetwork.IP[3]++
 
-	err = ensureIPAddress(name, selfNetwork)
+	existing, err := h.RouteList(link, netlink.FAMILY_V4)
 	if err != nil {
-		logrus.WithError(err).Errorf("Could not configure bridge %s with its address %s", name, selfNetwork.String())
+		logrus.Errorf("Could not get routes of interface %s", name)
 		return err
 	}
-
-	return nil
-}
-
-func configureWireguardInterface(name string, key wgtypes.Key, port int, config *proto.ConfigurationResponse) error {
-	client, err := wgctrl.New()
-	if err != nil {
-		logrus.Fatal(err)
+	for _, route := range existing {
+		// Leave the routes the kernel manages and the default route alone,
+		// netlink reports the latter with a 0.0.0.0/0 destination
+		if route.Dst == nil || route.Protocol == syscall.RTPROT_KERNEL || containsIPNet(routes, route.Dst) {
+			continue
+		}
+		if ones, _ := route.Dst.Mask.Size(); ones == 0 {
+			continue
+		}
+		logrus.Infof("Found route %s through %s, we do not want it, removing", route.Dst.String(), name)
+		err = h.RouteDel(&route)
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not remove route %s from %s", route.Dst.String(), name)
+		}
 	}
 
-	var peers []wgtypes.PeerConfig
-	for _, endpoint := range config.Network.Endpoints {
-		// TODO: Make it configurable
-		keepaliveDuration := 5 * time.Second
+	var current []*net.IPNet
+	for _, route := range existing {
+		if route.Dst != nil {
+			current = append(current, route.Dst)
+		}
+	}
 
-		var peerIPs []net.IPNet
-		peerKey, err := wgtypes.ParseKey(endpoint.PublicKey)
-		var udpEndpoint *net.UDPAddr
-		if endpoint.Peer != nil {
-			udpEndpoint = &net.UDPAddr{
-				IP:   net.ParseIP(endpoint.Peer.Address),
-				Port: int(endpoint.Peer.Port),
-			}
+	for _, route := range routes {
+		if containsIPNet(current, route) {
+			continue
 		}
+
+		// A route the host already has, like the LAN the node is on, wins
+		// over the one advertised by a peer
+		others, err := h.RouteListFiltered(netlink.FAMILY_V4, &netlink.Route{
+			Dst:   route,
+			Table: syscall.RT_TABLE_MAIN,
+		}, netlink.RT_FILTER_DST|netlink.RT_FILTER_TABLE)
 		if err != nil {
-			logrus.WithError(err).Warningf("Could not parse peer key %s, skipping", endpoint.PublicKey)
-			continue
+			logrus.WithError(err).Errorf("Could not get the routes to %s", route.String())
+			return err
 		}
-		for _, nw := range endpoint.Networks {
-			_, peerNet, err := net.ParseCIDR(nw)
-			if peerNet != nil {
-				peerIPs = append(peerIPs, *peerNet)
-			}
-			if err != nil {
-				logrus.WithError(err).Warningf("Could not parse peer network %s, skipping", nw)
-			}
+		if len(others) != 0 {
+			logrus.Warningf("%s is already routed through another interface, not routing it through %s", route.String(), name)
+			continue
 		}
 
-		peers = append(peers, wgtypes.PeerConfig{
-			PublicKey:                   peerKey,
-			PersistentKeepaliveInterval: &keepaliveDuration,
-			ReplaceAllowedIPs:           true,
-			AllowedIPs:                  peerIPs,
-			Endpoint:                    udpEndpoint,
+		err = h.RouteAdd(&netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       route,
+			Scope:     netlink.SCOPE_LINK,
 		})
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not add route %s through %s", route.String(), name)
+			return err
+		}
 	}
 
-	return client.ConfigureDevice(ifaceName, wgtypes.Config{
-		PrivateKey:   &key,
-		ListenPort:   &port,
-		ReplacePeers: true,
-		Peers:        peers,
-	})
+	return nil
+}
+
+func containsIPNet(nets []*net.IPNet, n *net.IPNet) bool {
+	for _, candidate := range nets {
+		if candidate.IP.Equal(n.IP) && candidate.Mask.String() == n.Mask.String() {
+			return true
+		}
+	}
+	return false
 }
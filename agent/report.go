@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// reportStatus tells the controller how the tunnels to our peers are doing
+func (a *agent) reportStatus(lease *proto.Lease) error {
+	device, err := a.dp.Device(a.iface)
+	if err != nil {
+		return err
+	}
+
+	var peers []*proto.PeerReport
+	for _, peer := range device.Peers {
+		report := &proto.PeerReport{
+			PublicKey: peer.PublicKey.String(),
+			Handshake: !peer.LastHandshakeTime.IsZero(),
+			RxBytes:   peer.ReceiveBytes,
+			TxBytes:   peer.TransmitBytes,
+		}
+		if report.Handshake {
+			report.HandshakeAge = int64(time.Since(peer.LastHandshakeTime).Seconds())
+		}
+		if peer.Endpoint != nil {
+			report.Endpoint = peer.Endpoint.String()
+		}
+		peers = append(peers, report)
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+	_, err = a.client.ReportStatus(ctx, &proto.ReportStatusRequest{
+		LeaseUuid: lease.Uuid,
+		Peers:     peers,
+	})
+	return err
+}
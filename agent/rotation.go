@@ -0,0 +1,129 @@
+package main
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/common"
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// rotateKey asks the controller to move the lease to the next key if a
+// rotation is in progress, and switches to it once the rotation is due
+func (a *agent) rotateKey(lease *proto.Lease) (*proto.Lease, error) {
+	next := a.keys.pending()
+	if next == nil || a.switchKey(lease) {
+		return lease, nil
+	}
+
+	nextPublicKey := next.PublicKey().String()
+	if lease.NextPublicKey == nextPublicKey {
+		// Waiting for the peers to fetch the next key, then for the overlap
+		return lease, nil
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+	controllerKey, err := a.client.GetControllerKey(ctx, &empty.Empty{})
+	if err != nil {
+		return lease, err
+	}
+	controllerPublicKey, err := wgtypes.ParseKey(controllerKey.PublicKey)
+	if err != nil {
+		return lease, err
+	}
+
+	proof, err := common.KeyRotationProof(a.keys.key(a.network), controllerPublicKey, lease.Uuid, nextPublicKey)
+	if err != nil {
+		return lease, err
+	}
+
+	ctx, cancel = getContext()
+	defer cancel()
+	resp, err := a.client.RotateLeaseKey(ctx, &proto.RotateLeaseKeyRequest{
+		Uuid:          lease.Uuid,
+		NextPublicKey: nextPublicKey,
+		Proof:         proof,
+	})
+	if err != nil {
+		return lease, err
+	}
+
+	a.log().Infof("Lease %s switches to the public key %s once every peer has it", lease.Uuid, nextPublicKey)
+	return resp.Lease, nil
+}
+
+// switchKey moves the membership to the next key if the lease already
+// uses it, or if its rotation is due. It returns true if the membership
+// uses the next key.
+func (a *agent) switchKey(lease *proto.Lease) bool {
+	next := a.keys.pending()
+	if next == nil {
+		return false
+	}
+	if a.keys.key(a.network) == *next {
+		return true
+	}
+
+	nextPublicKey := next.PublicKey().String()
+	due := lease.NextPublicKey == nextPublicKey && lease.RotateAt != 0 && lease.RotateAt <= time.Now().Unix()
+	if lease.PublicKey != nextPublicKey && !due {
+		return false
+	}
+
+	err := a.keys.switchKey(a.network)
+	if err != nil {
+		a.log().WithError(err).Error("Could not save the rotated key")
+	}
+	return true
+}
+
+// nextRotation returns when the next pending key rotation in the network
+// is due, so that the agent syncs right after it, or the zero time if none
+func (a *agent) nextRotation() time.Time {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	var rotateAts []int64
+	if a.lease != nil && a.lease.NextPublicKey != "" {
+		rotateAts = append(rotateAts, a.lease.RotateAt)
+	}
+	if a.config != nil {
+		for _, endpoint := range a.config.Network.GetEndpoints() {
+			if endpoint.NextPublicKey != "" {
+				rotateAts = append(rotateAts, endpoint.RotateAt)
+			}
+		}
+	}
+
+	var next time.Time
+	now := time.Now()
+	for _, rotateAt := range rotateAts {
+		t := time.Unix(rotateAt, 0)
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// rotateKeys starts a key rotation every interval
+func rotateKeys(keys *keyManager, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := keys.rotate()
+			if err != nil {
+				logrus.WithError(err).Error("Could not start the key rotation")
+			}
+		}
+	}
+}
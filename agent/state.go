@@ -3,21 +3,60 @@ package main
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/thomas-maurice/wgnw/proto"
 )
 
-type State struct {
+// NetworkState is the state of the membership of a network
+type NetworkState struct {
 	LeaseUUID string `json:"lease_uuid"`
+	// Lease and Configuration are the last ones we got from the
+	// controller, they are applied at boot if it cannot be reached
+	Lease         *proto.Lease                 `json:"lease,omitempty"`
+	Configuration *proto.ConfigurationResponse `json:"configuration,omitempty"`
+}
+
+// State is what the agent keeps between runs
+type State struct {
+	// LeaseUUID is only read from the state files written before
+	// the agent could join several networks
+	LeaseUUID string                   `json:"lease_uuid,omitempty"`
+	Networks  map[string]*NetworkState `json:"networks"`
+
+	// Containers are the addresses the CNI plugin got from the bridges,
+	// by network and by container interface
+	Containers map[string]map[string]string `json:"containers,omitempty"`
 }
 
-func saveState(filename string, lease *proto.Lease) error {
-	b, err := json.Marshal(&State{LeaseUUID: lease.Uuid})
+// saveState writes the state to a temporary file and renames it, so
+// that a crash never leaves a truncated state file behind
+func saveState(filename string, state *State) error {
+	b, err := json.Marshal(state)
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(filename, b, 0600)
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
 }
 
 func loadState(filename string) (State, error) {
@@ -30,3 +69,90 @@ func loadState(filename string) (State, error) {
 	err = json.Unmarshal(b, &state)
 	return state, err
 }
+
+// stateStore saves the state of every membership in the same file
+type stateStore struct {
+	sync.Mutex
+	filename string
+	state    State
+}
+
+func newStateStore(filename string, state State) *stateStore {
+	if state.Networks == nil {
+		state.Networks = make(map[string]*NetworkState)
+	}
+
+	return &stateStore{
+		filename: filename,
+		state:    state,
+	}
+}
+
+// migrate gives the lease of an old state file to the network
+func (s *stateStore) migrate(network string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.state.LeaseUUID != "" && s.state.Networks[network] == nil {
+		s.state.Networks[network] = &NetworkState{LeaseUUID: s.state.LeaseUUID}
+	}
+	s.state.LeaseUUID = ""
+}
+
+// get returns a copy of the state of the network
+func (s *stateStore) get(network string) NetworkState {
+	s.Lock()
+	defer s.Unlock()
+
+	if state, ok := s.state.Networks[network]; ok {
+		return *state
+	}
+	return NetworkState{}
+}
+
+// update sets the state of the network and saves the state file
+func (s *stateStore) update(network string, state NetworkState) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.state.Networks[network] = &state
+	return saveState(s.filename, &s.state)
+}
+
+// containers returns a copy of the addresses of the containers of the network
+func (s *stateStore) containers(network string) map[string]string {
+	s.Lock()
+	defer s.Unlock()
+
+	containers := make(map[string]string)
+	for owner, address := range s.state.Containers[network] {
+		containers[owner] = address
+	}
+	return containers
+}
+
+// setContainer sets the address of a container interface of the network,
+// or removes it if empty, and saves the state file
+func (s *stateStore) setContainer(network string, owner string, address string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if address == "" {
+		if _, ok := s.state.Containers[network][owner]; !ok {
+			return nil
+		}
+		delete(s.state.Containers[network], owner)
+		if len(s.state.Containers[network]) == 0 {
+			delete(s.state.Containers, network)
+		}
+	} else {
+		if s.state.Containers == nil {
+			s.state.Containers = make(map[string]map[string]string)
+		}
+		if s.state.Containers[network] == nil {
+			s.state.Containers[network] = make(map[string]string)
+		}
+		s.state.Containers[network][owner] = address
+	}
+	return saveState(s.filename, &s.state)
+}
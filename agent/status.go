@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// syncStatus is the outcome of the last syncs with the controller
+type syncStatus struct {
+	lastAttempt time.Time
+	lastSync    time.Time
+	lastError   error
+	connected   bool
+	controller  string
+}
+
+// networkStatus is what the status API returns for each membership
+type networkStatus struct {
+	Network      string     `json:"network" yaml:"network"`
+	Interface    string     `json:"interface" yaml:"interface"`
+	Netns        string     `json:"netns,omitempty" yaml:"netns,omitempty"`
+	LeaseUUID    string     `json:"lease_uuid,omitempty" yaml:"lease_uuid,omitempty"`
+	IPRange      string     `json:"ip_range,omitempty" yaml:"ip_range,omitempty"`
+	NextIPRange  string     `json:"next_ip_range,omitempty" yaml:"next_ip_range,omitempty"`
+	LeaseExpires *time.Time `json:"lease_expires,omitempty" yaml:"lease_expires,omitempty"`
+	PublicKey    string     `json:"public_key" yaml:"public_key"`
+	// Set while the key of the lease is being rotated
+	NextPublicKey string     `json:"next_public_key,omitempty" yaml:"next_public_key,omitempty"`
+	RotateAt      *time.Time `json:"rotate_at,omitempty" yaml:"rotate_at,omitempty"`
+	Controller    string     `json:"controller,omitempty" yaml:"controller,omitempty"`
+	Connected     bool       `json:"connected" yaml:"connected"`
+	LastAttempt   *time.Time `json:"last_attempt,omitempty" yaml:"last_attempt,omitempty"`
+	LastSync      *time.Time `json:"last_sync,omitempty" yaml:"last_sync,omitempty"`
+	LastError     string     `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+}
+
+// keyRotation is the key rotation in progress
+type keyRotation struct {
+	PublicKey     string   `json:"public_key" yaml:"public_key"`
+	NextPublicKey string   `json:"next_public_key,omitempty" yaml:"next_public_key,omitempty"`
+	Switched      []string `json:"switched,omitempty" yaml:"switched,omitempty"`
+}
+
+// peerStatus is what the kernel knows about a peer
+type peerStatus struct {
+	PublicKey     string     `json:"public_key" yaml:"public_key"`
+	Endpoint      string     `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	AllowedIPs    []string   `json:"allowed_ips" yaml:"allowed_ips"`
+	LastHandshake *time.Time `json:"last_handshake,omitempty" yaml:"last_handshake,omitempty"`
+	ReceiveBytes  int64      `json:"rx_bytes" yaml:"rx_bytes"`
+	TransmitBytes int64      `json:"tx_bytes" yaml:"tx_bytes"`
+}
+
+// networkPeers are the peers of the interface of a membership
+type networkPeers struct {
+	Network   string       `json:"network" yaml:"network"`
+	Interface string       `json:"interface" yaml:"interface"`
+	Peers     []peerStatus `json:"peers" yaml:"peers"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// recordSync remembers the outcome of a sync for the status API
+func (a *agent) recordSync(err error) {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	a.syncs.lastAttempt = time.Now()
+	a.syncs.lastError = err
+	a.syncs.connected = a.connected
+	a.syncs.controller = a.controller
+	if err == nil {
+		a.syncs.lastSync = a.syncs.lastAttempt
+	}
+	a.lease = a.state.Lease
+	a.config = a.state.Configuration
+}
+
+func (a *agent) status() networkStatus {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	status := networkStatus{
+		Network:     a.network,
+		Interface:   a.iface,
+		Netns:       a.netns,
+		PublicKey:   a.keys.key(a.network).PublicKey().String(),
+		Controller:  a.syncs.controller,
+		Connected:   a.syncs.connected,
+		LastAttempt: optionalTime(a.syncs.lastAttempt),
+		LastSync:    optionalTime(a.syncs.lastSync),
+	}
+	if a.syncs.lastError != nil {
+		status.LastError = a.syncs.lastError.Error()
+	}
+	if a.lease != nil {
+		status.LeaseUUID = a.lease.Uuid
+		status.IPRange = a.lease.IpRange
+		status.NextIPRange = a.lease.NextIpRange
+		status.LeaseExpires = optionalTime(time.Unix(a.lease.Expires, 0))
+		status.NextPublicKey = a.lease.NextPublicKey
+		if a.lease.RotateAt != 0 {
+			status.RotateAt = optionalTime(time.Unix(a.lease.RotateAt, 0))
+		}
+	}
+
+	return status
+}
+
+func (a *agent) peers() (networkPeers, error) {
+	result := networkPeers{
+		Network:   a.network,
+		Interface: a.iface,
+		Peers:     []peerStatus{},
+	}
+
+	device, err := a.dp.Device(a.iface)
+	if err != nil {
+		return result, err
+	}
+
+	for _, peer := range device.Peers {
+		status := peerStatus{
+			PublicKey:     peer.PublicKey.String(),
+			LastHandshake: optionalTime(peer.LastHandshakeTime),
+			ReceiveBytes:  peer.ReceiveBytes,
+			TransmitBytes: peer.TransmitBytes,
+		}
+		if peer.Endpoint != nil {
+			status.Endpoint = peer.Endpoint.String()
+		}
+		for _, ip := range peer.AllowedIPs {
+			status.AllowedIPs = append(status.AllowedIPs, ip.String())
+		}
+		result.Peers = append(result.Peers, status)
+	}
+
+	return result, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logrus.WithError(err).Warning("Could not write the status response")
+	}
+}
+
+// serveStatus serves the status of the agents on a unix socket
+func serveStatus(socket string, agents []*agent, keys *keyManager) (io.Closer, error) {
+	err := os.Remove(socket)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(socket, 0660)
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		var statuses []networkStatus
+		for _, a := range agents {
+			statuses = append(statuses, a.status())
+		}
+		writeJSON(w, statuses)
+	})
+	mux.HandleFunc("/rotate-key", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		err := keys.rotate()
+		if err != nil {
+			logrus.WithError(err).Error("Could not start the key rotation")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, keys.rotation())
+	})
+	mux.HandleFunc("/peers", func(w http.ResponseWriter, r *http.Request) {
+		var peers []networkPeers
+		for _, a := range agents {
+			p, err := a.peers()
+			if err != nil {
+				a.log().WithError(err).Warningf("Could not get the peers of %s", a.iface)
+			}
+			peers = append(peers, p)
+		}
+		writeJSON(w, peers)
+	})
+	mux.HandleFunc("/ipam/", func(w http.ResponseWriter, r *http.Request) {
+		serveIPAM(w, r, agents)
+	})
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		err := srv.Serve(lis)
+		if err != nil && err != http.ErrServerClosed {
+			logrus.WithError(err).Error("Status API stopped")
+		}
+	}()
+
+	logrus.Infof("Status API listening on %s", socket)
+	return srv, nil
+}
+
+// queryStatus asks a running agent for its status and prints it
+func queryStatus(socket string, command string, w io.Writer) error {
+	var path string
+	var result interface{}
+	method := http.MethodGet
+	switch command {
+	case "status":
+		path = "/status"
+		result = &[]networkStatus{}
+	case "peers":
+		path = "/peers"
+		result = &[]networkPeers{}
+	case "rotate-key":
+		path = "/rotate-key"
+		method = http.MethodPost
+		result = &keyRotation{}
+	default:
+		return fmt.Errorf("unknown command %s, should be status, peers or rotate-key", command)
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	req, err := http.NewRequest(method, "http://wgnwd"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent answered %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
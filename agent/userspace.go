@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/ipc"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+const (
+	wireguardModeAuto      = "auto"
+	wireguardModeKernel    = "kernel"
+	wireguardModeUserspace = "userspace"
+)
+
+// userspaceDevice is a wireguard-go device running in the agent on a TUN
+// interface. It serves the same UAPI socket as the wireguard-go binary,
+// so wgctrl configures it like a kernel device.
+type userspaceDevice struct {
+	device *device.Device
+	uapi   net.Listener
+}
+
+func newUserspaceDevice(name string, mtu int) (*userspaceDevice, error) {
+	tunDevice, err := tun.CreateTUN(name, mtu)
+	if err != nil {
+		return nil, fmt.Errorf("could not create TUN interface %s: %s", name, err)
+	}
+
+	logger := device.NewLogger(device.LogLevelError, fmt.Sprintf("(%s) ", name))
+	dev := device.NewDevice(tunDevice, logger)
+
+	uapiFile, err := ipc.UAPIOpen(name)
+	if err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("could not open the UAPI socket of %s: %s", name, err)
+	}
+
+	uapi, err := ipc.UAPIListen(name, uapiFile)
+	if err != nil {
+		uapiFile.Close()
+		dev.Close()
+		return nil, fmt.Errorf("could not listen on the UAPI socket of %s: %s", name, err)
+	}
+
+	go func() {
+		for {
+			conn, err := uapi.Accept()
+			if err != nil {
+				return
+			}
+			go dev.IpcHandle(conn)
+		}
+	}()
+
+	logrus.Infof("Created userspace wireguard interface %s", name)
+	return &userspaceDevice{
+		device: dev,
+		uapi:   uapi,
+	}, nil
+}
+
+// Close stops the device, which removes its TUN interface
+func (u *userspaceDevice) Close() {
+	u.uapi.Close()
+	u.device.Close()
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+// endpointKey returns the key the endpoint uses right now, the next
+// one once its rotation is scheduled and due
+func endpointKey(endpoint *proto.Endpoint) string {
+	if endpoint.NextPublicKey != "" && endpoint.RotateAt != 0 && endpoint.RotateAt <= time.Now().Unix() {
+		return endpoint.NextPublicKey
+	}
+	return endpoint.PublicKey
+}
+
+// desiredPeers builds the peer configurations for every endpoint of the network
+// but ourselves. The exit node, if any, gets the default routes. A key held by
+// several leases, like an agent restarted without its state, gets the
+// configuration of the newest one, the controller lists them last.
+func desiredPeers(self wgtypes.Key, config *proto.ConfigurationResponse, exitNode string) []wgtypes.PeerConfig {
+	keepaliveDuration := time.Duration(config.Network.GetSettings().GetPersistentKeepalive()) * time.Second
+
+	var peers []wgtypes.PeerConfig
+	seen := make(map[wgtypes.Key]int)
+	for _, endpoint := range config.Network.Endpoints {
+		var peerIPs []net.IPNet
+		peerKey, err := wgtypes.ParseKey(endpointKey(endpoint))
+		var udpEndpoint *net.UDPAddr
+		if endpoint.Peer != nil {
+			udpEndpoint = &net.UDPAddr{
+				IP:   net.ParseIP(endpoint.Peer.Address),
+				Port: int(endpoint.Peer.Port),
+			}
+		}
+		if err != nil {
+			logrus.WithError(err).Warningf("Could not parse peer key %s, skipping", endpointKey(endpoint))
+			continue
+		}
+		// Our own endpoint, with either key while we rotate
+		if endpoint.PublicKey == self.String() || endpoint.NextPublicKey == self.String() {
+			continue
+		}
+		for _, nw := range endpoint.Networks {
+			_, peerNet, err := net.ParseCIDR(nw)
+			if peerNet != nil {
+				peerIPs = append(peerIPs, *peerNet)
+			}
+			if err != nil {
+				logrus.WithError(err).Warningf("Could not parse peer network %s, skipping", nw)
+			}
+		}
+
+		if exitNode != "" && endpoint.NodeName == exitNode && endpoint.ExitNode {
+			for _, route := range defaultRoutes {
+				_, defaultNet, _ := net.ParseCIDR(route)
+				peerIPs = append(peerIPs, *defaultNet)
+			}
+		}
+
+		// The zero key clears the preshared key of the peer
+		var presharedKey wgtypes.Key
+		if endpoint.PresharedKey != "" {
+			presharedKey, err = wgtypes.ParseKey(endpoint.PresharedKey)
+			if err != nil {
+				logrus.WithError(err).Warningf("Could not parse the preshared key of peer %s, skipping", peerKey)
+				continue
+			}
+		}
+
+		peer := wgtypes.PeerConfig{
+			PublicKey:                   peerKey,
+			PresharedKey:                &presharedKey,
+			PersistentKeepaliveInterval: &keepaliveDuration,
+			ReplaceAllowedIPs:           true,
+			AllowedIPs:                  peerIPs,
+			Endpoint:                    udpEndpoint,
+		}
+		if i, found := seen[peerKey]; found {
+			peers[i] = peer
+			continue
+		}
+		seen[peerKey] = len(peers)
+		peers = append(peers, peer)
+	}
+
+	return peers
+}
+
+func sameEndpoint(a, b *net.UDPAddr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.IP.Equal(b.IP) && a.Port == b.Port
+}
+
+func sameAllowedIPs(a, b []net.IPNet) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	var as, bs []string
+	for i := range a {
+		as = append(as, a[i].String())
+		bs = append(bs, b[i].String())
+	}
+	sort.Strings(as)
+	sort.Strings(bs)
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// diffPeers returns the peer configurations needed to go from the current
+// peers of the device to the desired ones. Peers that are already
+// configured as desired are left out.
+func diffPeers(current []wgtypes.Peer, desired []wgtypes.PeerConfig) []wgtypes.PeerConfig {
+	existing := make(map[wgtypes.Key]wgtypes.Peer)
+	for _, peer := range current {
+		existing[peer.PublicKey] = peer
+	}
+
+	var changes []wgtypes.PeerConfig
+	wanted := make(map[wgtypes.Key]bool)
+	for _, peer := range desired {
+		wanted[peer.PublicKey] = true
+
+		old, found := existing[peer.PublicKey]
+		if !found {
+			logrus.Infof("Adding peer %s, allowed IPs %v, endpoint %v", peer.PublicKey, peer.AllowedIPs, peer.Endpoint)
+			changes = append(changes, peer)
+			continue
+		}
+
+		change := wgtypes.PeerConfig{
+			PublicKey:  peer.PublicKey,
+			UpdateOnly: true,
+		}
+		changed := false
+		// A nil endpoint means the peer is behind a NAT, in which case
+		// we keep whatever endpoint it roamed from
+		if peer.Endpoint != nil && !sameEndpoint(peer.Endpoint, old.Endpoint) {
+			logrus.Infof("Peer %s endpoint changed from %v to %v", peer.PublicKey, old.Endpoint, peer.Endpoint)
+			change.Endpoint = peer.Endpoint
+			changed = true
+		}
+		if !sameAllowedIPs(peer.AllowedIPs, old.AllowedIPs) {
+			logrus.Infof("Peer %s allowed IPs changed from %v to %v", peer.PublicKey, old.AllowedIPs, peer.AllowedIPs)
+			change.ReplaceAllowedIPs = true
+			change.AllowedIPs = peer.AllowedIPs
+			changed = true
+		}
+		if peer.PersistentKeepaliveInterval != nil && *peer.PersistentKeepaliveInterval != old.PersistentKeepaliveInterval {
+			logrus.Infof("Peer %s keepalive changed from %s to %s", peer.PublicKey, old.PersistentKeepaliveInterval, *peer.PersistentKeepaliveInterval)
+			change.PersistentKeepaliveInterval = peer.PersistentKeepaliveInterval
+			changed = true
+		}
+		if peer.PresharedKey != nil && *peer.PresharedKey != old.PresharedKey {
+			logrus.Infof("Peer %s preshared key changed", peer.PublicKey)
+			change.PresharedKey = peer.PresharedKey
+			changed = true
+		}
+		if changed {
+			changes = append(changes, change)
+		}
+	}
+
+	for _, peer := range current {
+		if !wanted[peer.PublicKey] {
+			logrus.Infof("Removing peer %s", peer.PublicKey)
+			changes = append(changes, wgtypes.PeerConfig{
+				PublicKey: peer.PublicKey,
+				Remove:    true,
+			})
+		}
+	}
+
+	return changes
+}
+
+// configureWireguardInterface applies the difference between the current
+// configuration of the device and the desired one, nothing is written
+// if the device is already configured as desired. The packets of the
+// tunnel are marked when the traffic goes through an exit node.
+func configureWireguardInterface(dp dataplane, name string, key wgtypes.Key, port int, exitNode string, config *proto.ConfigurationResponse) error {
+	device, err := dp.Device(name)
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not get the configuration of %s", name)
+		return err
+	}
+
+	wgConfig := wgtypes.Config{
+		Peers: diffPeers(device.Peers, desiredPeers(key.PublicKey(), config, exitNode)),
+	}
+	changed := len(wgConfig.Peers) != 0
+
+	if device.PrivateKey != key {
+		logrus.Infof("Setting the private key of %s", name)
+		wgConfig.PrivateKey = &key
+		changed = true
+	}
+	fwmark := 0
+	if exitNode != "" {
+		fwmark = exitTable
+	}
+	if device.FirewallMark != fwmark {
+		logrus.Infof("Firewall mark of %s changed from %d to %d", name, device.FirewallMark, fwmark)
+		wgConfig.FirewallMark = &fwmark
+		changed = true
+	}
+	if device.ListenPort != port {
+		logrus.Infof("Listen port of %s changed from %d to %d", name, device.ListenPort, port)
+		wgConfig.ListenPort = &port
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return dp.ConfigureDevice(name, wgConfig)
+}
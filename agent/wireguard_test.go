@@ -0,0 +1,287 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+func mustKey(t *testing.T) wgtypes.Key {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustNets(t *testing.T, cidrs ...string) []net.IPNet {
+	t.Helper()
+	var nets []net.IPNet
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets = append(nets, *n)
+	}
+	return nets
+}
+
+// newTestAgent returns an agent of network lab on a fake dataplane
+func newTestAgent(t *testing.T) (*agent, *fakeDataplane) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "wgnw-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	keys, err := newKeyManager(filepath.Join(dir, "key"), mustKey(t), []string{"lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dp := newFakeDataplane()
+	return &agent{
+		dp:      dp,
+		keys:    keys,
+		network: "lab",
+		iface:   "wg-test",
+		port:    51820,
+	}, dp
+}
+
+// testConfig returns the configuration of network lab with the endpoints
+func testConfig(endpoints ...*proto.Endpoint) *proto.ConfigurationResponse {
+	return &proto.ConfigurationResponse{
+		Network: &proto.NetworkDefinition{
+			Name:      "lab",
+			Address:   "10.60.0.0/16",
+			Endpoints: endpoints,
+			Settings: &proto.NetworkSettings{
+				Mtu:                 1380,
+				PersistentKeepalive: 25,
+			},
+		},
+	}
+}
+
+func testEndpoint(t *testing.T, name string, ipRange string, public string) *proto.Endpoint {
+	endpoint := &proto.Endpoint{
+		NodeName:  name,
+		PublicKey: mustKey(t).PublicKey().String(),
+		Networks:  []string{ipRange},
+	}
+	if public != "" {
+		endpoint.Peer = &proto.PublicPeer{Address: public, Port: 51820}
+	}
+	return endpoint
+}
+
+func TestDiffPeers(t *testing.T) {
+	a := mustKey(t).PublicKey()
+	b := mustKey(t).PublicKey()
+	psk := mustKey(t)
+	keepalive := 25 * time.Second
+	otherKeepalive := 10 * time.Second
+	endpoint := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 51820}
+	otherEndpoint := &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 51820}
+
+	current := func() []wgtypes.Peer {
+		return []wgtypes.Peer{{
+			PublicKey:                   a,
+			Endpoint:                    endpoint,
+			AllowedIPs:                  mustNets(t, "10.60.16.0/20", "192.168.1.0/24"),
+			PersistentKeepaliveInterval: keepalive,
+		}}
+	}
+	desired := func() wgtypes.PeerConfig {
+		var zero wgtypes.Key
+		return wgtypes.PeerConfig{
+			PublicKey:                   a,
+			Endpoint:                    endpoint,
+			AllowedIPs:                  mustNets(t, "10.60.16.0/20", "192.168.1.0/24"),
+			PersistentKeepaliveInterval: &keepalive,
+			PresharedKey:                &zero,
+			ReplaceAllowedIPs:           true,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		current []wgtypes.Peer
+		desired func() []wgtypes.PeerConfig
+		check   func(t *testing.T, changes []wgtypes.PeerConfig)
+	}{
+		{
+			name:    "unchanged",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig { return []wgtypes.PeerConfig{desired()} },
+		},
+		{
+			name:    "allowed IPs in another order",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				p := desired()
+				p.AllowedIPs = mustNets(t, "192.168.1.0/24", "10.60.16.0/20")
+				return []wgtypes.PeerConfig{p}
+			},
+		},
+		{
+			name:    "no endpoint keeps the roamed one",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				p := desired()
+				p.Endpoint = nil
+				return []wgtypes.PeerConfig{p}
+			},
+		},
+		{
+			name:    "new peer",
+			current: nil,
+			desired: func() []wgtypes.PeerConfig { return []wgtypes.PeerConfig{desired()} },
+			check: func(t *testing.T, changes []wgtypes.PeerConfig) {
+				if changes[0].UpdateOnly || !changes[0].ReplaceAllowedIPs || len(changes[0].AllowedIPs) != 2 {
+					t.Errorf("expected the full configuration of the peer, got %+v", changes[0])
+				}
+			},
+		},
+		{
+			name:    "removed peer",
+			current: append(current(), wgtypes.Peer{PublicKey: b}),
+			desired: func() []wgtypes.PeerConfig { return []wgtypes.PeerConfig{desired()} },
+			check: func(t *testing.T, changes []wgtypes.PeerConfig) {
+				if changes[0].PublicKey != b || !changes[0].Remove {
+					t.Errorf("expected %s to be removed, got %+v", b, changes[0])
+				}
+			},
+		},
+		{
+			name:    "endpoint",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				p := desired()
+				p.Endpoint = otherEndpoint
+				return []wgtypes.PeerConfig{p}
+			},
+			check: func(t *testing.T, changes []wgtypes.PeerConfig) {
+				if !changes[0].UpdateOnly || changes[0].Endpoint != otherEndpoint || changes[0].ReplaceAllowedIPs {
+					t.Errorf("expected only the endpoint to change, got %+v", changes[0])
+				}
+			},
+		},
+		{
+			name:    "allowed IPs",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				p := desired()
+				p.AllowedIPs = mustNets(t, "10.60.16.0/20")
+				return []wgtypes.PeerConfig{p}
+			},
+			check: func(t *testing.T, changes []wgtypes.PeerConfig) {
+				if !changes[0].ReplaceAllowedIPs || len(changes[0].AllowedIPs) != 1 || changes[0].Endpoint != nil {
+					t.Errorf("expected only the allowed IPs to change, got %+v", changes[0])
+				}
+			},
+		},
+		{
+			name:    "keepalive",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				p := desired()
+				p.PersistentKeepaliveInterval = &otherKeepalive
+				return []wgtypes.PeerConfig{p}
+			},
+			check: func(t *testing.T, changes []wgtypes.PeerConfig) {
+				if changes[0].PersistentKeepaliveInterval == nil || *changes[0].PersistentKeepaliveInterval != otherKeepalive {
+					t.Errorf("expected the keepalive to change, got %+v", changes[0])
+				}
+			},
+		},
+		{
+			name:    "key held by two leases",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				stale := &proto.Endpoint{PublicKey: a.String(), Networks: []string{"10.60.32.0/20"}}
+				newest := &proto.Endpoint{
+					PublicKey: a.String(),
+					Networks:  []string{"10.60.16.0/20", "192.168.1.0/24"},
+					Peer:      &proto.PublicPeer{Address: "192.0.2.1", Port: 51820},
+				}
+				return desiredPeers(mustKey(t), testConfig(stale, newest), "")
+			},
+		},
+		{
+			name:    "preshared key",
+			current: current(),
+			desired: func() []wgtypes.PeerConfig {
+				p := desired()
+				p.PresharedKey = &psk
+				return []wgtypes.PeerConfig{p}
+			},
+			check: func(t *testing.T, changes []wgtypes.PeerConfig) {
+				if changes[0].PresharedKey == nil || *changes[0].PresharedKey != psk {
+					t.Errorf("expected the preshared key to change, got %+v", changes[0])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := diffPeers(tt.current, tt.desired())
+			if tt.check == nil {
+				if len(changes) != 0 {
+					t.Fatalf("expected no change, got %+v", changes)
+				}
+				return
+			}
+			if len(changes) != 1 {
+				t.Fatalf("expected one change, got %+v", changes)
+			}
+			tt.check(t, changes)
+		})
+	}
+}
+
+func TestApplyUnchangedWritesNothing(t *testing.T) {
+	a, dp := newTestAgent(t)
+	lease := &proto.Lease{Uuid: "self", Network: "lab", IpRange: "10.60.0.0/20"}
+	config := testConfig(
+		&proto.Endpoint{
+			NodeName:  "self",
+			PublicKey: a.keys.key("lab").PublicKey().String(),
+			Networks:  []string{lease.IpRange},
+		},
+		testEndpoint(t, "peer1", "10.60.16.0/20", "192.0.2.1"),
+		testEndpoint(t, "peer2", "10.60.32.0/20", ""),
+	)
+
+	err := a.apply(lease, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dp.writes == 0 || dp.linkWrites == 0 || dp.routeWrites == 0 {
+		t.Fatalf("expected the first sync to configure the host, got %d device, %d link and %d route writes", dp.writes, dp.linkWrites, dp.routeWrites)
+	}
+	if peers := len(dp.devices["wg-test"].Peers); peers != 2 {
+		t.Fatalf("expected 2 peers, got %d", peers)
+	}
+
+	dp.writes, dp.linkWrites, dp.routeWrites = 0, 0, 0
+	err = a.apply(lease, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dp.writes != 0 || dp.linkWrites != 0 || dp.routeWrites != 0 {
+		t.Fatalf("expected no write on an unchanged sync, got %d device, %d link and %d route writes", dp.writes, dp.linkWrites, dp.routeWrites)
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+var (
+	onlyUnhealthy bool
+)
+
+var networkHealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Shows the connectivity between the peers of a network",
+	Long: `Lists what every lease reported about every other lease of the network.
+Pairs that never completed a handshake are logged as warnings.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			logrus.Fatal("You should only provide a network name")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.GetNetworkHealth(getContext(), &proto.NetworkHealthRequest{Name: args[0]})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+
+		var unhealthy []*proto.PeerHealth
+		for _, pair := range data.Peers {
+			if pair.Handshake {
+				continue
+			}
+			unhealthy = append(unhealthy, pair)
+			if !pair.Reported {
+				logrus.Warningf("%s (%s) did not report %s (%s)", pair.LeaseUuid, pair.IpRange, pair.PeerLeaseUuid, pair.PeerIpRange)
+			} else {
+				logrus.Warningf("%s (%s) never completed a handshake with %s (%s)", pair.LeaseUuid, pair.IpRange, pair.PeerLeaseUuid, pair.PeerIpRange)
+			}
+		}
+
+		if onlyUnhealthy {
+			data.Peers = unhealthy
+		}
+		output(data)
+	},
+}
+
+func initHealthCmd() {
+	networkHealthCmd.PersistentFlags().BoolVarP(&onlyUnhealthy, "unhealthy", "u", false, "Only show the pairs that never completed a handshake")
+}
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/common"
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+var (
+	importAddress string
+	importSubnets int32
+	importDryRun  bool
+)
+
+// importPeer is a peer seen in the imported files, either as the interface
+// of a file or as a [Peer] section of another one
+type importPeer struct {
+	name      string
+	publicKey string
+	address   net.IP
+	endpoint  string
+	// The name comes from a file, not from a comment or the key
+	named bool
+}
+
+// importedPeers merges what the files say about every peer, by public key,
+// and returns the prefixes seen on the interfaces along with the conflicts
+func importedPeers(files []string) ([]*importPeer, []*net.IPNet, []*common.WgQuickConfig, []string) {
+	var peers []*importPeer
+	var prefixes []*net.IPNet
+	var configs []*common.WgQuickConfig
+	var conflicts []string
+	byKey := make(map[string]*importPeer)
+
+	merge := func(p *importPeer, source string) {
+		existing, ok := byKey[p.publicKey]
+		if !ok {
+			byKey[p.publicKey] = p
+			peers = append(peers, p)
+			return
+		}
+		if p.named && !existing.named {
+			existing.name = p.name
+			existing.named = true
+		}
+		if p.address != nil {
+			if existing.address == nil {
+				existing.address = p.address
+			} else if !existing.address.Equal(p.address) {
+				conflicts = append(conflicts, fmt.Sprintf("%s gives %s the address %s, it has %s elsewhere", source, existing.name, p.address, existing.address))
+			}
+		}
+		if p.endpoint != "" {
+			if existing.endpoint == "" {
+				existing.endpoint = p.endpoint
+			} else if existing.endpoint != p.endpoint {
+				conflicts = append(conflicts, fmt.Sprintf("%s gives %s the endpoint %s, it has %s elsewhere", source, existing.name, p.endpoint, existing.endpoint))
+			}
+		}
+	}
+
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Could not open %s", file)
+		}
+		config, err := common.ParseWgQuick(f)
+		f.Close()
+		if err != nil {
+			logrus.WithError(err).Fatalf("Could not parse %s", file)
+		}
+		configs = append(configs, config)
+
+		self := &importPeer{
+			name:  strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+			named: true,
+		}
+		for _, address := range config.Interface.Address {
+			ip, prefix, err := net.ParseCIDR(address)
+			if err != nil || ip.To4() == nil {
+				continue
+			}
+			prefixes = append(prefixes, prefix)
+			if self.address == nil {
+				self.address = ip.To4()
+			}
+		}
+		key, err := wgtypes.ParseKey(config.Interface.PrivateKey)
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s has no valid private key, the public key of %s is unknown", file, self.name))
+		} else {
+			self.publicKey = key.PublicKey().String()
+			merge(self, file)
+		}
+
+		for _, section := range config.Peers {
+			p := &importPeer{
+				publicKey: section.PublicKey,
+				name:      "peer-" + strings.NewReplacer("/", "", "+", "").Replace(section.PublicKey),
+			}
+			if len(p.name) > 13 {
+				p.name = p.name[:13]
+			}
+			if comment := strings.Fields(section.Comment); len(comment) == 1 {
+				p.name = comment[0]
+			}
+
+			for _, allowed := range section.AllowedIPs {
+				ip, prefix, err := net.ParseCIDR(allowed)
+				if err == nil && ip.To4() != nil {
+					if ones, bits := prefix.Mask.Size(); ones == bits && p.address == nil {
+						p.address = ip.To4()
+						continue
+					}
+				}
+				logrus.Warningf("AllowedIPs %s of %s in %s is not the address of the peer, it is not imported", allowed, p.name, file)
+			}
+
+			if section.Endpoint != "" {
+				endpoint, err := resolveEndpoint(section.Endpoint)
+				if err != nil {
+					conflicts = append(conflicts, fmt.Sprintf("%s has an invalid endpoint %s for %s: %s", file, section.Endpoint, p.name, err))
+				}
+				p.endpoint = endpoint
+			}
+			merge(p, file)
+		}
+	}
+
+	for _, p := range peers {
+		if p.address == nil {
+			conflicts = append(conflicts, fmt.Sprintf("no file gives %s an IPv4 address", p.name))
+		}
+	}
+
+	return peers, prefixes, configs, conflicts
+}
+
+// resolveEndpoint returns the endpoint with its host resolved, the agents
+// only take IP addresses
+func resolveEndpoint(endpoint string) (string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) == nil {
+		addr, err := net.ResolveIPAddr("ip4", host)
+		if err != nil {
+			return "", err
+		}
+		logrus.Warningf("Endpoint %s is resolved to %s", endpoint, addr)
+		host = addr.String()
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// coveringPrefix returns the smallest prefix containing all the given ones
+func coveringPrefix(prefixes []*net.IPNet) *net.IPNet {
+	ones, _ := prefixes[0].Mask.Size()
+	for _, prefix := range prefixes[1:] {
+		if o, _ := prefix.Mask.Size(); o < ones {
+			ones = o
+		}
+	}
+
+	for ; ones > 0; ones-- {
+		candidate := &net.IPNet{IP: prefixes[0].IP.Mask(net.CIDRMask(ones, 32)), Mask: net.CIDRMask(ones, 32)}
+		covered := true
+		for _, prefix := range prefixes[1:] {
+			covered = covered && candidate.Contains(prefix.IP)
+		}
+		if covered {
+			return candidate
+		}
+	}
+	return &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+}
+
+// importSubnetCount returns the smallest number of subnets of the network
+// that puts every address in its own subnet, 0 if there is none
+func importSubnetCount(network *net.IPNet, addresses []net.IP) int32 {
+	ones, bits := network.Mask.Size()
+	for extraBits := 0; ones+extraBits <= bits && extraBits < 31; extraBits++ {
+		mask := net.CIDRMask(ones+extraBits, bits)
+		seen := make(map[string]bool)
+		distinct := true
+		for _, ip := range addresses {
+			subnet := ip.Mask(mask).String()
+			distinct = distinct && !seen[subnet]
+			seen[subnet] = true
+		}
+		if distinct {
+			return 1 << uint(extraBits)
+		}
+	}
+	return 0
+}
+
+// sharedValue returns the value if all the non zero ones agree, 0 otherwise
+func sharedValue(values []int) int32 {
+	var shared int
+	for _, v := range values {
+		if v == 0 {
+			continue
+		}
+		if shared != 0 && v != shared {
+			return 0
+		}
+		shared = v
+	}
+	return int32(shared)
+}
+
+var networkImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Creates a network from existing wg-quick configuration files",
+	Long: `Every file is a node named after the file, the peers of the files that have no file of their own
+are imported too. The nodes get static leases that keep their address, public key and endpoint, so the
+existing configurations keep working. The network is the smallest range containing every address unless
+--address is given, with the fewest subnets that give every node its own. Nothing is created if there is
+any conflict. Preshared keys and the AllowedIPs that are not the address of a peer are not imported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			logrus.Fatal("You should pass a network name and at least one wg-quick file")
+		}
+
+		peers, prefixes, configs, conflicts := importedPeers(args[1:])
+
+		var addresses []net.IP
+		for _, p := range peers {
+			if p.address != nil {
+				addresses = append(addresses, p.address)
+				prefixes = append(prefixes, &net.IPNet{IP: p.address, Mask: net.CIDRMask(32, 32)})
+			}
+		}
+		if len(addresses) == 0 {
+			logrus.Fatal("The files do not have any IPv4 address to import")
+		}
+
+		var network *net.IPNet
+		if importAddress != "" {
+			var err error
+			_, network, err = net.ParseCIDR(importAddress)
+			if err != nil {
+				logrus.WithError(err).Fatalf("Invalid address %s", importAddress)
+			}
+		} else {
+			network = coveringPrefix(prefixes)
+		}
+
+		numSubnets := importSubnets
+		if !cmd.Flags().Changed("subnets") {
+			numSubnets = importSubnetCount(network, addresses)
+			if numSubnets == 0 {
+				logrus.Fatalf("Some nodes share an address, %s cannot be split", network)
+			}
+		}
+
+		// Settings all the files agree on are kept unless given as flags
+		var mtus, ports, keepalives []int
+		for _, config := range configs {
+			mtus = append(mtus, config.Interface.MTU)
+			ports = append(ports, config.Interface.ListenPort)
+			for _, p := range config.Peers {
+				keepalives = append(keepalives, p.PersistentKeepalive)
+			}
+		}
+		if !cmd.Flags().Changed("mtu") {
+			mtu = sharedValue(mtus)
+		}
+		if !cmd.Flags().Changed("listen-port") {
+			listenPort = sharedValue(ports)
+		}
+		if !cmd.Flags().Changed("keepalive") {
+			keepalive = sharedValue(keepalives)
+		}
+
+		request := &proto.ImportNetworkRequest{
+			Network: &proto.CreateNetworkRequest{
+				Name:     args[0],
+				Address:  network.String(),
+				Subnets:  numSubnets,
+				Settings: networkSettings(cmd),
+			},
+			// Only check what the controller thinks if the files conflict already
+			DryRun: importDryRun || len(conflicts) != 0,
+		}
+		for _, p := range peers {
+			if p.address == nil {
+				continue
+			}
+			imported := &proto.ImportedPeer{
+				NodeName:  p.name,
+				PublicKey: p.publicKey,
+				Address:   p.address.String(),
+			}
+			if p.endpoint != "" {
+				host, port, _ := net.SplitHostPort(p.endpoint)
+				portNumber, err := strconv.Atoi(port)
+				if err == nil {
+					imported.Peer = &proto.PublicPeer{Address: host, Port: int32(portNumber)}
+				}
+			}
+			request.Peers = append(request.Peers, imported)
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.ImportNetwork(getContext(), request)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+
+		conflicts = append(conflicts, data.Conflicts...)
+		if len(conflicts) != 0 {
+			for _, conflict := range conflicts {
+				logrus.Error(conflict)
+			}
+			logrus.Fatalf("Found %d conflicts, nothing was imported", len(conflicts))
+		}
+		output(data)
+	},
+}
+
+func initImportCmd() {
+	networkImportCmd.PersistentFlags().StringVarP(&importAddress, "address", "a", "", "Address range of the network, the smallest one containing every address if empty")
+	networkImportCmd.PersistentFlags().Int32VarP(&importSubnets, "subnets", "s", 0, "Number of subnets, the fewest that give every node its own if not set")
+	networkImportCmd.PersistentFlags().BoolVar(&importDryRun, "dry-run", false, "Only show the network and the leases that would be created")
+}
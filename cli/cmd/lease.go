@@ -15,6 +15,7 @@ var (
 	publicKey string
 	address   string
 	port      int32
+	leaseTags []string
 )
 
 var leaseCmd = &cobra.Command{
@@ -63,6 +64,7 @@ var leaseCreateCmd = &cobra.Command{
 			NodeName:    hostname,
 			PublicKey:   publicKey,
 			Peer:        peer,
+			Tags:        leaseTags,
 		})
 		if err != nil {
 			logrus.WithError(err).Fatal("Error")
@@ -149,6 +151,7 @@ func initLeaseCmd() {
 	leaseCreateCmd.PersistentFlags().StringVarP(&address, "address", "a", "", "Address where the peer is reachable")
 	leaseCreateCmd.PersistentFlags().StringVarP(&publicKey, "pubkey", "k", "", "Public key for the lease")
 	leaseCreateCmd.PersistentFlags().Int32VarP(&port, "port", "p", 0, "Port where the peer is reachable")
+	leaseCreateCmd.PersistentFlags().StringSliceVar(&leaseTags, "tags", nil, "Tags of the node, exposed to service discovery")
 
 	leaseCmd.AddCommand(leaseCreateCmd)
 	leaseCmd.AddCommand(leaseListCmd)
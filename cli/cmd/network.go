@@ -9,9 +9,39 @@ import (
 )
 
 var (
-	subnets int32
+	subnets       int32
+	mtu           int32
+	keepalive     int32
+	listenPort    int32
+	leaseDuration int64
+	presharedKeys bool
+	dnsDomain     string
+	dnsEnabled    bool
 )
 
+func networkSettings(cmd *cobra.Command) *proto.NetworkSettings {
+	settings := &proto.NetworkSettings{
+		Mtu:                 mtu,
+		PersistentKeepalive: keepalive,
+		ListenPort:          listenPort,
+		LeaseDuration:       leaseDuration,
+		DnsDomain:           dnsDomain,
+	}
+	if cmd.Flags().Changed("preshared-keys") {
+		settings.PresharedKeys = proto.PresharedKeys_PRESHARED_KEYS_DISABLED
+		if presharedKeys {
+			settings.PresharedKeys = proto.PresharedKeys_PRESHARED_KEYS_ENABLED
+		}
+	}
+	if cmd.Flags().Changed("dns") {
+		settings.Dns = proto.DNS_DNS_DISABLED
+		if dnsEnabled {
+			settings.Dns = proto.DNS_DNS_ENABLED
+		}
+	}
+	return settings
+}
+
 var networkCmd = &cobra.Command{
 	Use:   "network",
 	Short: "Manages the networks",
@@ -33,9 +63,10 @@ var networkCreateCmd = &cobra.Command{
 		}
 
 		data, err := c.CreateNetwork(getContext(), &proto.CreateNetworkRequest{
-			Name:    args[0],
-			Address: args[1],
-			Subnets: subnets,
+			Name:     args[0],
+			Address:  args[1],
+			Subnets:  subnets,
+			Settings: networkSettings(cmd),
 		})
 		if err != nil {
 			logrus.WithError(err).Fatal("Error")
@@ -106,10 +137,54 @@ var networkDeleteCmd = &cobra.Command{
 	},
 }
 
+var networkUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates the settings of a network",
+	Long:  `Only the settings passed as flags are changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			logrus.Fatal("You should only provide a network name")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.UpdateNetwork(getContext(), &proto.UpdateNetworkRequest{
+			Name:     args[0],
+			Settings: networkSettings(cmd),
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
 func initNetworkCmd() {
 	networkCreateCmd.PersistentFlags().Int32VarP(&subnets, "subnets", "s", 4, "Number of subnets")
+	for _, c := range []*cobra.Command{networkCreateCmd, networkUpdateCmd, networkImportCmd} {
+		c.PersistentFlags().Int32Var(&mtu, "mtu", 0, "MTU of the mesh interfaces, 0 for the default")
+		c.PersistentFlags().Int32Var(&keepalive, "keepalive", 0, "Persistent keepalive interval in seconds, 0 for the default")
+		c.PersistentFlags().Int32Var(&listenPort, "listen-port", 0, "Port the agents listen on, 0 for the default")
+		c.PersistentFlags().Int64Var(&leaseDuration, "lease-duration", 0, "Lease duration in seconds, 0 for the controller's default")
+		c.PersistentFlags().BoolVar(&presharedKeys, "preshared-keys", false, "Give every pair of peers a preshared key, needs a master key on the controller")
+		c.PersistentFlags().StringVar(&dnsDomain, "dns-domain", "", "Domain of the names of the nodes, <network>.wgnw if empty")
+		c.PersistentFlags().BoolVar(&dnsEnabled, "dns", false, "Make the agents answer the names of the nodes on their mesh address")
+	}
 	networkCmd.AddCommand(networkCreateCmd)
 	networkCmd.AddCommand(networkListCmd)
 	networkCmd.AddCommand(networkGetCmd)
 	networkCmd.AddCommand(networkDeleteCmd)
+	networkCmd.AddCommand(networkUpdateCmd)
+
+	initImportCmd()
+	networkCmd.AddCommand(networkImportCmd)
+
+	initRenumberCmd()
+	networkCmd.AddCommand(networkRenumberCmd)
+
+	initHealthCmd()
+	networkCmd.AddCommand(networkHealthCmd)
 }
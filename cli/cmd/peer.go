@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+
+	"github.com/thomas-maurice/wgnw/common"
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+var (
+	peerPublicKey string
+	peerAddress   string
+	peerPort      int32
+	peerTags      []string
+	peerFile      string
+	peerQRCode    bool
+)
+
+var peerCmd = &cobra.Command{
+	Use:   "peer",
+	Short: "Manages the peers that do not run an agent",
+	Long:  `Phones and routers get a static lease, that never expires, and a wg-quick configuration file.`,
+}
+
+var peerAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Adds a peer to a network and prints its wg-quick configuration",
+	Long: `The controller generates the key pair of the peer unless --pubkey is given, the private key is not
+stored anywhere and is only shown once. The peer is removed with wgnw lease delete.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			logrus.Fatal("You should pass a network name and a node name")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		var peer *proto.PublicPeer
+		if peerAddress != "" && peerPort != 0 {
+			peer = &proto.PublicPeer{
+				Address: peerAddress,
+				Port:    peerPort,
+			}
+		}
+
+		data, err := c.AcquireLease(getContext(), &proto.AcquireLeaseRequest{
+			NetworkName: args[0],
+			NodeName:    args[1],
+			PublicKey:   peerPublicKey,
+			GenerateKey: peerPublicKey == "",
+			Static:      true,
+			Peer:        peer,
+			Tags:        peerTags,
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not acquire a lease")
+		}
+
+		config, err := c.FetchConfiguration(getContext(), &proto.ConfigurationRequest{
+			NetworkName: data.Lease.Network,
+			LeaseUuid:   data.Lease.Uuid,
+		})
+		if err != nil {
+			logrus.WithError(err).Fatalf("Could not fetch the configuration of lease %s", data.Lease.Uuid)
+		}
+
+		wgQuick, err := peerConfig(data.Lease, data.PrivateKey, peer, config)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not render the configuration")
+		}
+		content := wgQuick.String()
+
+		if peerFile != "" {
+			err = ioutil.WriteFile(peerFile, []byte(content), 0600)
+			if err != nil {
+				logrus.WithError(err).Fatalf("Could not write %s", peerFile)
+			}
+		} else {
+			fmt.Print(content)
+		}
+
+		if peerQRCode {
+			qr, err := qrcode.New(content, qrcode.Low)
+			if err != nil {
+				logrus.WithError(err).Warning("Could not render the configuration as a QR code, it is probably too large")
+			} else {
+				fmt.Fprint(os.Stderr, qr.ToSmallString(false))
+			}
+		}
+		if data.PrivateKey != "" {
+			logrus.Warningf("The private key of lease %s is not stored, it cannot be shown again", data.Lease.Uuid)
+		}
+	},
+}
+
+// peerConfig renders the wg-quick configuration of a static lease. The
+// routes follow the AllowedIPs, so the default table of wg-quick is used.
+func peerConfig(lease *proto.Lease, privateKey string, peer *proto.PublicPeer, config *proto.ConfigurationResponse) (*common.WgQuickConfig, error) {
+	ip, _, err := net.ParseCIDR(lease.IpRange)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := config.Network.GetSettings()
+	comment := fmt.Sprintf("Network %s, lease %s, node %s", lease.Network, lease.Uuid, lease.NodeName)
+	if privateKey == "" {
+		comment += fmt.Sprintf("\nSet PrivateKey to the private key of %s", lease.PublicKey)
+	}
+	wgQuick := &common.WgQuickConfig{
+		Interface: common.WgQuickInterface{
+			Comment:    comment,
+			PrivateKey: privateKey,
+			Address:    []string{ip.String() + "/32"},
+			MTU:        int(settings.GetMtu()),
+		},
+	}
+	if peer != nil {
+		wgQuick.Interface.ListenPort = int(peer.Port)
+	}
+
+	var nameserver string
+	for _, endpoint := range config.Network.Endpoints {
+		if endpoint.PublicKey == lease.PublicKey {
+			continue
+		}
+
+		p := common.WgQuickPeer{
+			Comment:             endpoint.NodeName,
+			PublicKey:           endpoint.PublicKey,
+			PresharedKey:        endpoint.PresharedKey,
+			AllowedIPs:          endpoint.Networks,
+			PersistentKeepalive: int(settings.GetPersistentKeepalive()),
+		}
+		if endpoint.NextPublicKey != "" && endpoint.RotateAt <= time.Now().Unix() {
+			p.PublicKey = endpoint.NextPublicKey
+		}
+		if endpoint.Peer != nil {
+			p.Endpoint = net.JoinHostPort(endpoint.Peer.Address, strconv.Itoa(int(endpoint.Peer.Port)))
+			// The agents answer DNS on their mesh address, pick one the
+			// peer can reach directly
+			if nameserver == "" && len(endpoint.Networks) != 0 {
+				nameserver = strings.Split(endpoint.Networks[0], "/")[0]
+			}
+		}
+		wgQuick.Peers = append(wgQuick.Peers, p)
+	}
+
+	if settings.GetDns() == proto.DNS_DNS_ENABLED && nameserver != "" {
+		domain := strings.TrimSuffix(common.NetworkDomain(lease.Network, settings.GetDnsDomain()), ".")
+		wgQuick.Interface.DNS = []string{nameserver, domain}
+	}
+
+	return wgQuick, nil
+}
+
+func initPeerCmd() {
+	peerAddCmd.PersistentFlags().StringVarP(&peerPublicKey, "pubkey", "k", "", "Public key of the peer, the controller generates a key pair if empty")
+	peerAddCmd.PersistentFlags().StringVarP(&peerAddress, "address", "a", "", "Address where the peer is reachable")
+	peerAddCmd.PersistentFlags().Int32VarP(&peerPort, "port", "p", 0, "Port where the peer is reachable, also its listen port")
+	peerAddCmd.PersistentFlags().StringSliceVar(&peerTags, "tags", nil, "Tags of the peer, exposed to service discovery")
+	peerAddCmd.PersistentFlags().StringVarP(&peerFile, "file", "f", "", "File to write the configuration to, stdout if empty")
+	peerAddCmd.PersistentFlags().BoolVar(&peerQRCode, "qr", true, "Print the configuration as a QR code on stderr")
+
+	peerCmd.AddCommand(peerAddCmd)
+}
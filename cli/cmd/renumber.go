@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+var (
+	forceRenumber bool
+)
+
+var networkRenumberCmd = &cobra.Command{
+	Use:   "renumber",
+	Short: "Moves a network to a different address range",
+	Long:  ``,
+}
+
+var networkRenumberStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Starts renumbering a network",
+	Long: `Allocates a range in the new address space for every lease. Both ranges
+are published to the agents until the renumbering is finished. Networks with
+static peers are refused, their peer files would keep the old range.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			logrus.Fatal("You should pass a network name and a CIDR")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.StartRenumber(getContext(), &proto.StartRenumberRequest{
+			Name:    args[0],
+			Address: args[1],
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+var networkRenumberStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows the progress of a renumbering",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			logrus.Fatal("You should only provide a network name")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.GetRenumberStatus(getContext(), &proto.RenumberStatusRequest{Name: args[0]})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+var networkRenumberFinishCmd = &cobra.Command{
+	Use:   "finish",
+	Short: "Cuts a network over to its new address range",
+	Long: `Removes the old address range from the network. This fails unless every
+lease acknowledged its new range, or --force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			logrus.Fatal("You should only provide a network name")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.FinishRenumber(getContext(), &proto.FinishRenumberRequest{
+			Name:  args[0],
+			Force: forceRenumber,
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+var networkRenumberAbortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Aborts a renumbering and drops the new address range",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			logrus.Fatal("You should only provide a network name")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+
+		data, err := c.AbortRenumber(getContext(), &proto.AbortRenumberRequest{Name: args[0]})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+func initRenumberCmd() {
+	networkRenumberFinishCmd.PersistentFlags().BoolVarP(&forceRenumber, "force", "f", false, "Finish even if some leases did not acknowledge their new range")
+	networkRenumberCmd.AddCommand(networkRenumberStartCmd)
+	networkRenumberCmd.AddCommand(networkRenumberStatusCmd)
+	networkRenumberCmd.AddCommand(networkRenumberFinishCmd)
+	networkRenumberCmd.AddCommand(networkRenumberAbortCmd)
+}
@@ -34,9 +34,13 @@ func Execute() {
 func init() {
 	initNetworkCmd()
 	initLeaseCmd()
+	initRouteCmd()
+	initPeerCmd()
 
 	rootCmd.AddCommand(networkCmd)
 	rootCmd.AddCommand(leaseCmd)
+	rootCmd.AddCommand(routeCmd)
+	rootCmd.AddCommand(peerCmd)
 	rootCmd.PersistentFlags().StringVarP(&marshaller, "output", "o", "json", "Output marshaller, json or yaml")
 	rootCmd.PersistentFlags().StringVarP(&controllerAddress, "controller", "c", "localhost:10000", "Controller address")
 	rootCmd.PersistentFlags().StringVarP(&authToken, "token", "t", "", "Auth token to talk to the API")
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/thomas-maurice/wgnw/proto"
+)
+
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Manages the routes advertised by the nodes",
+	Long:  `Nodes advertise the prefixes they can reach, the peers only route them once they are approved.`,
+}
+
+var routeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the advertised routes, of every network if none is given",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			logrus.Fatal("You should provide at most a network name")
+		}
+
+		var network string
+		if len(args) == 1 {
+			network = args[0]
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+		data, err := c.ListRoutes(getContext(), &proto.ListRoutesRequest{Network: network})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+var routeApproveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approves a route, the peers route the prefix through the node",
+	Long: `The node is the uuid of the lease advertising the prefix, or its node name if only one lease of
+that name advertises it. The prefix must not overlap with the network or with another approved route.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 3 {
+			logrus.Fatal("You should pass a network name, a lease uuid or node name and a prefix")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+		data, err := c.ApproveRoute(getContext(), &proto.RouteRequest{
+			Network:  args[0],
+			NodeName: args[1],
+			Prefix:   args[2],
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+var routeRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revokes the approval of a route",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 3 {
+			logrus.Fatal("You should pass a network name, a lease uuid or node name and a prefix")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not get a client")
+		}
+		data, err := c.RevokeRoute(getContext(), &proto.RouteRequest{
+			Network:  args[0],
+			NodeName: args[1],
+			Prefix:   args[2],
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Error")
+		}
+		output(data)
+	},
+}
+
+func initRouteCmd() {
+	routeCmd.AddCommand(routeListCmd)
+	routeCmd.AddCommand(routeApproveCmd)
+	routeCmd.AddCommand(routeRevokeCmd)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+
+	"github.com/thomas-maurice/wgnw/common"
+)
+
+// errNoAllocation is returned by the agent when the container interface
+// has no address
+type errNoAllocation struct {
+	msg string
+}
+
+func (e errNoAllocation) Error() string {
+	return e.msg
+}
+
+// callAgent posts the request to the IPAM API of the agent, the result is
+// decoded into result if it is not nil
+func callAgent(conf *netConf, path string, req common.IPAMRequest, result interface{}) error {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", conf.Socket)
+			},
+		},
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post("http://wgnwd"+path, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return types.NewError(types.ErrTryAgainLater, fmt.Sprintf("could not reach the agent on %s", conf.Socket), err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return errNoAllocation{strings.TrimSpace(string(msg))}
+		}
+		return types.NewError(types.ErrInternal, fmt.Sprintf("agent answered %s", resp.Status), strings.TrimSpace(string(msg)))
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func ipamRequest(conf *netConf, args *skel.CmdArgs) common.IPAMRequest {
+	return common.IPAMRequest{
+		Network:     conf.Network,
+		ContainerID: args.ContainerID,
+		IfName:      args.IfName,
+	}
+}
@@ -0,0 +1,47 @@
+// wgnw-cni is a CNI plugin that plugs containers into the bridge of a wgnwd
+// agent, with an address of the lease range of the node
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// supportedVersions are the versions of the CNI spec the plugin speaks
+var supportedVersions = version.PluginSupports("0.3.0", "0.3.1", "0.4.0", "1.0.0")
+
+// netConf is the network configuration the runtime gives on stdin
+type netConf struct {
+	types.NetConf
+	// Socket is the status socket of the agent
+	Socket string `json:"socket"`
+	// Network is the wgnw network to take the address from, it can be
+	// empty if the agent only joined one
+	Network string `json:"network"`
+}
+
+func main() {
+	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, supportedVersions, "wgnw-cni plugs containers into the bridge of a wgnwd agent")
+}
+
+// loadConf decodes the network configuration, along with the result of the
+// previous ADD if the runtime passes one
+func loadConf(stdin []byte) (*netConf, error) {
+	var conf netConf
+	err := json.Unmarshal(stdin, &conf)
+	if err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, "could not decode the network configuration", err.Error())
+	}
+	err = version.ParsePrevResult(&conf.NetConf)
+	if err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, "could not decode the previous result", err.Error())
+	}
+
+	if conf.Socket == "" {
+		conf.Socket = "/tmp/wgagent.sock"
+	}
+	return &conf, nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/vishvananda/netlink"
+
+	"github.com/thomas-maurice/wgnw/common"
+)
+
+// fakeAgent answers the IPAM API of the agent with a single address
+type fakeAgent struct {
+	lock       sync.Mutex
+	allocation common.IPAMAllocation
+	owners     map[string]bool
+}
+
+func (f *fakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var req common.IPAMRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	owner := req.ContainerID + "/" + req.IfName
+
+	switch r.URL.Path {
+	case "/ipam/add":
+		f.owners[owner] = true
+	case "/ipam/check":
+		if !f.owners[owner] {
+			http.Error(w, "no address", http.StatusNotFound)
+			return
+		}
+	case "/ipam/del":
+		delete(f.owners, owner)
+		json.NewEncoder(w).Encode(struct{}{})
+		return
+	}
+	json.NewEncoder(w).Encode(f.allocation)
+}
+
+// testEnv is a host namespace with the bridge of the agent, a container
+// namespace and a fake agent on a socket
+type testEnv struct {
+	hostNS      ns.NetNS
+	containerNS ns.NetNS
+	agent       *fakeAgent
+	socket      string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("needs root to create network namespaces")
+	}
+
+	hostNS, err := testutils.NewNS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hostNS.Close()
+		testutils.UnmountNS(hostNS)
+	})
+	containerNS, err := testutils.NewNS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		containerNS.Close()
+		testutils.UnmountNS(containerNS)
+	})
+
+	err = hostNS.Do(func(ns.NetNS) error {
+		bridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br-test"}}
+		err := netlink.LinkAdd(bridge)
+		if err != nil {
+			return err
+		}
+		addr, _ := netlink.ParseAddr("10.60.0.1/20")
+		err = netlink.AddrAdd(bridge, addr)
+		if err != nil {
+			return err
+		}
+		return netlink.LinkSetUp(bridge)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "wgnw-cni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := &fakeAgent{
+		allocation: common.IPAMAllocation{
+			Network:     "lab",
+			Address:     "10.60.0.2/20",
+			Gateway:     "10.60.0.1",
+			MTU:         1380,
+			Bridge:      "br-test",
+			Netns:       hostNS.Path(),
+			Nameservers: []string{"10.60.0.0"},
+			Domain:      "lab.wgnw",
+		},
+		owners: make(map[string]bool),
+	}
+	server := &http.Server{Handler: agent}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return &testEnv{hostNS: hostNS, containerNS: containerNS, agent: agent, socket: socket}
+}
+
+func (e *testEnv) args(cniVersion string, prevResult []byte) *skel.CmdArgs {
+	conf := fmt.Sprintf(`{"cniVersion": %q, "name": "mesh", "type": "wgnw-cni", "socket": %q, "network": "lab"`, cniVersion, e.socket)
+	if prevResult != nil {
+		conf += fmt.Sprintf(`, "prevResult": %s`, prevResult)
+	}
+	return &skel.CmdArgs{
+		ContainerID: "container1",
+		Netns:       e.containerNS.Path(),
+		IfName:      "eth0",
+		StdinData:   []byte(conf + "}"),
+	}
+}
+
+// hostLink returns the link of the host namespace, nil if it does not exist
+func (e *testEnv) hostLink(t *testing.T, name string) netlink.Link {
+	t.Helper()
+	var link netlink.Link
+	e.hostNS.Do(func(ns.NetNS) error {
+		link, _ = netlink.LinkByName(name)
+		return nil
+	})
+	return link
+}
+
+func TestAddCheckDel(t *testing.T) {
+	for _, cniVersion := range []string{"0.4.0", "1.0.0"} {
+		t.Run(cniVersion, func(t *testing.T) {
+			e := newTestEnv(t)
+			args := e.args(cniVersion, nil)
+
+			r, raw, err := testutils.CmdAddWithArgs(args, func() error { return cmdAdd(args) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := current.GetResult(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Interfaces) != 2 || result.Interfaces[1].Name != "eth0" || result.Interfaces[1].Sandbox != args.Netns {
+				t.Fatalf("expected the veth pair, got %+v", result.Interfaces)
+			}
+			if len(result.IPs) != 1 || result.IPs[0].Address.String() != "10.60.0.2/20" || !result.IPs[0].Gateway.Equal(net.ParseIP("10.60.0.1")) {
+				t.Fatalf("expected the address of the agent, got %+v", result.IPs)
+			}
+			if len(result.DNS.Nameservers) != 1 || result.DNS.Domain != "lab.wgnw" {
+				t.Errorf("expected the nameserver of the agent, got %+v", result.DNS)
+			}
+
+			veth := e.hostLink(t, result.Interfaces[0].Name)
+			if veth == nil || veth.Attrs().MasterIndex != e.hostLink(t, "br-test").Attrs().Index {
+				t.Fatalf("expected %s to be plugged into the bridge", result.Interfaces[0].Name)
+			}
+			err = e.containerNS.Do(func(ns.NetNS) error {
+				link, err := netlink.LinkByName("eth0")
+				if err != nil {
+					return err
+				}
+				addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+				if err != nil {
+					return err
+				}
+				if len(addrs) != 1 || addrs[0].IPNet.String() != "10.60.0.2/20" {
+					return fmt.Errorf("expected the address of the agent on eth0, got %v", addrs)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			args = e.args(cniVersion, raw)
+			err = testutils.CmdCheckWithArgs(args, func() error { return cmdCheck(args) })
+			if err != nil {
+				t.Fatalf("expected CHECK to pass, got %s", err)
+			}
+
+			args = e.args(cniVersion, nil)
+			err = testutils.CmdDelWithArgs(args, func() error { return cmdDel(args) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.hostLink(t, result.Interfaces[0].Name) != nil {
+				t.Errorf("expected %s to be removed", result.Interfaces[0].Name)
+			}
+			if len(e.agent.owners) != 0 {
+				t.Errorf("expected the address to be released, got %v", e.agent.owners)
+			}
+
+			// DEL is idempotent
+			err = testutils.CmdDelWithArgs(args, func() error { return cmdDel(args) })
+			if err != nil {
+				t.Fatalf("expected a second DEL to pass, got %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckPrevResult(t *testing.T) {
+	e := newTestEnv(t)
+	args := e.args("1.0.0", nil)
+	_, raw, err := testutils.CmdAddWithArgs(args, func() error { return cmdAdd(args) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args = e.args("1.0.0", nil)
+	err = testutils.CmdCheckWithArgs(args, func() error { return cmdCheck(args) })
+	if err == nil {
+		t.Error("expected CHECK to fail without prevResult")
+	}
+
+	var prev current.Result
+	err = json.Unmarshal(raw, &prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(r *current.Result)
+	}{
+		{
+			name: "other address",
+			mutate: func(r *current.Result) {
+				r.IPs[0].Address.IP = net.ParseIP("10.60.0.3")
+			},
+		},
+		{
+			name: "other MAC",
+			mutate: func(r *current.Result) {
+				r.Interfaces[1].Mac = "02:00:00:00:00:01"
+			},
+		},
+		{
+			name: "other interface",
+			mutate: func(r *current.Result) {
+				r.Interfaces[1].Name = "eth1"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r current.Result
+			json.Unmarshal(raw, &r)
+			tt.mutate(&r)
+			b, err := json.Marshal(&r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := e.args("1.0.0", b)
+			err = testutils.CmdCheckWithArgs(args, func() error { return cmdCheck(args) })
+			if err == nil {
+				t.Error("expected CHECK to fail")
+			}
+		})
+	}
+
+	// The interface is gone from the container
+	err = e.containerNS.Do(func(ns.NetNS) error {
+		link, err := netlink.LinkByName("eth0")
+		if err != nil {
+			return err
+		}
+		return netlink.LinkDel(link)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args = e.args("1.0.0", raw)
+	err = testutils.CmdCheckWithArgs(args, func() error { return cmdCheck(args) })
+	if err == nil {
+		t.Error("expected CHECK to fail once the interface is gone")
+	}
+}
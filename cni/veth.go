@@ -0,0 +1,330 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+
+	"github.com/thomas-maurice/wgnw/common"
+)
+
+// hostVethName is the name of the end of the veth pair on the bridge, it
+// only depends on the container interface so that CHECK can find it
+func hostVethName(args *skel.CmdArgs) string {
+	sum := sha1.Sum([]byte(args.ContainerID + "/" + args.IfName))
+	return "wgnw" + hex.EncodeToString(sum[:])[:8]
+}
+
+// openNetns opens a namespace by path, or by name from /var/run/netns
+func openNetns(namespace string) (netns.NsHandle, error) {
+	if strings.Contains(namespace, "/") {
+		return netns.GetFromPath(namespace)
+	}
+	return netns.GetFromName(namespace)
+}
+
+// bridgeHandle returns a netlink handle in the namespace of the bridge
+func bridgeHandle(allocation *common.IPAMAllocation) (*netlink.Handle, error) {
+	if allocation.Netns == "" {
+		return netlink.NewHandle()
+	}
+
+	ns, err := openNetns(allocation.Netns)
+	if err != nil {
+		return nil, fmt.Errorf("could not open namespace %s: %s", allocation.Netns, err)
+	}
+	defer ns.Close()
+	return netlink.NewHandleAt(ns)
+}
+
+func cmdAdd(args *skel.CmdArgs) error {
+	conf, err := loadConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	req := ipamRequest(conf, args)
+	existing := callAgent(conf, "/ipam/check", req, nil) == nil
+
+	var allocation common.IPAMAllocation
+	err = callAgent(conf, "/ipam/add", req, &allocation)
+	if err != nil {
+		return err
+	}
+
+	res, err := plug(args, &allocation)
+	if err != nil {
+		// Give a new address back, the runtime does not call DEL after a
+		// failed ADD, but leave the one of a container that is plugged in
+		if !existing {
+			callAgent(conf, "/ipam/del", req, nil)
+		}
+		return err
+	}
+	return types.PrintResult(res, conf.CNIVersion)
+}
+
+// plug creates the veth pair between the bridge and the container, and
+// configures the container end with the address
+func plug(args *skel.CmdArgs, allocation *common.IPAMAllocation) (*current.Result, error) {
+	address, err := netlink.ParseAddr(allocation.Address)
+	if err != nil {
+		return nil, err
+	}
+	gateway := net.ParseIP(allocation.Gateway)
+
+	host, err := bridgeHandle(allocation)
+	if err != nil {
+		return nil, err
+	}
+	defer host.Delete()
+
+	containerNS, err := netns.GetFromPath(args.Netns)
+	if err != nil {
+		return nil, fmt.Errorf("could not open namespace %s: %s", args.Netns, err)
+	}
+	defer containerNS.Close()
+	container, err := netlink.NewHandleAt(containerNS)
+	if err != nil {
+		return nil, err
+	}
+	defer container.Delete()
+
+	bridge, err := host.LinkByName(allocation.Bridge)
+	if err != nil {
+		return nil, fmt.Errorf("could not find bridge %s: %s", allocation.Bridge, err)
+	}
+	if link, err := container.LinkByName(args.IfName); err == nil {
+		return nil, fmt.Errorf("interface %s already exists in %s", link.Attrs().Name, args.Netns)
+	}
+
+	// The peer only exists on the host until it is moved to the container
+	name := hostVethName(args)
+	if link, err := host.LinkByName(name); err == nil {
+		host.LinkDel(link)
+	}
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        name,
+			MTU:         allocation.MTU,
+			MasterIndex: bridge.Attrs().Index,
+		},
+		PeerName: name + "p",
+	}
+	err = host.LinkAdd(veth)
+	if err != nil {
+		return nil, fmt.Errorf("could not create veth %s: %s", name, err)
+	}
+
+	res, err := configureVeth(host, container, containerNS, args, veth, address, gateway)
+	if err != nil {
+		host.LinkDel(veth)
+		return nil, err
+	}
+
+	if len(allocation.Nameservers) != 0 {
+		res.DNS = types.DNS{Nameservers: allocation.Nameservers, Domain: allocation.Domain}
+		if allocation.Domain != "" {
+			res.DNS.Search = []string{allocation.Domain}
+		}
+	}
+	return res, nil
+}
+
+func configureVeth(host, container *netlink.Handle, containerNS netns.NsHandle, args *skel.CmdArgs, veth *netlink.Veth, address *netlink.Addr, gateway net.IP) (*current.Result, error) {
+	peer, err := host.LinkByName(veth.PeerName)
+	if err != nil {
+		return nil, err
+	}
+	err = host.LinkSetNsFd(peer, int(containerNS))
+	if err != nil {
+		return nil, err
+	}
+
+	peer, err = container.LinkByName(veth.PeerName)
+	if err == nil {
+		err = container.LinkSetName(peer, args.IfName)
+	}
+	if err == nil {
+		err = container.AddrAdd(peer, address)
+	}
+	if err == nil {
+		err = container.LinkSetUp(peer)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not configure %s in %s: %s", args.IfName, args.Netns, err)
+	}
+	err = container.RouteAdd(&netlink.Route{
+		LinkIndex: peer.Attrs().Index,
+		Gw:        gateway,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not add the default route in %s: %s", args.Netns, err)
+	}
+
+	err = host.LinkSetUp(veth)
+	if err != nil {
+		return nil, err
+	}
+
+	hostLink, err := host.LinkByName(veth.Name)
+	if err != nil {
+		return nil, err
+	}
+	peer, err = container.LinkByName(args.IfName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &current.Result{
+		CNIVersion: current.ImplementedSpecVersion,
+		Interfaces: []*current.Interface{
+			{Name: veth.Name, Mac: hostLink.Attrs().HardwareAddr.String()},
+			{Name: args.IfName, Mac: peer.Attrs().HardwareAddr.String(), Sandbox: args.Netns},
+		},
+		IPs: []*current.IPConfig{
+			{Address: *address.IPNet, Gateway: gateway, Interface: current.Int(1)},
+		},
+		Routes: []*types.Route{
+			{Dst: net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}, GW: gateway},
+		},
+	}, nil
+}
+
+// cmdDel removes the veth pair and releases the address, what is already
+// gone is not an error
+func cmdDel(args *skel.CmdArgs) error {
+	conf, err := loadConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	if args.Netns != "" {
+		err := unplug(args)
+		if err != nil {
+			return err
+		}
+	}
+	return callAgent(conf, "/ipam/del", ipamRequest(conf, args), nil)
+}
+
+// unplug removes the container end of the veth pair, which removes the
+// other one too
+func unplug(args *skel.CmdArgs) error {
+	containerNS, err := netns.GetFromPath(args.Netns)
+	if err != nil {
+		// The runtime may call DEL after the namespace is gone
+		return nil
+	}
+	defer containerNS.Close()
+	container, err := netlink.NewHandleAt(containerNS)
+	if err != nil {
+		return err
+	}
+	defer container.Delete()
+
+	link, err := container.LinkByName(args.IfName)
+	if err != nil {
+		return nil
+	}
+	err = container.LinkDel(link)
+	if err != nil {
+		return fmt.Errorf("could not remove %s from %s: %s", args.IfName, args.Netns, err)
+	}
+	return nil
+}
+
+// cmdCheck makes sure the container interface is still plugged into the
+// bridge with what ADD returned, and that the agent still gives it the
+// same address
+func cmdCheck(args *skel.CmdArgs) error {
+	conf, err := loadConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+	if conf.PrevResult == nil {
+		return types.NewError(types.ErrInvalidNetworkConfig, "CHECK needs the result of ADD in prevResult", "")
+	}
+	prev, err := current.NewResultFromResult(conf.PrevResult)
+	if err != nil {
+		return types.NewError(types.ErrDecodingFailure, "could not convert the previous result", err.Error())
+	}
+
+	var allocation common.IPAMAllocation
+	err = callAgent(conf, "/ipam/check", ipamRequest(conf, args), &allocation)
+	if err != nil {
+		return err
+	}
+
+	var hostIface, containerIface *current.Interface
+	var containerIPs []*current.IPConfig
+	for i, iface := range prev.Interfaces {
+		switch {
+		case iface.Name == hostVethName(args) && iface.Sandbox == "":
+			hostIface = iface
+		case iface.Name == args.IfName && iface.Sandbox == args.Netns:
+			containerIface = iface
+			for _, ipc := range prev.IPs {
+				if ipc.Interface != nil && *ipc.Interface == i {
+					containerIPs = append(containerIPs, ipc)
+				}
+			}
+		}
+	}
+	if hostIface == nil || containerIface == nil {
+		return fmt.Errorf("the previous result does not have the veth pair of %s in %s", args.IfName, args.Netns)
+	}
+	found := false
+	for _, ipc := range containerIPs {
+		found = found || ipc.Address.String() == allocation.Address
+	}
+	if !found {
+		return fmt.Errorf("the previous result does not have address %s of %s", allocation.Address, args.IfName)
+	}
+
+	host, err := bridgeHandle(&allocation)
+	if err != nil {
+		return err
+	}
+	defer host.Delete()
+
+	bridge, err := host.LinkByName(allocation.Bridge)
+	if err != nil {
+		return fmt.Errorf("could not find bridge %s: %s", allocation.Bridge, err)
+	}
+	link, err := host.LinkByName(hostIface.Name)
+	if err != nil {
+		return fmt.Errorf("could not find veth %s: %s", hostIface.Name, err)
+	}
+	if link.Attrs().MasterIndex != bridge.Attrs().Index {
+		return fmt.Errorf("veth %s is not plugged into %s", link.Attrs().Name, allocation.Bridge)
+	}
+	if mac := link.Attrs().HardwareAddr.String(); hostIface.Mac != "" && mac != hostIface.Mac {
+		return fmt.Errorf("veth %s has MAC %s, the previous result has %s", link.Attrs().Name, mac, hostIface.Mac)
+	}
+
+	return ns.WithNetNSPath(args.Netns, func(ns.NetNS) error {
+		peer, err := netlink.LinkByName(args.IfName)
+		if err != nil {
+			return fmt.Errorf("could not find %s in %s: %s", args.IfName, args.Netns, err)
+		}
+		if mac := peer.Attrs().HardwareAddr.String(); containerIface.Mac != "" && mac != containerIface.Mac {
+			return fmt.Errorf("%s in %s has MAC %s, the previous result has %s", args.IfName, args.Netns, mac, containerIface.Mac)
+		}
+		err = ip.ValidateExpectedInterfaceIPs(args.IfName, containerIPs)
+		if err != nil {
+			return err
+		}
+		return ip.ValidateExpectedRoute(prev.Routes)
+	})
+}
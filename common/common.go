@@ -6,9 +6,11 @@ import (
 	"crypto/x509"
 	"errors"
 	"io/ioutil"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/thomas-maurice/wgnw/proto"
@@ -47,20 +49,43 @@ func GetTLSConfig(rootCA, clientCert, clientKey string, skipVerify bool) (*tls.C
 	return &config, nil
 }
 
+// ClientKeepalive pings the controller when the connection is idle, so
+// that a dead controller is noticed without waiting for a call to fail
+var ClientKeepalive = keepalive.ClientParameters{
+	Time:                30 * time.Second,
+	Timeout:             10 * time.Second,
+	PermitWithoutStream: true,
+}
+
+// ServerKeepalive lets the clients ping as often as ClientKeepalive does
+var ServerKeepalive = keepalive.EnforcementPolicy{
+	MinTime:             15 * time.Second,
+	PermitWithoutStream: true,
+}
+
+// DialTimeout is how long GetClient waits for a TLS connection
+const DialTimeout = 10 * time.Second
+
 // GetClient generates a client
 func GetClient(addr string, useTLS bool, config *tls.Config) (proto.WireguardServiceClient, error) {
 	var conn *grpc.ClientConn
 	var err error
 	if !useTLS {
-		conn, err = grpc.Dial(addr, grpc.WithInsecure())
+		conn, err = grpc.Dial(addr,
+			grpc.WithInsecure(),
+			grpc.WithKeepaliveParams(ClientKeepalive),
+		)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		conn, err = grpc.Dial(addr,
+		ctx, cancel := context.WithTimeout(context.Background(), DialTimeout)
+		defer cancel()
+		conn, err = grpc.DialContext(ctx, addr,
 			grpc.WithTransportCredentials(credentials.NewTLS(config)),
 			grpc.FailOnNonTempDialError(true),
 			grpc.WithBackoffConfig(grpc.DefaultBackoffConfig),
+			grpc.WithKeepaliveParams(ClientKeepalive),
 			grpc.WithBlock(),
 		)
 		if err != nil {
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config sets flags from a YAML file and from the environment. The keys of
+// the file are the names of the flags, and the environment variables are
+// the names of the flags in upper case with a prefix, e.g. WGNWD_AUTH_TOKEN
+// for the flag auth-token. Flags given on the command line always win, then
+// come the environment variables, then the file.
+type Config struct {
+	flags     *flag.FlagSet
+	filename  string
+	envPrefix string
+	cmdline   map[string]bool
+}
+
+// NewConfig should be called once the flags have been parsed. The file
+// itself can be given in the environment, e.g. WGNWD_CONFIG, unless the
+// config flag is on the command line.
+func NewConfig(flags *flag.FlagSet, filename string, envPrefix string) *Config {
+	cmdline := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		cmdline[f.Name] = true
+	})
+
+	c := &Config{
+		flags:     flags,
+		filename:  filename,
+		envPrefix: envPrefix,
+		cmdline:   cmdline,
+	}
+	if value, inEnv := os.LookupEnv(c.EnvName("config")); inEnv && !cmdline["config"] {
+		c.filename = value
+	}
+	return c
+}
+
+// EnvName returns the environment variable that sets the flag
+func (c *Config) EnvName(flagName string) string {
+	return c.envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// Load sets the flags that were not given on the command line. If extra is
+// not nil the file is also unmarshalled into it, for the settings that are
+// not flags. If only is not empty, only these flags are set.
+func (c *Config) Load(extra interface{}, only ...string) error {
+	wanted := func(name string) bool {
+		if c.cmdline[name] {
+			return false
+		}
+		if len(only) == 0 {
+			return true
+		}
+		for _, o := range only {
+			if o == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	values := make(map[string]interface{})
+	if c.filename != "" {
+		b, err := ioutil.ReadFile(c.filename)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(b, &values)
+		if err != nil {
+			return err
+		}
+
+		if extra != nil {
+			err = yaml.Unmarshal(b, extra)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, value := range values {
+		f := c.flags.Lookup(key)
+		switch value.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			// Nested settings belong to extra
+			if f != nil {
+				return fmt.Errorf("setting %s of %s should be a single value", key, c.filename)
+			}
+			continue
+		}
+		if f == nil {
+			if extra == nil {
+				return fmt.Errorf("unknown setting %s in %s", key, c.filename)
+			}
+			continue
+		}
+
+		if _, inEnv := os.LookupEnv(c.EnvName(key)); inEnv || !wanted(key) {
+			continue
+		}
+		if err := c.flags.Set(key, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("invalid value for setting %s in %s: %s", key, c.filename, err)
+		}
+	}
+
+	var err error
+	c.flags.VisitAll(func(f *flag.Flag) {
+		value, inEnv := os.LookupEnv(c.EnvName(f.Name))
+		if !inEnv || !wanted(f.Name) || err != nil {
+			return
+		}
+		if setErr := c.flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value for %s: %s", c.EnvName(f.Name), setErr)
+		}
+	})
+
+	return err
+}
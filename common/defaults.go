@@ -0,0 +1,12 @@
+package common
+
+const (
+	// DefaultMTU is the MTU of the mesh interfaces when the network does not set one
+	DefaultMTU = 1420
+	// DefaultPersistentKeepalive is the keepalive interval, in seconds, when the network does not set one
+	DefaultPersistentKeepalive = 5
+	// DefaultListenPort is the port the agents listen on when the network does not set one
+	DefaultListenPort = 6666
+	// DefaultDNSDomain is the parent of the domains of the networks that do not set one
+	DefaultDNSDomain = "wgnw"
+)
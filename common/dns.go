@@ -0,0 +1,185 @@
+package common
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+)
+
+// DNSTTL is the TTL of the answers, short so that renumbered and new
+// nodes show up quickly
+const DNSTTL = 30
+
+// DNSZone holds the addresses of the nodes of a network by fully
+// qualified name
+type DNSZone struct {
+	Domain  string
+	Records map[string][]net.IP
+}
+
+// NetworkDomain returns the domain of the names of the nodes of the
+// network, the configured one or <network>.wgnw
+func NetworkDomain(network string, domain string) string {
+	if domain == "" {
+		domain = network + "." + DefaultDNSDomain
+	}
+	return dns.Fqdn(strings.ToLower(domain))
+}
+
+// NewDNSZone returns an empty zone for the domain
+func NewDNSZone(domain string) DNSZone {
+	return DNSZone{
+		Domain:  dns.Fqdn(strings.ToLower(domain)),
+		Records: make(map[string][]net.IP),
+	}
+}
+
+// Add records the address of the node under the first label of its name,
+// nodes whose name is not a valid DNS label are left out
+func (z DNSZone) Add(node string, ip net.IP) {
+	node = strings.ToLower(strings.SplitN(node, ".", 2)[0])
+	if node == "" {
+		return
+	}
+	name := node + "." + z.Domain
+	if _, ok := dns.IsDomainName(name); !ok {
+		return
+	}
+	for _, existing := range z.Records[name] {
+		if existing.Equal(ip) {
+			return
+		}
+	}
+	z.Records[name] = append(z.Records[name], ip)
+}
+
+// DNSHandler answers the queries for the names of the nodes, and forwards
+// the others to the upstream servers if there are some
+type DNSHandler struct {
+	// Zones returns the zones to answer from
+	Zones func() ([]DNSZone, error)
+	// Upstream are the addresses, with their port, of the servers the
+	// queries outside of the zones go to. Only the queries of the host
+	// itself are forwarded, the others are refused.
+	Upstream []string
+}
+
+func (h *DNSHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	if len(r.Question) != 1 {
+		m.SetRcode(r, dns.RcodeFormatError)
+		w.WriteMsg(m)
+		return
+	}
+	q := r.Question[0]
+	name := strings.ToLower(q.Name)
+
+	zones, err := h.Zones()
+	if err != nil {
+		logrus.WithError(err).Error("Could not get the DNS zones")
+		m.SetRcode(r, dns.RcodeServerFailure)
+		w.WriteMsg(m)
+		return
+	}
+
+	for _, zone := range zones {
+		if !dns.IsSubDomain(zone.Domain, name) {
+			continue
+		}
+
+		m.SetReply(r)
+		m.Authoritative = true
+		ips, ok := zone.Records[name]
+		if !ok && name != zone.Domain {
+			m.SetRcode(r, dns.RcodeNameError)
+		}
+		for _, ip := range ips {
+			header := dns.RR_Header{Name: q.Name, Class: dns.ClassINET, Ttl: DNSTTL}
+			if ip4 := ip.To4(); ip4 != nil && q.Qtype == dns.TypeA {
+				header.Rrtype = dns.TypeA
+				m.Answer = append(m.Answer, &dns.A{Hdr: header, A: ip4})
+			} else if ip4 == nil && q.Qtype == dns.TypeAAAA {
+				header.Rrtype = dns.TypeAAAA
+				m.Answer = append(m.Answer, &dns.AAAA{Hdr: header, AAAA: ip})
+			}
+		}
+		w.WriteMsg(m)
+		return
+	}
+
+	w.WriteMsg(h.forward(w, r))
+}
+
+// forward asks the upstream servers in turn, the query is refused if
+// none answers
+func (h *DNSHandler) forward(w dns.ResponseWriter, r *dns.Msg) *dns.Msg {
+	client := &dns.Client{Timeout: 2 * time.Second}
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		client.Net = "tcp"
+	}
+
+	upstreams := h.Upstream
+	if !localClient(w) {
+		upstreams = nil
+	}
+	for _, upstream := range upstreams {
+		resp, _, err := client.Exchange(r, upstream)
+		if err == nil {
+			return resp
+		}
+		logrus.WithError(err).Debugf("Could not forward the query for %s to %s", r.Question[0].Name, upstream)
+	}
+
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeRefused)
+	return m
+}
+
+// ServeDNS answers on the UDP and TCP sockets until they are closed
+func ServeDNS(pc net.PacketConn, l net.Listener, handler dns.Handler) {
+	for _, server := range []*dns.Server{
+		{PacketConn: pc, Handler: handler},
+		{Listener: l, Handler: handler},
+	} {
+		go func(server *dns.Server) {
+			err := server.ActivateAndServe()
+			if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+				logrus.WithError(err).Error("DNS server stopped")
+			}
+		}(server)
+	}
+}
+
+// ListenDNS opens the UDP and TCP sockets of a DNS server
+func ListenDNS(address string) (net.PacketConn, net.Listener, error) {
+	pc, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		pc.Close()
+		return nil, nil, err
+	}
+	return pc, l, nil
+}
+
+// localClient tells if the query comes from the host itself, either from
+// the loopback or from the address it was sent to
+func localClient(w dns.ResponseWriter) bool {
+	remote := addrIP(w.RemoteAddr())
+	return remote != nil && (remote.IsLoopback() || remote.Equal(addrIP(w.LocalAddr())))
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+	return nil
+}
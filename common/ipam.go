@@ -0,0 +1,28 @@
+package common
+
+// IPAMRequest asks the agent for the address of a container interface, it
+// is what the CNI plugin sends on the socket of the agent
+type IPAMRequest struct {
+	// Network is the network the address comes from, it can be empty
+	// if the agent only joined one
+	Network     string `json:"network,omitempty"`
+	ContainerID string `json:"container_id"`
+	IfName      string `json:"ifname"`
+}
+
+// IPAMAllocation is the address of a container interface, and where to
+// plug the interface in
+type IPAMAllocation struct {
+	Network string `json:"network"`
+	// Address is in the CIDR notation, with the mask of the lease range
+	Address string `json:"address"`
+	Gateway string `json:"gateway"`
+	MTU     int    `json:"mtu"`
+	// Bridge is the bridge of the agent, in the Netns namespace, a name
+	// or a path, empty for the one of the agent
+	Bridge string `json:"bridge"`
+	Netns  string `json:"netns,omitempty"`
+	// Nameservers and Domain are set if the network has DNS enabled
+	Nameservers []string `json:"nameservers,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// KeyRotationProof proves that the lease holder owns the current key of
+// the lease. The agent computes it with its private key and the public key
+// of the controller, the controller with its private key and the public key
+// of the lease, both get the same X25519 shared secret.
+func KeyRotationProof(private wgtypes.Key, public wgtypes.Key, leaseUUID string, nextPublicKey string) ([]byte, error) {
+	shared, err := curve25519.X25519(private[:], public[:])
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, shared)
+	mac.Write([]byte(leaseUUID))
+	mac.Write([]byte(nextPublicKey))
+	return mac.Sum(nil), nil
+}
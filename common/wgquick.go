@@ -0,0 +1,217 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WgQuickConfig is the content of a wg-quick configuration file
+type WgQuickConfig struct {
+	Interface WgQuickInterface
+	Peers     []WgQuickPeer
+}
+
+// WgQuickInterface is the [Interface] section of a wg-quick configuration
+type WgQuickInterface struct {
+	// Comment is written on top of the section
+	Comment    string
+	PrivateKey string
+	Address    []string
+	ListenPort int
+	MTU        int
+	DNS        []string
+	Table      string
+	PostUp     []string
+	PostDown   []string
+}
+
+// WgQuickPeer is a [Peer] section of a wg-quick configuration
+type WgQuickPeer struct {
+	// Comment is written on top of the section
+	Comment             string
+	PublicKey           string
+	PresharedKey        string
+	Endpoint            string
+	AllowedIPs          []string
+	PersistentKeepalive int
+}
+
+// String renders the configuration in the wg-quick format
+func (c *WgQuickConfig) String() string {
+	var b strings.Builder
+
+	writeComment(&b, c.Interface.Comment)
+	b.WriteString("[Interface]\n")
+	writeValue(&b, "PrivateKey", c.Interface.PrivateKey)
+	writeValue(&b, "Address", strings.Join(c.Interface.Address, ", "))
+	if c.Interface.ListenPort != 0 {
+		writeValue(&b, "ListenPort", fmt.Sprintf("%d", c.Interface.ListenPort))
+	}
+	if c.Interface.MTU != 0 {
+		writeValue(&b, "MTU", fmt.Sprintf("%d", c.Interface.MTU))
+	}
+	writeValue(&b, "DNS", strings.Join(c.Interface.DNS, ", "))
+	writeValue(&b, "Table", c.Interface.Table)
+	for _, cmd := range c.Interface.PostUp {
+		writeValue(&b, "PostUp", cmd)
+	}
+	for _, cmd := range c.Interface.PostDown {
+		writeValue(&b, "PostDown", cmd)
+	}
+
+	for _, peer := range c.Peers {
+		b.WriteString("\n")
+		writeComment(&b, peer.Comment)
+		b.WriteString("[Peer]\n")
+		writeValue(&b, "PublicKey", peer.PublicKey)
+		writeValue(&b, "PresharedKey", peer.PresharedKey)
+		writeValue(&b, "Endpoint", peer.Endpoint)
+		writeValue(&b, "AllowedIPs", strings.Join(peer.AllowedIPs, ", "))
+		if peer.PersistentKeepalive != 0 {
+			writeValue(&b, "PersistentKeepalive", fmt.Sprintf("%d", peer.PersistentKeepalive))
+		}
+	}
+
+	return b.String()
+}
+
+func writeComment(b *strings.Builder, comment string) {
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" {
+			fmt.Fprintf(b, "# %s\n", line)
+		}
+	}
+}
+
+func writeValue(b *strings.Builder, key string, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "%s = %s\n", key, value)
+	}
+}
+
+// ParseWgQuick reads a wg-quick configuration. The comment lines right
+// above a section become its comment, the keys wg-quick knows but that
+// have no field are ignored.
+func ParseWgQuick(r io.Reader) (*WgQuickConfig, error) {
+	config := &WgQuickConfig{}
+	var section string
+	var comment []string
+	var peer *WgQuickPeer
+
+	scanner := bufio.NewScanner(r)
+	for line := 0; scanner.Scan(); {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(text, "#")))
+			continue
+		}
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			comment = nil
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			section = strings.ToLower(strings.TrimSpace(text[1 : len(text)-1]))
+			switch section {
+			case "interface":
+				config.Interface.Comment = strings.Join(comment, "\n")
+			case "peer":
+				config.Peers = append(config.Peers, WgQuickPeer{Comment: strings.Join(comment, "\n")})
+				peer = &config.Peers[len(config.Peers)-1]
+			default:
+				return nil, fmt.Errorf("line %d: unknown section %s", line, text)
+			}
+			comment = nil
+			continue
+		}
+
+		kv := strings.SplitN(text, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		// The comments above a key are not the ones of the next section
+		comment = nil
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		value := strings.TrimSpace(kv[1])
+
+		var err error
+		switch section {
+		case "interface":
+			err = config.Interface.set(key, value)
+		case "peer":
+			err = peer.set(key, value)
+		default:
+			err = fmt.Errorf("%s outside of a section", kv[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+
+	return config, scanner.Err()
+}
+
+func (i *WgQuickInterface) set(key string, value string) error {
+	var err error
+	switch key {
+	case "privatekey":
+		i.PrivateKey = value
+	case "address":
+		i.Address = append(i.Address, splitValues(value)...)
+	case "listenport":
+		i.ListenPort, err = strconv.Atoi(value)
+	case "mtu":
+		i.MTU, err = strconv.Atoi(value)
+	case "dns":
+		i.DNS = append(i.DNS, splitValues(value)...)
+	case "table":
+		i.Table = value
+	case "postup":
+		i.PostUp = append(i.PostUp, value)
+	case "postdown":
+		i.PostDown = append(i.PostDown, value)
+	case "preup", "predown", "saveconfig", "fwmark":
+	default:
+		return fmt.Errorf("unknown interface key %s", key)
+	}
+	return err
+}
+
+func (p *WgQuickPeer) set(key string, value string) error {
+	var err error
+	switch key {
+	case "publickey":
+		p.PublicKey = value
+	case "presharedkey":
+		p.PresharedKey = value
+	case "endpoint":
+		p.Endpoint = value
+	case "allowedips":
+		p.AllowedIPs = append(p.AllowedIPs, splitValues(value)...)
+	case "persistentkeepalive":
+		if value != "off" {
+			p.PersistentKeepalive, err = strconv.Atoi(value)
+		}
+	default:
+		return fmt.Errorf("unknown peer key %s", key)
+	}
+	return err
+}
+
+// splitValues splits a comma separated value of a wg-quick configuration
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
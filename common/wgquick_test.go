@@ -0,0 +1,41 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWgQuickComments(t *testing.T) {
+	config, err := ParseWgQuick(strings.NewReader(`# laptop
+[Interface]
+PrivateKey = cHJpdmF0ZQ==
+Address = 10.60.0.1/24
+
+# phone
+[Peer]
+PublicKey = cGhvbmU=
+# the old endpoint
+Endpoint = 192.0.2.1:51820
+[Peer]
+PublicKey = cm91dGVy
+AllowedIPs = 10.60.0.3/32
+# router
+[Peer]
+PublicKey = bGFzdA==
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Interface.Comment != "laptop" {
+		t.Errorf("expected the comment of the interface, got %q", config.Interface.Comment)
+	}
+	if len(config.Peers) != 3 {
+		t.Fatalf("expected 3 peers, got %d", len(config.Peers))
+	}
+	for i, expected := range []string{"phone", "", "router"} {
+		if config.Peers[i].Comment != expected {
+			t.Errorf("expected peer %d to have comment %q, got %q", i, expected, config.Peers[i].Comment)
+		}
+	}
+}
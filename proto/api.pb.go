@@ -25,6 +25,62 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type DNS int32
+
+const (
+	DNS_DNS_UNSET    DNS = 0
+	DNS_DNS_ENABLED  DNS = 1
+	DNS_DNS_DISABLED DNS = 2
+)
+
+var DNS_name = map[int32]string{
+	0: "DNS_UNSET",
+	1: "DNS_ENABLED",
+	2: "DNS_DISABLED",
+}
+
+var DNS_value = map[string]int32{
+	"DNS_UNSET":    0,
+	"DNS_ENABLED":  1,
+	"DNS_DISABLED": 2,
+}
+
+func (x DNS) String() string {
+	return proto.EnumName(DNS_name, int32(x))
+}
+
+func (DNS) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{0}
+}
+
+type PresharedKeys int32
+
+const (
+	PresharedKeys_PRESHARED_KEYS_UNSET    PresharedKeys = 0
+	PresharedKeys_PRESHARED_KEYS_ENABLED  PresharedKeys = 1
+	PresharedKeys_PRESHARED_KEYS_DISABLED PresharedKeys = 2
+)
+
+var PresharedKeys_name = map[int32]string{
+	0: "PRESHARED_KEYS_UNSET",
+	1: "PRESHARED_KEYS_ENABLED",
+	2: "PRESHARED_KEYS_DISABLED",
+}
+
+var PresharedKeys_value = map[string]int32{
+	"PRESHARED_KEYS_UNSET":    0,
+	"PRESHARED_KEYS_ENABLED":  1,
+	"PRESHARED_KEYS_DISABLED": 2,
+}
+
+func (x PresharedKeys) String() string {
+	return proto.EnumName(PresharedKeys_name, int32(x))
+}
+
+func (PresharedKeys) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{1}
+}
+
 type ListNetworksResponse struct {
 	Networks             []*Network `protobuf:"bytes,1,rep,name=networks,proto3" json:"networks,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
@@ -299,13 +355,18 @@ func (m *DeleteLeaseResponse) GetUuid() string {
 }
 
 type Network struct {
-	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Address              string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
-	Subnets              []string `protobuf:"bytes,3,rep,name=subnets,proto3" json:"subnets,omitempty"`
-	NumSubnets           int32    `protobuf:"varint,4,opt,name=num_subnets,json=numSubnets,proto3" json:"num_subnets,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Name       string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Address    string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Subnets    []string `protobuf:"bytes,3,rep,name=subnets,proto3" json:"subnets,omitempty"`
+	NumSubnets int32    `protobuf:"varint,4,opt,name=num_subnets,json=numSubnets,proto3" json:"num_subnets,omitempty"`
+	// Range the network is being renumbered to, empty if no renumbering is in progress
+	NextAddress string `protobuf:"bytes,5,opt,name=next_address,json=nextAddress,proto3" json:"next_address,omitempty"`
+	// Subnets of the range the network is being renumbered to
+	NextSubnets          []string         `protobuf:"bytes,6,rep,name=next_subnets,json=nextSubnets,proto3" json:"next_subnets,omitempty"`
+	Settings             *NetworkSettings `protobuf:"bytes,7,opt,name=settings,proto3" json:"settings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *Network) Reset()         { *m = Network{} }
@@ -361,20 +422,138 @@ func (m *Network) GetNumSubnets() int32 {
 	return 0
 }
 
-type CreateNetworkRequest struct {
-	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Address              string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
-	Subnets              int32    `protobuf:"varint,3,opt,name=subnets,proto3" json:"subnets,omitempty"`
+func (m *Network) GetNextAddress() string {
+	if m != nil {
+		return m.NextAddress
+	}
+	return ""
+}
+
+func (m *Network) GetNextSubnets() []string {
+	if m != nil {
+		return m.NextSubnets
+	}
+	return nil
+}
+
+func (m *Network) GetSettings() *NetworkSettings {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+type NetworkSettings struct {
+	// MTU of the mesh interfaces, 0 means default
+	Mtu int32 `protobuf:"varint,1,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	// Persistent keepalive interval in seconds, 0 means default
+	PersistentKeepalive int32 `protobuf:"varint,2,opt,name=persistent_keepalive,json=persistentKeepalive,proto3" json:"persistent_keepalive,omitempty"`
+	// Port the agents listen on, 0 means default
+	ListenPort int32 `protobuf:"varint,3,opt,name=listen_port,json=listenPort,proto3" json:"listen_port,omitempty"`
+	// Duration of the leases in seconds, 0 means the controller's default
+	LeaseDuration int64 `protobuf:"varint,4,opt,name=lease_duration,json=leaseDuration,proto3" json:"lease_duration,omitempty"`
+	// Whether the peers use preshared keys, unset means unchanged or disabled
+	PresharedKeys PresharedKeys `protobuf:"varint,5,opt,name=preshared_keys,json=presharedKeys,proto3,enum=proto.PresharedKeys" json:"preshared_keys,omitempty"`
+	// Domain the names of the nodes are in, empty means unchanged
+	// or <network>.wgnw
+	DnsDomain string `protobuf:"bytes,6,opt,name=dns_domain,json=dnsDomain,proto3" json:"dns_domain,omitempty"`
+	// Whether the agents answer the names of the nodes on their mesh
+	// address, unset means unchanged or disabled
+	Dns                  DNS      `protobuf:"varint,7,opt,name=dns,proto3,enum=proto.DNS" json:"dns,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *NetworkSettings) Reset()         { *m = NetworkSettings{} }
+func (m *NetworkSettings) String() string { return proto.CompactTextString(m) }
+func (*NetworkSettings) ProtoMessage()    {}
+func (*NetworkSettings) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{8}
+}
+
+func (m *NetworkSettings) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NetworkSettings.Unmarshal(m, b)
+}
+func (m *NetworkSettings) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NetworkSettings.Marshal(b, m, deterministic)
+}
+func (m *NetworkSettings) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetworkSettings.Merge(m, src)
+}
+func (m *NetworkSettings) XXX_Size() int {
+	return xxx_messageInfo_NetworkSettings.Size(m)
+}
+func (m *NetworkSettings) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkSettings.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkSettings proto.InternalMessageInfo
+
+func (m *NetworkSettings) GetMtu() int32 {
+	if m != nil {
+		return m.Mtu
+	}
+	return 0
+}
+
+func (m *NetworkSettings) GetPersistentKeepalive() int32 {
+	if m != nil {
+		return m.PersistentKeepalive
+	}
+	return 0
+}
+
+func (m *NetworkSettings) GetListenPort() int32 {
+	if m != nil {
+		return m.ListenPort
+	}
+	return 0
+}
+
+func (m *NetworkSettings) GetLeaseDuration() int64 {
+	if m != nil {
+		return m.LeaseDuration
+	}
+	return 0
+}
+
+func (m *NetworkSettings) GetPresharedKeys() PresharedKeys {
+	if m != nil {
+		return m.PresharedKeys
+	}
+	return PresharedKeys_PRESHARED_KEYS_UNSET
+}
+
+func (m *NetworkSettings) GetDnsDomain() string {
+	if m != nil {
+		return m.DnsDomain
+	}
+	return ""
+}
+
+func (m *NetworkSettings) GetDns() DNS {
+	if m != nil {
+		return m.Dns
+	}
+	return DNS_DNS_UNSET
+}
+
+type CreateNetworkRequest struct {
+	Name                 string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Address              string           `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Subnets              int32            `protobuf:"varint,3,opt,name=subnets,proto3" json:"subnets,omitempty"`
+	Settings             *NetworkSettings `protobuf:"bytes,4,opt,name=settings,proto3" json:"settings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
 func (m *CreateNetworkRequest) Reset()         { *m = CreateNetworkRequest{} }
 func (m *CreateNetworkRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateNetworkRequest) ProtoMessage()    {}
 func (*CreateNetworkRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{8}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{9}
 }
 
 func (m *CreateNetworkRequest) XXX_Unmarshal(b []byte) error {
@@ -416,6 +595,100 @@ func (m *CreateNetworkRequest) GetSubnets() int32 {
 	return 0
 }
 
+func (m *CreateNetworkRequest) GetSettings() *NetworkSettings {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+type UpdateNetworkRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Settings to change, fields left to 0 are not updated
+	Settings             *NetworkSettings `protobuf:"bytes,2,opt,name=settings,proto3" json:"settings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *UpdateNetworkRequest) Reset()         { *m = UpdateNetworkRequest{} }
+func (m *UpdateNetworkRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateNetworkRequest) ProtoMessage()    {}
+func (*UpdateNetworkRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{10}
+}
+
+func (m *UpdateNetworkRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateNetworkRequest.Unmarshal(m, b)
+}
+func (m *UpdateNetworkRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateNetworkRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateNetworkRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateNetworkRequest.Merge(m, src)
+}
+func (m *UpdateNetworkRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateNetworkRequest.Size(m)
+}
+func (m *UpdateNetworkRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateNetworkRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateNetworkRequest proto.InternalMessageInfo
+
+func (m *UpdateNetworkRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UpdateNetworkRequest) GetSettings() *NetworkSettings {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+type UpdateNetworkResponse struct {
+	Network              *Network `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateNetworkResponse) Reset()         { *m = UpdateNetworkResponse{} }
+func (m *UpdateNetworkResponse) String() string { return proto.CompactTextString(m) }
+func (*UpdateNetworkResponse) ProtoMessage()    {}
+func (*UpdateNetworkResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{11}
+}
+
+func (m *UpdateNetworkResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateNetworkResponse.Unmarshal(m, b)
+}
+func (m *UpdateNetworkResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateNetworkResponse.Marshal(b, m, deterministic)
+}
+func (m *UpdateNetworkResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateNetworkResponse.Merge(m, src)
+}
+func (m *UpdateNetworkResponse) XXX_Size() int {
+	return xxx_messageInfo_UpdateNetworkResponse.Size(m)
+}
+func (m *UpdateNetworkResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateNetworkResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateNetworkResponse proto.InternalMessageInfo
+
+func (m *UpdateNetworkResponse) GetNetwork() *Network {
+	if m != nil {
+		return m.Network
+	}
+	return nil
+}
+
 type CreateNetworkResponse struct {
 	Network              *Network `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -427,7 +700,7 @@ func (m *CreateNetworkResponse) Reset()         { *m = CreateNetworkResponse{} }
 func (m *CreateNetworkResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateNetworkResponse) ProtoMessage()    {}
 func (*CreateNetworkResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{9}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{12}
 }
 
 func (m *CreateNetworkResponse) XXX_Unmarshal(b []byte) error {
@@ -455,6 +728,184 @@ func (m *CreateNetworkResponse) GetNetwork() *Network {
 	return nil
 }
 
+// ImportedPeer is a peer of an existing wireguard network, it gets a
+// static lease that keeps its address and key
+type ImportedPeer struct {
+	NodeName  string `protobuf:"bytes,1,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	PublicKey string `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// Mesh address of the peer, without prefix length
+	Address              string      `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Peer                 *PublicPeer `protobuf:"bytes,4,opt,name=peer,proto3" json:"peer,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ImportedPeer) Reset()         { *m = ImportedPeer{} }
+func (m *ImportedPeer) String() string { return proto.CompactTextString(m) }
+func (*ImportedPeer) ProtoMessage()    {}
+func (*ImportedPeer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{13}
+}
+
+func (m *ImportedPeer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportedPeer.Unmarshal(m, b)
+}
+func (m *ImportedPeer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportedPeer.Marshal(b, m, deterministic)
+}
+func (m *ImportedPeer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportedPeer.Merge(m, src)
+}
+func (m *ImportedPeer) XXX_Size() int {
+	return xxx_messageInfo_ImportedPeer.Size(m)
+}
+func (m *ImportedPeer) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportedPeer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportedPeer proto.InternalMessageInfo
+
+func (m *ImportedPeer) GetNodeName() string {
+	if m != nil {
+		return m.NodeName
+	}
+	return ""
+}
+
+func (m *ImportedPeer) GetPublicKey() string {
+	if m != nil {
+		return m.PublicKey
+	}
+	return ""
+}
+
+func (m *ImportedPeer) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *ImportedPeer) GetPeer() *PublicPeer {
+	if m != nil {
+		return m.Peer
+	}
+	return nil
+}
+
+type ImportNetworkRequest struct {
+	Network *CreateNetworkRequest `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Peers   []*ImportedPeer       `protobuf:"bytes,2,rep,name=peers,proto3" json:"peers,omitempty"`
+	// Only report the conflicts and what would be created
+	DryRun               bool     `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImportNetworkRequest) Reset()         { *m = ImportNetworkRequest{} }
+func (m *ImportNetworkRequest) String() string { return proto.CompactTextString(m) }
+func (*ImportNetworkRequest) ProtoMessage()    {}
+func (*ImportNetworkRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{14}
+}
+
+func (m *ImportNetworkRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportNetworkRequest.Unmarshal(m, b)
+}
+func (m *ImportNetworkRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportNetworkRequest.Marshal(b, m, deterministic)
+}
+func (m *ImportNetworkRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportNetworkRequest.Merge(m, src)
+}
+func (m *ImportNetworkRequest) XXX_Size() int {
+	return xxx_messageInfo_ImportNetworkRequest.Size(m)
+}
+func (m *ImportNetworkRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportNetworkRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportNetworkRequest proto.InternalMessageInfo
+
+func (m *ImportNetworkRequest) GetNetwork() *CreateNetworkRequest {
+	if m != nil {
+		return m.Network
+	}
+	return nil
+}
+
+func (m *ImportNetworkRequest) GetPeers() []*ImportedPeer {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+func (m *ImportNetworkRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type ImportNetworkResponse struct {
+	Network *Network `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Leases  []*Lease `protobuf:"bytes,2,rep,name=leases,proto3" json:"leases,omitempty"`
+	// Nothing is created if there is any
+	Conflicts            []string `protobuf:"bytes,3,rep,name=conflicts,proto3" json:"conflicts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImportNetworkResponse) Reset()         { *m = ImportNetworkResponse{} }
+func (m *ImportNetworkResponse) String() string { return proto.CompactTextString(m) }
+func (*ImportNetworkResponse) ProtoMessage()    {}
+func (*ImportNetworkResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{15}
+}
+
+func (m *ImportNetworkResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportNetworkResponse.Unmarshal(m, b)
+}
+func (m *ImportNetworkResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportNetworkResponse.Marshal(b, m, deterministic)
+}
+func (m *ImportNetworkResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportNetworkResponse.Merge(m, src)
+}
+func (m *ImportNetworkResponse) XXX_Size() int {
+	return xxx_messageInfo_ImportNetworkResponse.Size(m)
+}
+func (m *ImportNetworkResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportNetworkResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportNetworkResponse proto.InternalMessageInfo
+
+func (m *ImportNetworkResponse) GetNetwork() *Network {
+	if m != nil {
+		return m.Network
+	}
+	return nil
+}
+
+func (m *ImportNetworkResponse) GetLeases() []*Lease {
+	if m != nil {
+		return m.Leases
+	}
+	return nil
+}
+
+func (m *ImportNetworkResponse) GetConflicts() []string {
+	if m != nil {
+		return m.Conflicts
+	}
+	return nil
+}
+
 type PublicPeer struct {
 	// Public IP address that peer is reachable through
 	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
@@ -469,7 +920,7 @@ func (m *PublicPeer) Reset()         { *m = PublicPeer{} }
 func (m *PublicPeer) String() string { return proto.CompactTextString(m) }
 func (*PublicPeer) ProtoMessage()    {}
 func (*PublicPeer) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{10}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{16}
 }
 
 func (m *PublicPeer) XXX_Unmarshal(b []byte) error {
@@ -510,7 +961,19 @@ type Endpoint struct {
 	// Public key of the peer
 	PublicKey string `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
 	// Networks that are reachable through that peer.
-	Networks             []string `protobuf:"bytes,3,rep,name=networks,proto3" json:"networks,omitempty"`
+	Networks []string `protobuf:"bytes,3,rep,name=networks,proto3" json:"networks,omitempty"`
+	// Name of the node holding the lease
+	NodeName string `protobuf:"bytes,4,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	// Key the peer rotates to at rotate_at, a Unix timestamp. rotate_at
+	// stays 0 until every peer of the network fetched the next key.
+	NextPublicKey string `protobuf:"bytes,5,opt,name=next_public_key,json=nextPublicKey,proto3" json:"next_public_key,omitempty"`
+	RotateAt      int64  `protobuf:"varint,6,opt,name=rotate_at,json=rotateAt,proto3" json:"rotate_at,omitempty"`
+	// Preshared key shared with this peer, only set for the peers
+	// of the lease the configuration was requested for
+	PresharedKey string `protobuf:"bytes,7,opt,name=preshared_key,json=presharedKey,proto3" json:"preshared_key,omitempty"`
+	// The peer has an approved default route, the nodes that opted in
+	// send their internet traffic through it
+	ExitNode             bool     `protobuf:"varint,8,opt,name=exit_node,json=exitNode,proto3" json:"exit_node,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -520,7 +983,7 @@ func (m *Endpoint) Reset()         { *m = Endpoint{} }
 func (m *Endpoint) String() string { return proto.CompactTextString(m) }
 func (*Endpoint) ProtoMessage()    {}
 func (*Endpoint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{11}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{17}
 }
 
 func (m *Endpoint) XXX_Unmarshal(b []byte) error {
@@ -562,23 +1025,63 @@ func (m *Endpoint) GetNetworks() []string {
 	return nil
 }
 
+func (m *Endpoint) GetNodeName() string {
+	if m != nil {
+		return m.NodeName
+	}
+	return ""
+}
+
+func (m *Endpoint) GetNextPublicKey() string {
+	if m != nil {
+		return m.NextPublicKey
+	}
+	return ""
+}
+
+func (m *Endpoint) GetRotateAt() int64 {
+	if m != nil {
+		return m.RotateAt
+	}
+	return 0
+}
+
+func (m *Endpoint) GetPresharedKey() string {
+	if m != nil {
+		return m.PresharedKey
+	}
+	return ""
+}
+
+func (m *Endpoint) GetExitNode() bool {
+	if m != nil {
+		return m.ExitNode
+	}
+	return false
+}
+
 type NetworkDefinition struct {
 	// Name of the network, this maps to a network identifier
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	// Network range
 	Address string `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
 	// List of endpoints of the network
-	Endpoints            []*Endpoint `protobuf:"bytes,3,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	Endpoints []*Endpoint `protobuf:"bytes,3,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	// Range the network is being renumbered to, both ranges
+	// should be routed during the transition
+	NextAddress string `protobuf:"bytes,4,opt,name=next_address,json=nextAddress,proto3" json:"next_address,omitempty"`
+	// Settings of the network, with the defaults filled in
+	Settings             *NetworkSettings `protobuf:"bytes,5,opt,name=settings,proto3" json:"settings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *NetworkDefinition) Reset()         { *m = NetworkDefinition{} }
 func (m *NetworkDefinition) String() string { return proto.CompactTextString(m) }
 func (*NetworkDefinition) ProtoMessage()    {}
 func (*NetworkDefinition) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{12}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{18}
 }
 
 func (m *NetworkDefinition) XXX_Unmarshal(b []byte) error {
@@ -620,6 +1123,20 @@ func (m *NetworkDefinition) GetEndpoints() []*Endpoint {
 	return nil
 }
 
+func (m *NetworkDefinition) GetNextAddress() string {
+	if m != nil {
+		return m.NextAddress
+	}
+	return ""
+}
+
+func (m *NetworkDefinition) GetSettings() *NetworkSettings {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
 type AcquireLeaseRequest struct {
 	// Node name, should be unique accross the network
 	NodeName string `protobuf:"bytes,1,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
@@ -628,17 +1145,29 @@ type AcquireLeaseRequest struct {
 	// Public key of the endpoint
 	PublicKey string `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
 	// If this is null then the peer is considered to be behind a NAT
-	Peer                 *PublicPeer `protobuf:"bytes,4,opt,name=peer,proto3" json:"peer,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	Peer *PublicPeer `protobuf:"bytes,4,opt,name=peer,proto3" json:"peer,omitempty"`
+	// Prefixes reachable through the node, used once approved
+	Routes []string `protobuf:"bytes,5,rep,name=routes,proto3" json:"routes,omitempty"`
+	// Free form tags of the node, exposed to service discovery
+	Tags []string `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Have the controller generate the key pair of the lease, public_key
+	// must be empty. The private key is in the response and not stored.
+	GenerateKey bool `protobuf:"varint,7,opt,name=generate_key,json=generateKey,proto3" json:"generate_key,omitempty"`
+	// Static leases never expire, for the peers that do not run an agent
+	Static bool `protobuf:"varint,8,opt,name=static,proto3" json:"static,omitempty"`
+	// Only return the lease that would be acquired, nothing is stored
+	// and the returned lease has no uuid
+	DryRun               bool     `protobuf:"varint,9,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *AcquireLeaseRequest) Reset()         { *m = AcquireLeaseRequest{} }
 func (m *AcquireLeaseRequest) String() string { return proto.CompactTextString(m) }
 func (*AcquireLeaseRequest) ProtoMessage()    {}
 func (*AcquireLeaseRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{13}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{19}
 }
 
 func (m *AcquireLeaseRequest) XXX_Unmarshal(b []byte) error {
@@ -687,8 +1216,47 @@ func (m *AcquireLeaseRequest) GetPeer() *PublicPeer {
 	return nil
 }
 
+func (m *AcquireLeaseRequest) GetRoutes() []string {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+func (m *AcquireLeaseRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *AcquireLeaseRequest) GetGenerateKey() bool {
+	if m != nil {
+		return m.GenerateKey
+	}
+	return false
+}
+
+func (m *AcquireLeaseRequest) GetStatic() bool {
+	if m != nil {
+		return m.Static
+	}
+	return false
+}
+
+func (m *AcquireLeaseRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
 type RenewLeaseRequest struct {
-	Uuid                 string   `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Uuid string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// Prefixes reachable through the node, replaces the previous ones
+	Routes []string `protobuf:"bytes,2,rep,name=routes,proto3" json:"routes,omitempty"`
+	// Tags of the node, replaces the previous ones
+	Tags                 []string `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -698,7 +1266,7 @@ func (m *RenewLeaseRequest) Reset()         { *m = RenewLeaseRequest{} }
 func (m *RenewLeaseRequest) String() string { return proto.CompactTextString(m) }
 func (*RenewLeaseRequest) ProtoMessage()    {}
 func (*RenewLeaseRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{14}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{20}
 }
 
 func (m *RenewLeaseRequest) XXX_Unmarshal(b []byte) error {
@@ -726,6 +1294,20 @@ func (m *RenewLeaseRequest) GetUuid() string {
 	return ""
 }
 
+func (m *RenewLeaseRequest) GetRoutes() []string {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+func (m *RenewLeaseRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
 type RenewLeaseResponse struct {
 	Lease                *Lease   `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -737,7 +1319,7 @@ func (m *RenewLeaseResponse) Reset()         { *m = RenewLeaseResponse{} }
 func (m *RenewLeaseResponse) String() string { return proto.CompactTextString(m) }
 func (*RenewLeaseResponse) ProtoMessage()    {}
 func (*RenewLeaseResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{15}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{21}
 }
 
 func (m *RenewLeaseResponse) XXX_Unmarshal(b []byte) error {
@@ -765,13 +1347,103 @@ func (m *RenewLeaseResponse) GetLease() *Lease {
 	return nil
 }
 
+type ReleaseLeaseRequest struct {
+	Uuid                 string   `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseLeaseRequest) Reset()         { *m = ReleaseLeaseRequest{} }
+func (m *ReleaseLeaseRequest) String() string { return proto.CompactTextString(m) }
+func (*ReleaseLeaseRequest) ProtoMessage()    {}
+func (*ReleaseLeaseRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{22}
+}
+
+func (m *ReleaseLeaseRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseLeaseRequest.Unmarshal(m, b)
+}
+func (m *ReleaseLeaseRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseLeaseRequest.Marshal(b, m, deterministic)
+}
+func (m *ReleaseLeaseRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseLeaseRequest.Merge(m, src)
+}
+func (m *ReleaseLeaseRequest) XXX_Size() int {
+	return xxx_messageInfo_ReleaseLeaseRequest.Size(m)
+}
+func (m *ReleaseLeaseRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseLeaseRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseLeaseRequest proto.InternalMessageInfo
+
+func (m *ReleaseLeaseRequest) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+type ReleaseLeaseResponse struct {
+	Uuid                 string   `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseLeaseResponse) Reset()         { *m = ReleaseLeaseResponse{} }
+func (m *ReleaseLeaseResponse) String() string { return proto.CompactTextString(m) }
+func (*ReleaseLeaseResponse) ProtoMessage()    {}
+func (*ReleaseLeaseResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{23}
+}
+
+func (m *ReleaseLeaseResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseLeaseResponse.Unmarshal(m, b)
+}
+func (m *ReleaseLeaseResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseLeaseResponse.Marshal(b, m, deterministic)
+}
+func (m *ReleaseLeaseResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseLeaseResponse.Merge(m, src)
+}
+func (m *ReleaseLeaseResponse) XXX_Size() int {
+	return xxx_messageInfo_ReleaseLeaseResponse.Size(m)
+}
+func (m *ReleaseLeaseResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseLeaseResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseLeaseResponse proto.InternalMessageInfo
+
+func (m *ReleaseLeaseResponse) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
 type Lease struct {
-	IpRange              string   `protobuf:"bytes,1,opt,name=ip_range,json=ipRange,proto3" json:"ip_range,omitempty"`
-	Network              string   `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
-	Expires              int64    `protobuf:"varint,3,opt,name=expires,proto3" json:"expires,omitempty"`
-	Uuid                 string   `protobuf:"bytes,4,opt,name=uuid,proto3" json:"uuid,omitempty"`
-	PublicKey            string   `protobuf:"bytes,5,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
-	Expired              bool     `protobuf:"varint,6,opt,name=expired,proto3" json:"expired,omitempty"`
+	IpRange   string `protobuf:"bytes,1,opt,name=ip_range,json=ipRange,proto3" json:"ip_range,omitempty"`
+	Network   string `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
+	Expires   int64  `protobuf:"varint,3,opt,name=expires,proto3" json:"expires,omitempty"`
+	Uuid      string `protobuf:"bytes,4,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	PublicKey string `protobuf:"bytes,5,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Expired   bool   `protobuf:"varint,6,opt,name=expired,proto3" json:"expired,omitempty"`
+	// Range allocated in the network's next address space while
+	// the network is being renumbered
+	NextIpRange string `protobuf:"bytes,7,opt,name=next_ip_range,json=nextIpRange,proto3" json:"next_ip_range,omitempty"`
+	// Name of the node holding the lease
+	NodeName string `protobuf:"bytes,8,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	// Key the lease rotates to at rotate_at, a Unix timestamp. rotate_at
+	// stays 0 until every peer of the network fetched the next key.
+	NextPublicKey string   `protobuf:"bytes,9,opt,name=next_public_key,json=nextPublicKey,proto3" json:"next_public_key,omitempty"`
+	RotateAt      int64    `protobuf:"varint,10,opt,name=rotate_at,json=rotateAt,proto3" json:"rotate_at,omitempty"`
+	Tags          []string `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Static leases never expire
+	Static               bool     `protobuf:"varint,12,opt,name=static,proto3" json:"static,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -781,7 +1453,7 @@ func (m *Lease) Reset()         { *m = Lease{} }
 func (m *Lease) String() string { return proto.CompactTextString(m) }
 func (*Lease) ProtoMessage()    {}
 func (*Lease) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{16}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{24}
 }
 
 func (m *Lease) XXX_Unmarshal(b []byte) error {
@@ -844,8 +1516,52 @@ func (m *Lease) GetExpired() bool {
 	return false
 }
 
+func (m *Lease) GetNextIpRange() string {
+	if m != nil {
+		return m.NextIpRange
+	}
+	return ""
+}
+
+func (m *Lease) GetNodeName() string {
+	if m != nil {
+		return m.NodeName
+	}
+	return ""
+}
+
+func (m *Lease) GetNextPublicKey() string {
+	if m != nil {
+		return m.NextPublicKey
+	}
+	return ""
+}
+
+func (m *Lease) GetRotateAt() int64 {
+	if m != nil {
+		return m.RotateAt
+	}
+	return 0
+}
+
+func (m *Lease) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *Lease) GetStatic() bool {
+	if m != nil {
+		return m.Static
+	}
+	return false
+}
+
 type AcquireLeaseResponse struct {
-	Lease                *Lease   `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
+	Lease *Lease `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
+	// Set if the controller generated the key pair
+	PrivateKey           string   `protobuf:"bytes,2,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -855,7 +1571,7 @@ func (m *AcquireLeaseResponse) Reset()         { *m = AcquireLeaseResponse{} }
 func (m *AcquireLeaseResponse) String() string { return proto.CompactTextString(m) }
 func (*AcquireLeaseResponse) ProtoMessage()    {}
 func (*AcquireLeaseResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{17}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{25}
 }
 
 func (m *AcquireLeaseResponse) XXX_Unmarshal(b []byte) error {
@@ -883,6 +1599,13 @@ func (m *AcquireLeaseResponse) GetLease() *Lease {
 	return nil
 }
 
+func (m *AcquireLeaseResponse) GetPrivateKey() string {
+	if m != nil {
+		return m.PrivateKey
+	}
+	return ""
+}
+
 type GetLeaseRequest struct {
 	Uuid                 string   `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -894,7 +1617,7 @@ func (m *GetLeaseRequest) Reset()         { *m = GetLeaseRequest{} }
 func (m *GetLeaseRequest) String() string { return proto.CompactTextString(m) }
 func (*GetLeaseRequest) ProtoMessage()    {}
 func (*GetLeaseRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{18}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{26}
 }
 
 func (m *GetLeaseRequest) XXX_Unmarshal(b []byte) error {
@@ -933,7 +1656,7 @@ func (m *GetLeaseResponse) Reset()         { *m = GetLeaseResponse{} }
 func (m *GetLeaseResponse) String() string { return proto.CompactTextString(m) }
 func (*GetLeaseResponse) ProtoMessage()    {}
 func (*GetLeaseResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{19}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{27}
 }
 
 func (m *GetLeaseResponse) XXX_Unmarshal(b []byte) error {
@@ -972,7 +1695,7 @@ func (m *ListLeasesResponse) Reset()         { *m = ListLeasesResponse{} }
 func (m *ListLeasesResponse) String() string { return proto.CompactTextString(m) }
 func (*ListLeasesResponse) ProtoMessage()    {}
 func (*ListLeasesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{20}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{28}
 }
 
 func (m *ListLeasesResponse) XXX_Unmarshal(b []byte) error {
@@ -1002,7 +1725,9 @@ func (m *ListLeasesResponse) GetLeases() []*Lease {
 
 type ConfigurationRequest struct {
 	// Name of the network we want to get the configuration for
-	NetworkName          string   `protobuf:"bytes,1,opt,name=network_name,json=networkName,proto3" json:"network_name,omitempty"`
+	NetworkName string `protobuf:"bytes,1,opt,name=network_name,json=networkName,proto3" json:"network_name,omitempty"`
+	// Lease of the requester, needed to get the preshared keys of its peers
+	LeaseUuid            string   `protobuf:"bytes,2,opt,name=lease_uuid,json=leaseUuid,proto3" json:"lease_uuid,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1012,7 +1737,7 @@ func (m *ConfigurationRequest) Reset()         { *m = ConfigurationRequest{} }
 func (m *ConfigurationRequest) String() string { return proto.CompactTextString(m) }
 func (*ConfigurationRequest) ProtoMessage()    {}
 func (*ConfigurationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{21}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{29}
 }
 
 func (m *ConfigurationRequest) XXX_Unmarshal(b []byte) error {
@@ -1040,6 +1765,13 @@ func (m *ConfigurationRequest) GetNetworkName() string {
 	return ""
 }
 
+func (m *ConfigurationRequest) GetLeaseUuid() string {
+	if m != nil {
+		return m.LeaseUuid
+	}
+	return ""
+}
+
 type ConfigurationResponse struct {
 	Network              *NetworkDefinition `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
@@ -1051,7 +1783,7 @@ func (m *ConfigurationResponse) Reset()         { *m = ConfigurationResponse{} }
 func (m *ConfigurationResponse) String() string { return proto.CompactTextString(m) }
 func (*ConfigurationResponse) ProtoMessage()    {}
 func (*ConfigurationResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_00212fb1f9d3bf1c, []int{22}
+	return fileDescriptor_00212fb1f9d3bf1c, []int{30}
 }
 
 func (m *ConfigurationResponse) XXX_Unmarshal(b []byte) error {
@@ -1079,128 +1811,1484 @@ func (m *ConfigurationResponse) GetNetwork() *NetworkDefinition {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*ListNetworksResponse)(nil), "proto.ListNetworksResponse")
-	proto.RegisterType((*GetNetworkRequest)(nil), "proto.GetNetworkRequest")
-	proto.RegisterType((*GetNetworkResponse)(nil), "proto.GetNetworkResponse")
-	proto.RegisterType((*DeleteNetworkRequest)(nil), "proto.DeleteNetworkRequest")
-	proto.RegisterType((*DeleteNetworkResponse)(nil), "proto.DeleteNetworkResponse")
-	proto.RegisterType((*DeleteLeaseRequest)(nil), "proto.DeleteLeaseRequest")
-	proto.RegisterType((*DeleteLeaseResponse)(nil), "proto.DeleteLeaseResponse")
-	proto.RegisterType((*Network)(nil), "proto.Network")
-	proto.RegisterType((*CreateNetworkRequest)(nil), "proto.CreateNetworkRequest")
-	proto.RegisterType((*CreateNetworkResponse)(nil), "proto.CreateNetworkResponse")
-	proto.RegisterType((*PublicPeer)(nil), "proto.PublicPeer")
-	proto.RegisterType((*Endpoint)(nil), "proto.Endpoint")
-	proto.RegisterType((*NetworkDefinition)(nil), "proto.NetworkDefinition")
-	proto.RegisterType((*AcquireLeaseRequest)(nil), "proto.AcquireLeaseRequest")
-	proto.RegisterType((*RenewLeaseRequest)(nil), "proto.RenewLeaseRequest")
-	proto.RegisterType((*RenewLeaseResponse)(nil), "proto.RenewLeaseResponse")
-	proto.RegisterType((*Lease)(nil), "proto.Lease")
-	proto.RegisterType((*AcquireLeaseResponse)(nil), "proto.AcquireLeaseResponse")
-	proto.RegisterType((*GetLeaseRequest)(nil), "proto.GetLeaseRequest")
-	proto.RegisterType((*GetLeaseResponse)(nil), "proto.GetLeaseResponse")
-	proto.RegisterType((*ListLeasesResponse)(nil), "proto.ListLeasesResponse")
-	proto.RegisterType((*ConfigurationRequest)(nil), "proto.ConfigurationRequest")
-	proto.RegisterType((*ConfigurationResponse)(nil), "proto.ConfigurationResponse")
+type StartRenumberRequest struct {
+	// Name of the network to renumber
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// New address range of the network
+	Address              string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("api.proto", fileDescriptor_00212fb1f9d3bf1c) }
+func (m *StartRenumberRequest) Reset()         { *m = StartRenumberRequest{} }
+func (m *StartRenumberRequest) String() string { return proto.CompactTextString(m) }
+func (*StartRenumberRequest) ProtoMessage()    {}
+func (*StartRenumberRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{31}
+}
 
-var fileDescriptor_00212fb1f9d3bf1c = []byte{
-	// 842 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x5b, 0x4f, 0xdb, 0x48,
-	0x14, 0xb6, 0x37, 0xf7, 0x93, 0xb0, 0xc0, 0x10, 0xd8, 0x24, 0xec, 0x6a, 0xb3, 0xa3, 0x45, 0x9b,
-	0xa5, 0x6a, 0x90, 0x52, 0xa9, 0x6a, 0xa9, 0xda, 0x8a, 0xbb, 0x2a, 0x22, 0x44, 0xcd, 0x43, 0xa5,
-	0x3e, 0x34, 0x32, 0xf1, 0x21, 0xb5, 0x48, 0x6c, 0x33, 0xb6, 0x4b, 0xf9, 0x2d, 0x7d, 0xec, 0x4b,
-	0x7f, 0x66, 0x65, 0xcf, 0x8c, 0x6f, 0x31, 0x85, 0x3c, 0x25, 0x73, 0xce, 0xe7, 0x73, 0xf9, 0xe6,
-	0x9b, 0x73, 0xa0, 0xa6, 0x3b, 0x66, 0xdf, 0x61, 0xb6, 0x67, 0x93, 0x52, 0xf8, 0xd3, 0xd9, 0x9c,
-	0xd8, 0xf6, 0x64, 0x8a, 0x3b, 0xe1, 0xe9, 0xd2, 0xbf, 0xda, 0xc1, 0x99, 0xe3, 0xdd, 0x71, 0x0c,
-	0xdd, 0x87, 0xe6, 0xd0, 0x74, 0xbd, 0x33, 0xf4, 0x6e, 0x6d, 0x76, 0xed, 0x6a, 0xe8, 0x3a, 0xb6,
-	0xe5, 0x22, 0xd9, 0x86, 0xaa, 0x25, 0x6c, 0x2d, 0xb5, 0x5b, 0xe8, 0xd5, 0x07, 0xbf, 0xf3, 0x2f,
-	0xfa, 0x02, 0xaa, 0x45, 0x7e, 0xfa, 0x1f, 0xac, 0x9e, 0xa0, 0x0c, 0xa1, 0xe1, 0x8d, 0x8f, 0xae,
-	0x47, 0x08, 0x14, 0x2d, 0x7d, 0x86, 0x2d, 0xb5, 0xab, 0xf6, 0x6a, 0x5a, 0xf8, 0x9f, 0xbe, 0x01,
-	0x92, 0x04, 0x8a, 0x54, 0x3d, 0xa8, 0x88, 0x50, 0x21, 0x78, 0x3e, 0x93, 0x74, 0xd3, 0x6d, 0x68,
-	0x1e, 0xe2, 0x14, 0x3d, 0x7c, 0x44, 0xae, 0x27, 0xb0, 0x9e, 0xc1, 0x8a, 0x74, 0x79, 0xe0, 0x1e,
-	0x10, 0x0e, 0x1e, 0xa2, 0xee, 0x62, 0x22, 0xac, 0xef, 0x9b, 0x86, 0x44, 0x06, 0xff, 0xe9, 0xff,
-	0xb0, 0x96, 0x42, 0xc6, 0x41, 0xe7, 0xa0, 0x0c, 0x2a, 0x22, 0x77, 0x5e, 0x4e, 0xd2, 0x82, 0x8a,
-	0x6e, 0x18, 0x0c, 0x5d, 0xb7, 0xf5, 0x5b, 0x68, 0x96, 0xc7, 0xc0, 0xe3, 0xfa, 0x97, 0x16, 0x7a,
-	0x6e, 0xab, 0xd0, 0x2d, 0x04, 0x1e, 0x71, 0x24, 0x7f, 0x43, 0xdd, 0xf2, 0x67, 0x23, 0xe9, 0x2d,
-	0x76, 0xd5, 0x5e, 0x49, 0x03, 0xcb, 0x9f, 0x5d, 0x70, 0x0b, 0xfd, 0x04, 0xcd, 0x03, 0x86, 0xfa,
-	0x63, 0x18, 0x7a, 0x6c, 0x01, 0x41, 0x0a, 0x79, 0xa4, 0x7b, 0xb0, 0x9e, 0x89, 0xbf, 0xf0, 0x25,
-	0xee, 0x02, 0x9c, 0xfb, 0x97, 0x53, 0x73, 0x7c, 0x8e, 0xc8, 0x92, 0x45, 0xa8, 0xe9, 0x22, 0x08,
-	0x14, 0x1d, 0x9b, 0x79, 0x61, 0x6d, 0x25, 0x2d, 0xfc, 0x4f, 0xa7, 0x50, 0x3d, 0xb2, 0x0c, 0xc7,
-	0x36, 0x2d, 0x8f, 0x6c, 0x41, 0xd1, 0x41, 0x64, 0x22, 0xdd, 0xaa, 0x48, 0x17, 0x87, 0xd6, 0x42,
-	0x37, 0xf9, 0x0b, 0xc0, 0x09, 0x6d, 0xa3, 0x6b, 0xbc, 0x13, 0x8d, 0xd6, 0xb8, 0xe5, 0x14, 0xef,
-	0x48, 0x27, 0xa1, 0x73, 0x4e, 0x76, 0xac, 0x6b, 0x07, 0x56, 0x45, 0xf5, 0x87, 0x78, 0x65, 0x5a,
-	0xa6, 0x67, 0xda, 0xd6, 0x82, 0x4c, 0x3e, 0x85, 0x1a, 0x8a, 0x82, 0x79, 0xfc, 0xfa, 0x60, 0x59,
-	0x54, 0x2a, 0x1b, 0xd1, 0x62, 0x04, 0xfd, 0xa6, 0xc2, 0xda, 0xde, 0xf8, 0xc6, 0x37, 0x59, 0x5a,
-	0x89, 0x9b, 0x50, 0xb3, 0x6c, 0x03, 0x47, 0x89, 0xcc, 0xd5, 0xc0, 0x70, 0x16, 0x64, 0xff, 0x07,
-	0x1a, 0xa2, 0x64, 0xee, 0xe7, 0x25, 0xd4, 0x85, 0x2d, 0x84, 0xa4, 0x49, 0x28, 0x64, 0x49, 0x90,
-	0x54, 0x16, 0x7f, 0x49, 0x65, 0xf0, 0xce, 0x35, 0xb4, 0xf0, 0xf6, 0xc1, 0x47, 0xf2, 0x02, 0x48,
-	0x12, 0x28, 0x24, 0x42, 0xa1, 0x34, 0x0d, 0x0c, 0xe2, 0xc6, 0x1a, 0x22, 0x0d, 0x07, 0x71, 0x17,
-	0xfd, 0xae, 0x42, 0x29, 0x34, 0x90, 0x36, 0x54, 0x4d, 0x67, 0xc4, 0x74, 0x6b, 0x22, 0x3b, 0xae,
-	0x98, 0x8e, 0x16, 0x1c, 0x03, 0xba, 0xa5, 0xd6, 0x04, 0xdd, 0xe2, 0x18, 0x78, 0xf0, 0xab, 0x63,
-	0x32, 0xe4, 0xc2, 0x2d, 0x68, 0xf2, 0x18, 0x95, 0x59, 0x8c, 0xcb, 0xcc, 0xb0, 0x52, 0xca, 0xb2,
-	0x12, 0x05, 0x33, 0x5a, 0xe5, 0xae, 0xda, 0xab, 0xca, 0x60, 0x06, 0xdd, 0x85, 0x66, 0xfa, 0x96,
-	0x16, 0xe8, 0x70, 0x0b, 0x96, 0x4f, 0xd0, 0x7b, 0x90, 0xc2, 0xe7, 0xb0, 0x12, 0xc3, 0x16, 0x08,
-	0xbf, 0x0b, 0x24, 0x98, 0xe7, 0xa1, 0x2d, 0x9e, 0xe6, 0xff, 0x42, 0x39, 0x74, 0xcb, 0x59, 0x9e,
-	0xfe, 0x54, 0xf8, 0xe8, 0x4b, 0x68, 0x1e, 0xd8, 0xd6, 0x95, 0x39, 0xf1, 0x99, 0x1e, 0x68, 0x5d,
-	0xd6, 0x97, 0x15, 0x98, 0x3a, 0x27, 0x30, 0x7a, 0x0a, 0xeb, 0x99, 0x4f, 0x45, 0xe6, 0x41, 0x76,
-	0x2e, 0xb4, 0xd2, 0x73, 0x21, 0x7e, 0x59, 0xd1, 0x2d, 0x0e, 0x7e, 0x94, 0x61, 0xe5, 0x83, 0xc9,
-	0x70, 0xe2, 0xeb, 0xcc, 0xb8, 0x40, 0xf6, 0xc5, 0x1c, 0x23, 0x19, 0xc2, 0x52, 0x6a, 0xf2, 0x90,
-	0x4d, 0x11, 0x28, 0x6f, 0xde, 0x75, 0xfe, 0xcc, 0x77, 0xf2, 0xa2, 0xa8, 0x42, 0x8e, 0xa0, 0x91,
-	0x5c, 0x7b, 0x64, 0xa3, 0xcf, 0x97, 0x64, 0x5f, 0x2e, 0xc9, 0xfe, 0x51, 0xb0, 0x24, 0x3b, 0x32,
-	0x49, 0xde, 0x8e, 0xa4, 0x0a, 0x39, 0x00, 0x88, 0x17, 0x1a, 0x91, 0xad, 0xcd, 0x2d, 0xc3, 0x4e,
-	0x3b, 0xc7, 0x13, 0x05, 0x19, 0xc2, 0x52, 0x6a, 0x53, 0x45, 0x9d, 0xe5, 0xed, 0xba, 0xa8, 0xb3,
-	0xdc, 0xe5, 0x46, 0x15, 0xf2, 0x0e, 0x1a, 0x49, 0x6d, 0x92, 0x8e, 0xc0, 0xe7, 0x8c, 0x95, 0xa8,
-	0xbb, 0x3c, 0x31, 0x53, 0x85, 0xec, 0x01, 0xc4, 0x5a, 0xba, 0x97, 0xa2, 0x76, 0x82, 0xa2, 0xb4,
-	0xec, 0xa8, 0x42, 0x5e, 0x43, 0x55, 0xca, 0x98, 0x6c, 0xc4, 0x24, 0xa4, 0xaa, 0xf8, 0x63, 0xce,
-	0x1e, 0x7d, 0x7e, 0x0c, 0xf5, 0xc4, 0xb6, 0x25, 0xed, 0x54, 0xef, 0xa9, 0x20, 0x9d, 0x3c, 0x57,
-	0xf2, 0x9e, 0xe2, 0x81, 0x14, 0xdd, 0xd3, 0xdc, 0x30, 0x8b, 0x7a, 0x99, 0x9f, 0x5e, 0x54, 0x21,
-	0x6f, 0xa1, 0x7e, 0xee, 0xb3, 0x09, 0x3e, 0xc0, 0xc7, 0x3d, 0x76, 0xaa, 0x90, 0xf7, 0x40, 0x8e,
-	0xd1, 0x1b, 0x7f, 0x4e, 0xbd, 0x94, 0x58, 0xc7, 0x39, 0x4f, 0x2f, 0xd6, 0x71, 0xde, 0xe3, 0xa2,
-	0xca, 0x7e, 0xed, 0x63, 0xa5, 0xff, 0x8a, 0x27, 0x2a, 0x87, 0x3f, 0xcf, 0x7e, 0x06, 0x00, 0x00,
-	0xff, 0xff, 0x3f, 0xd8, 0xb4, 0x85, 0x01, 0x0a, 0x00, 0x00,
+func (m *StartRenumberRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartRenumberRequest.Unmarshal(m, b)
+}
+func (m *StartRenumberRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartRenumberRequest.Marshal(b, m, deterministic)
+}
+func (m *StartRenumberRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartRenumberRequest.Merge(m, src)
+}
+func (m *StartRenumberRequest) XXX_Size() int {
+	return xxx_messageInfo_StartRenumberRequest.Size(m)
+}
+func (m *StartRenumberRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartRenumberRequest.DiscardUnknown(m)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+var xxx_messageInfo_StartRenumberRequest proto.InternalMessageInfo
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *StartRenumberRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
 
-// WireguardServiceClient is the client API for WireguardService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type WireguardServiceClient interface {
-	CreateNetwork(ctx context.Context, in *CreateNetworkRequest, opts ...grpc.CallOption) (*CreateNetworkResponse, error)
-	ListNetworks(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListNetworksResponse, error)
-	GetNetwork(ctx context.Context, in *GetNetworkRequest, opts ...grpc.CallOption) (*GetNetworkResponse, error)
-	DeleteNetwork(ctx context.Context, in *DeleteNetworkRequest, opts ...grpc.CallOption) (*DeleteNetworkResponse, error)
-	AcquireLease(ctx context.Context, in *AcquireLeaseRequest, opts ...grpc.CallOption) (*AcquireLeaseResponse, error)
-	ListLeases(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListLeasesResponse, error)
-	GetLease(ctx context.Context, in *GetLeaseRequest, opts ...grpc.CallOption) (*GetLeaseResponse, error)
-	DeleteLease(ctx context.Context, in *DeleteLeaseRequest, opts ...grpc.CallOption) (*DeleteLeaseResponse, error)
-	RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error)
-	PurgeLeases(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
-	FetchConfiguration(ctx context.Context, in *ConfigurationRequest, opts ...grpc.CallOption) (*ConfigurationResponse, error)
+func (m *StartRenumberRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
 }
 
-type wireguardServiceClient struct {
-	cc *grpc.ClientConn
+type RenumberStatusRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func NewWireguardServiceClient(cc *grpc.ClientConn) WireguardServiceClient {
-	return &wireguardServiceClient{cc}
+func (m *RenumberStatusRequest) Reset()         { *m = RenumberStatusRequest{} }
+func (m *RenumberStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*RenumberStatusRequest) ProtoMessage()    {}
+func (*RenumberStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{32}
 }
 
-func (c *wireguardServiceClient) CreateNetwork(ctx context.Context, in *CreateNetworkRequest, opts ...grpc.CallOption) (*CreateNetworkResponse, error) {
-	out := new(CreateNetworkResponse)
-	err := c.cc.Invoke(ctx, "/proto.WireguardService/CreateNetwork", in, out, opts...)
-	if err != nil {
+func (m *RenumberStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenumberStatusRequest.Unmarshal(m, b)
+}
+func (m *RenumberStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenumberStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *RenumberStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenumberStatusRequest.Merge(m, src)
+}
+func (m *RenumberStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_RenumberStatusRequest.Size(m)
+}
+func (m *RenumberStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenumberStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenumberStatusRequest proto.InternalMessageInfo
+
+func (m *RenumberStatusRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type FinishRenumberRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Finish even if some leases have not acknowledged their new range
+	Force                bool     `protobuf:"varint,2,opt,name=force,proto3" json:"force,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FinishRenumberRequest) Reset()         { *m = FinishRenumberRequest{} }
+func (m *FinishRenumberRequest) String() string { return proto.CompactTextString(m) }
+func (*FinishRenumberRequest) ProtoMessage()    {}
+func (*FinishRenumberRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{33}
+}
+
+func (m *FinishRenumberRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FinishRenumberRequest.Unmarshal(m, b)
+}
+func (m *FinishRenumberRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FinishRenumberRequest.Marshal(b, m, deterministic)
+}
+func (m *FinishRenumberRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FinishRenumberRequest.Merge(m, src)
+}
+func (m *FinishRenumberRequest) XXX_Size() int {
+	return xxx_messageInfo_FinishRenumberRequest.Size(m)
+}
+func (m *FinishRenumberRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FinishRenumberRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FinishRenumberRequest proto.InternalMessageInfo
+
+func (m *FinishRenumberRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FinishRenumberRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+type AbortRenumberRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AbortRenumberRequest) Reset()         { *m = AbortRenumberRequest{} }
+func (m *AbortRenumberRequest) String() string { return proto.CompactTextString(m) }
+func (*AbortRenumberRequest) ProtoMessage()    {}
+func (*AbortRenumberRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{34}
+}
+
+func (m *AbortRenumberRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AbortRenumberRequest.Unmarshal(m, b)
+}
+func (m *AbortRenumberRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AbortRenumberRequest.Marshal(b, m, deterministic)
+}
+func (m *AbortRenumberRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AbortRenumberRequest.Merge(m, src)
+}
+func (m *AbortRenumberRequest) XXX_Size() int {
+	return xxx_messageInfo_AbortRenumberRequest.Size(m)
+}
+func (m *AbortRenumberRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AbortRenumberRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AbortRenumberRequest proto.InternalMessageInfo
+
+func (m *AbortRenumberRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// AcknowledgeRenumberRequest is sent by the agent once the next range of
+// its lease is configured on the host
+type AcknowledgeRenumberRequest struct {
+	Uuid string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// Next range the agent configured, it must be the one of the lease
+	NextIpRange          string   `protobuf:"bytes,2,opt,name=next_ip_range,json=nextIpRange,proto3" json:"next_ip_range,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcknowledgeRenumberRequest) Reset()         { *m = AcknowledgeRenumberRequest{} }
+func (m *AcknowledgeRenumberRequest) String() string { return proto.CompactTextString(m) }
+func (*AcknowledgeRenumberRequest) ProtoMessage()    {}
+func (*AcknowledgeRenumberRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{35}
+}
+
+func (m *AcknowledgeRenumberRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcknowledgeRenumberRequest.Unmarshal(m, b)
+}
+func (m *AcknowledgeRenumberRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcknowledgeRenumberRequest.Marshal(b, m, deterministic)
+}
+func (m *AcknowledgeRenumberRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcknowledgeRenumberRequest.Merge(m, src)
+}
+func (m *AcknowledgeRenumberRequest) XXX_Size() int {
+	return xxx_messageInfo_AcknowledgeRenumberRequest.Size(m)
+}
+func (m *AcknowledgeRenumberRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcknowledgeRenumberRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcknowledgeRenumberRequest proto.InternalMessageInfo
+
+func (m *AcknowledgeRenumberRequest) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+func (m *AcknowledgeRenumberRequest) GetNextIpRange() string {
+	if m != nil {
+		return m.NextIpRange
+	}
+	return ""
+}
+
+type AcknowledgeRenumberResponse struct {
+	Uuid                 string   `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcknowledgeRenumberResponse) Reset()         { *m = AcknowledgeRenumberResponse{} }
+func (m *AcknowledgeRenumberResponse) String() string { return proto.CompactTextString(m) }
+func (*AcknowledgeRenumberResponse) ProtoMessage()    {}
+func (*AcknowledgeRenumberResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{36}
+}
+
+func (m *AcknowledgeRenumberResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcknowledgeRenumberResponse.Unmarshal(m, b)
+}
+func (m *AcknowledgeRenumberResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcknowledgeRenumberResponse.Marshal(b, m, deterministic)
+}
+func (m *AcknowledgeRenumberResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcknowledgeRenumberResponse.Merge(m, src)
+}
+func (m *AcknowledgeRenumberResponse) XXX_Size() int {
+	return xxx_messageInfo_AcknowledgeRenumberResponse.Size(m)
+}
+func (m *AcknowledgeRenumberResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcknowledgeRenumberResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcknowledgeRenumberResponse proto.InternalMessageInfo
+
+func (m *AcknowledgeRenumberResponse) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+type RenumberedLease struct {
+	Uuid        string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	IpRange     string `protobuf:"bytes,2,opt,name=ip_range,json=ipRange,proto3" json:"ip_range,omitempty"`
+	NextIpRange string `protobuf:"bytes,3,opt,name=next_ip_range,json=nextIpRange,proto3" json:"next_ip_range,omitempty"`
+	// True once the lease holder has configured its next range
+	Acknowledged         bool     `protobuf:"varint,4,opt,name=acknowledged,proto3" json:"acknowledged,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenumberedLease) Reset()         { *m = RenumberedLease{} }
+func (m *RenumberedLease) String() string { return proto.CompactTextString(m) }
+func (*RenumberedLease) ProtoMessage()    {}
+func (*RenumberedLease) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{37}
+}
+
+func (m *RenumberedLease) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenumberedLease.Unmarshal(m, b)
+}
+func (m *RenumberedLease) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenumberedLease.Marshal(b, m, deterministic)
+}
+func (m *RenumberedLease) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenumberedLease.Merge(m, src)
+}
+func (m *RenumberedLease) XXX_Size() int {
+	return xxx_messageInfo_RenumberedLease.Size(m)
+}
+func (m *RenumberedLease) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenumberedLease.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenumberedLease proto.InternalMessageInfo
+
+func (m *RenumberedLease) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+func (m *RenumberedLease) GetIpRange() string {
+	if m != nil {
+		return m.IpRange
+	}
+	return ""
+}
+
+func (m *RenumberedLease) GetNextIpRange() string {
+	if m != nil {
+		return m.NextIpRange
+	}
+	return ""
+}
+
+func (m *RenumberedLease) GetAcknowledged() bool {
+	if m != nil {
+		return m.Acknowledged
+	}
+	return false
+}
+
+type RenumberStatus struct {
+	Network     string `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Address     string `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	NextAddress string `protobuf:"bytes,3,opt,name=next_address,json=nextAddress,proto3" json:"next_address,omitempty"`
+	// Unix timestamp of the start of the renumbering
+	Started              int64              `protobuf:"varint,4,opt,name=started,proto3" json:"started,omitempty"`
+	Leases               []*RenumberedLease `protobuf:"bytes,5,rep,name=leases,proto3" json:"leases,omitempty"`
+	Acknowledged         int32              `protobuf:"varint,6,opt,name=acknowledged,proto3" json:"acknowledged,omitempty"`
+	Total                int32              `protobuf:"varint,7,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *RenumberStatus) Reset()         { *m = RenumberStatus{} }
+func (m *RenumberStatus) String() string { return proto.CompactTextString(m) }
+func (*RenumberStatus) ProtoMessage()    {}
+func (*RenumberStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{38}
+}
+
+func (m *RenumberStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenumberStatus.Unmarshal(m, b)
+}
+func (m *RenumberStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenumberStatus.Marshal(b, m, deterministic)
+}
+func (m *RenumberStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenumberStatus.Merge(m, src)
+}
+func (m *RenumberStatus) XXX_Size() int {
+	return xxx_messageInfo_RenumberStatus.Size(m)
+}
+func (m *RenumberStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenumberStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenumberStatus proto.InternalMessageInfo
+
+func (m *RenumberStatus) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *RenumberStatus) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *RenumberStatus) GetNextAddress() string {
+	if m != nil {
+		return m.NextAddress
+	}
+	return ""
+}
+
+func (m *RenumberStatus) GetStarted() int64 {
+	if m != nil {
+		return m.Started
+	}
+	return 0
+}
+
+func (m *RenumberStatus) GetLeases() []*RenumberedLease {
+	if m != nil {
+		return m.Leases
+	}
+	return nil
+}
+
+func (m *RenumberStatus) GetAcknowledged() int32 {
+	if m != nil {
+		return m.Acknowledged
+	}
+	return 0
+}
+
+func (m *RenumberStatus) GetTotal() int32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type RenumberNetworkResponse struct {
+	Status               *RenumberStatus `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *RenumberNetworkResponse) Reset()         { *m = RenumberNetworkResponse{} }
+func (m *RenumberNetworkResponse) String() string { return proto.CompactTextString(m) }
+func (*RenumberNetworkResponse) ProtoMessage()    {}
+func (*RenumberNetworkResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{39}
+}
+
+func (m *RenumberNetworkResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenumberNetworkResponse.Unmarshal(m, b)
+}
+func (m *RenumberNetworkResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenumberNetworkResponse.Marshal(b, m, deterministic)
+}
+func (m *RenumberNetworkResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenumberNetworkResponse.Merge(m, src)
+}
+func (m *RenumberNetworkResponse) XXX_Size() int {
+	return xxx_messageInfo_RenumberNetworkResponse.Size(m)
+}
+func (m *RenumberNetworkResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenumberNetworkResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenumberNetworkResponse proto.InternalMessageInfo
+
+func (m *RenumberNetworkResponse) GetStatus() *RenumberStatus {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+type PeerReport struct {
+	PublicKey string `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// Whether a handshake ever completed with the peer
+	Handshake bool `protobuf:"varint,2,opt,name=handshake,proto3" json:"handshake,omitempty"`
+	// Seconds since the last handshake
+	HandshakeAge         int64    `protobuf:"varint,3,opt,name=handshake_age,json=handshakeAge,proto3" json:"handshake_age,omitempty"`
+	RxBytes              int64    `protobuf:"varint,4,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	TxBytes              int64    `protobuf:"varint,5,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	Endpoint             string   `protobuf:"bytes,6,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PeerReport) Reset()         { *m = PeerReport{} }
+func (m *PeerReport) String() string { return proto.CompactTextString(m) }
+func (*PeerReport) ProtoMessage()    {}
+func (*PeerReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{40}
+}
+
+func (m *PeerReport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PeerReport.Unmarshal(m, b)
+}
+func (m *PeerReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PeerReport.Marshal(b, m, deterministic)
+}
+func (m *PeerReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PeerReport.Merge(m, src)
+}
+func (m *PeerReport) XXX_Size() int {
+	return xxx_messageInfo_PeerReport.Size(m)
+}
+func (m *PeerReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_PeerReport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PeerReport proto.InternalMessageInfo
+
+func (m *PeerReport) GetPublicKey() string {
+	if m != nil {
+		return m.PublicKey
+	}
+	return ""
+}
+
+func (m *PeerReport) GetHandshake() bool {
+	if m != nil {
+		return m.Handshake
+	}
+	return false
+}
+
+func (m *PeerReport) GetHandshakeAge() int64 {
+	if m != nil {
+		return m.HandshakeAge
+	}
+	return 0
+}
+
+func (m *PeerReport) GetRxBytes() int64 {
+	if m != nil {
+		return m.RxBytes
+	}
+	return 0
+}
+
+func (m *PeerReport) GetTxBytes() int64 {
+	if m != nil {
+		return m.TxBytes
+	}
+	return 0
+}
+
+func (m *PeerReport) GetEndpoint() string {
+	if m != nil {
+		return m.Endpoint
+	}
+	return ""
+}
+
+type ReportStatusRequest struct {
+	// Lease of the reporting agent
+	LeaseUuid            string        `protobuf:"bytes,1,opt,name=lease_uuid,json=leaseUuid,proto3" json:"lease_uuid,omitempty"`
+	Peers                []*PeerReport `protobuf:"bytes,2,rep,name=peers,proto3" json:"peers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ReportStatusRequest) Reset()         { *m = ReportStatusRequest{} }
+func (m *ReportStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*ReportStatusRequest) ProtoMessage()    {}
+func (*ReportStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{41}
+}
+
+func (m *ReportStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportStatusRequest.Unmarshal(m, b)
+}
+func (m *ReportStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *ReportStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportStatusRequest.Merge(m, src)
+}
+func (m *ReportStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_ReportStatusRequest.Size(m)
+}
+func (m *ReportStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportStatusRequest proto.InternalMessageInfo
+
+func (m *ReportStatusRequest) GetLeaseUuid() string {
+	if m != nil {
+		return m.LeaseUuid
+	}
+	return ""
+}
+
+func (m *ReportStatusRequest) GetPeers() []*PeerReport {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+type ReportStatusResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportStatusResponse) Reset()         { *m = ReportStatusResponse{} }
+func (m *ReportStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*ReportStatusResponse) ProtoMessage()    {}
+func (*ReportStatusResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{42}
+}
+
+func (m *ReportStatusResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportStatusResponse.Unmarshal(m, b)
+}
+func (m *ReportStatusResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportStatusResponse.Marshal(b, m, deterministic)
+}
+func (m *ReportStatusResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportStatusResponse.Merge(m, src)
+}
+func (m *ReportStatusResponse) XXX_Size() int {
+	return xxx_messageInfo_ReportStatusResponse.Size(m)
+}
+func (m *ReportStatusResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportStatusResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportStatusResponse proto.InternalMessageInfo
+
+type NetworkHealthRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NetworkHealthRequest) Reset()         { *m = NetworkHealthRequest{} }
+func (m *NetworkHealthRequest) String() string { return proto.CompactTextString(m) }
+func (*NetworkHealthRequest) ProtoMessage()    {}
+func (*NetworkHealthRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{43}
+}
+
+func (m *NetworkHealthRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NetworkHealthRequest.Unmarshal(m, b)
+}
+func (m *NetworkHealthRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NetworkHealthRequest.Marshal(b, m, deterministic)
+}
+func (m *NetworkHealthRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetworkHealthRequest.Merge(m, src)
+}
+func (m *NetworkHealthRequest) XXX_Size() int {
+	return xxx_messageInfo_NetworkHealthRequest.Size(m)
+}
+func (m *NetworkHealthRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkHealthRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkHealthRequest proto.InternalMessageInfo
+
+func (m *NetworkHealthRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type PeerHealth struct {
+	// Lease that reported the peer
+	LeaseUuid string `protobuf:"bytes,1,opt,name=lease_uuid,json=leaseUuid,proto3" json:"lease_uuid,omitempty"`
+	IpRange   string `protobuf:"bytes,2,opt,name=ip_range,json=ipRange,proto3" json:"ip_range,omitempty"`
+	// Lease of the peer
+	PeerLeaseUuid string `protobuf:"bytes,3,opt,name=peer_lease_uuid,json=peerLeaseUuid,proto3" json:"peer_lease_uuid,omitempty"`
+	PeerIpRange   string `protobuf:"bytes,4,opt,name=peer_ip_range,json=peerIpRange,proto3" json:"peer_ip_range,omitempty"`
+	// False if the lease did not report the peer
+	Reported  bool `protobuf:"varint,5,opt,name=reported,proto3" json:"reported,omitempty"`
+	Handshake bool `protobuf:"varint,6,opt,name=handshake,proto3" json:"handshake,omitempty"`
+	// Seconds since the last handshake, as of now
+	HandshakeAge int64  `protobuf:"varint,7,opt,name=handshake_age,json=handshakeAge,proto3" json:"handshake_age,omitempty"`
+	RxBytes      int64  `protobuf:"varint,8,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	TxBytes      int64  `protobuf:"varint,9,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	Endpoint     string `protobuf:"bytes,10,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	// Unix timestamp of the report
+	ReportedAt           int64    `protobuf:"varint,11,opt,name=reported_at,json=reportedAt,proto3" json:"reported_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PeerHealth) Reset()         { *m = PeerHealth{} }
+func (m *PeerHealth) String() string { return proto.CompactTextString(m) }
+func (*PeerHealth) ProtoMessage()    {}
+func (*PeerHealth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{44}
+}
+
+func (m *PeerHealth) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PeerHealth.Unmarshal(m, b)
+}
+func (m *PeerHealth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PeerHealth.Marshal(b, m, deterministic)
+}
+func (m *PeerHealth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PeerHealth.Merge(m, src)
+}
+func (m *PeerHealth) XXX_Size() int {
+	return xxx_messageInfo_PeerHealth.Size(m)
+}
+func (m *PeerHealth) XXX_DiscardUnknown() {
+	xxx_messageInfo_PeerHealth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PeerHealth proto.InternalMessageInfo
+
+func (m *PeerHealth) GetLeaseUuid() string {
+	if m != nil {
+		return m.LeaseUuid
+	}
+	return ""
+}
+
+func (m *PeerHealth) GetIpRange() string {
+	if m != nil {
+		return m.IpRange
+	}
+	return ""
+}
+
+func (m *PeerHealth) GetPeerLeaseUuid() string {
+	if m != nil {
+		return m.PeerLeaseUuid
+	}
+	return ""
+}
+
+func (m *PeerHealth) GetPeerIpRange() string {
+	if m != nil {
+		return m.PeerIpRange
+	}
+	return ""
+}
+
+func (m *PeerHealth) GetReported() bool {
+	if m != nil {
+		return m.Reported
+	}
+	return false
+}
+
+func (m *PeerHealth) GetHandshake() bool {
+	if m != nil {
+		return m.Handshake
+	}
+	return false
+}
+
+func (m *PeerHealth) GetHandshakeAge() int64 {
+	if m != nil {
+		return m.HandshakeAge
+	}
+	return 0
+}
+
+func (m *PeerHealth) GetRxBytes() int64 {
+	if m != nil {
+		return m.RxBytes
+	}
+	return 0
+}
+
+func (m *PeerHealth) GetTxBytes() int64 {
+	if m != nil {
+		return m.TxBytes
+	}
+	return 0
+}
+
+func (m *PeerHealth) GetEndpoint() string {
+	if m != nil {
+		return m.Endpoint
+	}
+	return ""
+}
+
+func (m *PeerHealth) GetReportedAt() int64 {
+	if m != nil {
+		return m.ReportedAt
+	}
+	return 0
+}
+
+type NetworkHealthResponse struct {
+	Network string        `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Peers   []*PeerHealth `protobuf:"bytes,2,rep,name=peers,proto3" json:"peers,omitempty"`
+	// Number of pairs that never completed a handshake
+	Unhealthy            int32    `protobuf:"varint,3,opt,name=unhealthy,proto3" json:"unhealthy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NetworkHealthResponse) Reset()         { *m = NetworkHealthResponse{} }
+func (m *NetworkHealthResponse) String() string { return proto.CompactTextString(m) }
+func (*NetworkHealthResponse) ProtoMessage()    {}
+func (*NetworkHealthResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{45}
+}
+
+func (m *NetworkHealthResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NetworkHealthResponse.Unmarshal(m, b)
+}
+func (m *NetworkHealthResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NetworkHealthResponse.Marshal(b, m, deterministic)
+}
+func (m *NetworkHealthResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetworkHealthResponse.Merge(m, src)
+}
+func (m *NetworkHealthResponse) XXX_Size() int {
+	return xxx_messageInfo_NetworkHealthResponse.Size(m)
+}
+func (m *NetworkHealthResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkHealthResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkHealthResponse proto.InternalMessageInfo
+
+func (m *NetworkHealthResponse) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *NetworkHealthResponse) GetPeers() []*PeerHealth {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+func (m *NetworkHealthResponse) GetUnhealthy() int32 {
+	if m != nil {
+		return m.Unhealthy
+	}
+	return 0
+}
+
+type RotateLeaseKeyRequest struct {
+	Uuid          string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	NextPublicKey string `protobuf:"bytes,2,opt,name=next_public_key,json=nextPublicKey,proto3" json:"next_public_key,omitempty"`
+	// HMAC-SHA256 of the lease uuid and the next public key, keyed with the
+	// X25519 shared secret of the current lease key and the controller key
+	Proof                []byte   `protobuf:"bytes,3,opt,name=proof,proto3" json:"proof,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RotateLeaseKeyRequest) Reset()         { *m = RotateLeaseKeyRequest{} }
+func (m *RotateLeaseKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*RotateLeaseKeyRequest) ProtoMessage()    {}
+func (*RotateLeaseKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{46}
+}
+
+func (m *RotateLeaseKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RotateLeaseKeyRequest.Unmarshal(m, b)
+}
+func (m *RotateLeaseKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RotateLeaseKeyRequest.Marshal(b, m, deterministic)
+}
+func (m *RotateLeaseKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RotateLeaseKeyRequest.Merge(m, src)
+}
+func (m *RotateLeaseKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_RotateLeaseKeyRequest.Size(m)
+}
+func (m *RotateLeaseKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RotateLeaseKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RotateLeaseKeyRequest proto.InternalMessageInfo
+
+func (m *RotateLeaseKeyRequest) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+func (m *RotateLeaseKeyRequest) GetNextPublicKey() string {
+	if m != nil {
+		return m.NextPublicKey
+	}
+	return ""
+}
+
+func (m *RotateLeaseKeyRequest) GetProof() []byte {
+	if m != nil {
+		return m.Proof
+	}
+	return nil
+}
+
+type RotateLeaseKeyResponse struct {
+	Lease                *Lease   `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RotateLeaseKeyResponse) Reset()         { *m = RotateLeaseKeyResponse{} }
+func (m *RotateLeaseKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*RotateLeaseKeyResponse) ProtoMessage()    {}
+func (*RotateLeaseKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{47}
+}
+
+func (m *RotateLeaseKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RotateLeaseKeyResponse.Unmarshal(m, b)
+}
+func (m *RotateLeaseKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RotateLeaseKeyResponse.Marshal(b, m, deterministic)
+}
+func (m *RotateLeaseKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RotateLeaseKeyResponse.Merge(m, src)
+}
+func (m *RotateLeaseKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_RotateLeaseKeyResponse.Size(m)
+}
+func (m *RotateLeaseKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RotateLeaseKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RotateLeaseKeyResponse proto.InternalMessageInfo
+
+func (m *RotateLeaseKeyResponse) GetLease() *Lease {
+	if m != nil {
+		return m.Lease
+	}
+	return nil
+}
+
+type ControllerKeyResponse struct {
+	PublicKey            string   `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ControllerKeyResponse) Reset()         { *m = ControllerKeyResponse{} }
+func (m *ControllerKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*ControllerKeyResponse) ProtoMessage()    {}
+func (*ControllerKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{48}
+}
+
+func (m *ControllerKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ControllerKeyResponse.Unmarshal(m, b)
+}
+func (m *ControllerKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ControllerKeyResponse.Marshal(b, m, deterministic)
+}
+func (m *ControllerKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ControllerKeyResponse.Merge(m, src)
+}
+func (m *ControllerKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_ControllerKeyResponse.Size(m)
+}
+func (m *ControllerKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ControllerKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ControllerKeyResponse proto.InternalMessageInfo
+
+func (m *ControllerKeyResponse) GetPublicKey() string {
+	if m != nil {
+		return m.PublicKey
+	}
+	return ""
+}
+
+type Route struct {
+	Network string `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	// Node advertising the prefix
+	NodeName string `protobuf:"bytes,2,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	Prefix   string `protobuf:"bytes,3,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Only approved routes are given to the peers
+	Approved bool `protobuf:"varint,4,opt,name=approved,proto3" json:"approved,omitempty"`
+	// Lease advertising the prefix
+	LeaseUuid            string   `protobuf:"bytes,5,opt,name=lease_uuid,json=leaseUuid,proto3" json:"lease_uuid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Route) Reset()         { *m = Route{} }
+func (m *Route) String() string { return proto.CompactTextString(m) }
+func (*Route) ProtoMessage()    {}
+func (*Route) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{49}
+}
+
+func (m *Route) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Route.Unmarshal(m, b)
+}
+func (m *Route) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Route.Marshal(b, m, deterministic)
+}
+func (m *Route) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Route.Merge(m, src)
+}
+func (m *Route) XXX_Size() int {
+	return xxx_messageInfo_Route.Size(m)
+}
+func (m *Route) XXX_DiscardUnknown() {
+	xxx_messageInfo_Route.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Route proto.InternalMessageInfo
+
+func (m *Route) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *Route) GetNodeName() string {
+	if m != nil {
+		return m.NodeName
+	}
+	return ""
+}
+
+func (m *Route) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *Route) GetApproved() bool {
+	if m != nil {
+		return m.Approved
+	}
+	return false
+}
+
+func (m *Route) GetLeaseUuid() string {
+	if m != nil {
+		return m.LeaseUuid
+	}
+	return ""
+}
+
+type ListRoutesRequest struct {
+	// Name of the network, all the networks if empty
+	Network              string   `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRoutesRequest) Reset()         { *m = ListRoutesRequest{} }
+func (m *ListRoutesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRoutesRequest) ProtoMessage()    {}
+func (*ListRoutesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{50}
+}
+
+func (m *ListRoutesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRoutesRequest.Unmarshal(m, b)
+}
+func (m *ListRoutesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRoutesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRoutesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRoutesRequest.Merge(m, src)
+}
+func (m *ListRoutesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRoutesRequest.Size(m)
+}
+func (m *ListRoutesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRoutesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRoutesRequest proto.InternalMessageInfo
+
+func (m *ListRoutesRequest) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+type ListRoutesResponse struct {
+	Routes               []*Route `protobuf:"bytes,1,rep,name=routes,proto3" json:"routes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRoutesResponse) Reset()         { *m = ListRoutesResponse{} }
+func (m *ListRoutesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListRoutesResponse) ProtoMessage()    {}
+func (*ListRoutesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{51}
+}
+
+func (m *ListRoutesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRoutesResponse.Unmarshal(m, b)
+}
+func (m *ListRoutesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRoutesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListRoutesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRoutesResponse.Merge(m, src)
+}
+func (m *ListRoutesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListRoutesResponse.Size(m)
+}
+func (m *ListRoutesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRoutesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRoutesResponse proto.InternalMessageInfo
+
+func (m *ListRoutesResponse) GetRoutes() []*Route {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+type RouteRequest struct {
+	Network string `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	// Uuid of the lease advertising the prefix, or its node name if
+	// only one lease of that name advertises it
+	NodeName             string   `protobuf:"bytes,2,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	Prefix               string   `protobuf:"bytes,3,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RouteRequest) Reset()         { *m = RouteRequest{} }
+func (m *RouteRequest) String() string { return proto.CompactTextString(m) }
+func (*RouteRequest) ProtoMessage()    {}
+func (*RouteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{52}
+}
+
+func (m *RouteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RouteRequest.Unmarshal(m, b)
+}
+func (m *RouteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RouteRequest.Marshal(b, m, deterministic)
+}
+func (m *RouteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteRequest.Merge(m, src)
+}
+func (m *RouteRequest) XXX_Size() int {
+	return xxx_messageInfo_RouteRequest.Size(m)
+}
+func (m *RouteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteRequest proto.InternalMessageInfo
+
+func (m *RouteRequest) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *RouteRequest) GetNodeName() string {
+	if m != nil {
+		return m.NodeName
+	}
+	return ""
+}
+
+func (m *RouteRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+type RouteResponse struct {
+	Route                *Route   `protobuf:"bytes,1,opt,name=route,proto3" json:"route,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RouteResponse) Reset()         { *m = RouteResponse{} }
+func (m *RouteResponse) String() string { return proto.CompactTextString(m) }
+func (*RouteResponse) ProtoMessage()    {}
+func (*RouteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_00212fb1f9d3bf1c, []int{53}
+}
+
+func (m *RouteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RouteResponse.Unmarshal(m, b)
+}
+func (m *RouteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RouteResponse.Marshal(b, m, deterministic)
+}
+func (m *RouteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteResponse.Merge(m, src)
+}
+func (m *RouteResponse) XXX_Size() int {
+	return xxx_messageInfo_RouteResponse.Size(m)
+}
+func (m *RouteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteResponse proto.InternalMessageInfo
+
+func (m *RouteResponse) GetRoute() *Route {
+	if m != nil {
+		return m.Route
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("proto.DNS", DNS_name, DNS_value)
+	proto.RegisterEnum("proto.PresharedKeys", PresharedKeys_name, PresharedKeys_value)
+	proto.RegisterType((*ListNetworksResponse)(nil), "proto.ListNetworksResponse")
+	proto.RegisterType((*GetNetworkRequest)(nil), "proto.GetNetworkRequest")
+	proto.RegisterType((*GetNetworkResponse)(nil), "proto.GetNetworkResponse")
+	proto.RegisterType((*DeleteNetworkRequest)(nil), "proto.DeleteNetworkRequest")
+	proto.RegisterType((*DeleteNetworkResponse)(nil), "proto.DeleteNetworkResponse")
+	proto.RegisterType((*DeleteLeaseRequest)(nil), "proto.DeleteLeaseRequest")
+	proto.RegisterType((*DeleteLeaseResponse)(nil), "proto.DeleteLeaseResponse")
+	proto.RegisterType((*Network)(nil), "proto.Network")
+	proto.RegisterType((*NetworkSettings)(nil), "proto.NetworkSettings")
+	proto.RegisterType((*CreateNetworkRequest)(nil), "proto.CreateNetworkRequest")
+	proto.RegisterType((*UpdateNetworkRequest)(nil), "proto.UpdateNetworkRequest")
+	proto.RegisterType((*UpdateNetworkResponse)(nil), "proto.UpdateNetworkResponse")
+	proto.RegisterType((*CreateNetworkResponse)(nil), "proto.CreateNetworkResponse")
+	proto.RegisterType((*ImportedPeer)(nil), "proto.ImportedPeer")
+	proto.RegisterType((*ImportNetworkRequest)(nil), "proto.ImportNetworkRequest")
+	proto.RegisterType((*ImportNetworkResponse)(nil), "proto.ImportNetworkResponse")
+	proto.RegisterType((*PublicPeer)(nil), "proto.PublicPeer")
+	proto.RegisterType((*Endpoint)(nil), "proto.Endpoint")
+	proto.RegisterType((*NetworkDefinition)(nil), "proto.NetworkDefinition")
+	proto.RegisterType((*AcquireLeaseRequest)(nil), "proto.AcquireLeaseRequest")
+	proto.RegisterType((*RenewLeaseRequest)(nil), "proto.RenewLeaseRequest")
+	proto.RegisterType((*RenewLeaseResponse)(nil), "proto.RenewLeaseResponse")
+	proto.RegisterType((*ReleaseLeaseRequest)(nil), "proto.ReleaseLeaseRequest")
+	proto.RegisterType((*ReleaseLeaseResponse)(nil), "proto.ReleaseLeaseResponse")
+	proto.RegisterType((*Lease)(nil), "proto.Lease")
+	proto.RegisterType((*AcquireLeaseResponse)(nil), "proto.AcquireLeaseResponse")
+	proto.RegisterType((*GetLeaseRequest)(nil), "proto.GetLeaseRequest")
+	proto.RegisterType((*GetLeaseResponse)(nil), "proto.GetLeaseResponse")
+	proto.RegisterType((*ListLeasesResponse)(nil), "proto.ListLeasesResponse")
+	proto.RegisterType((*ConfigurationRequest)(nil), "proto.ConfigurationRequest")
+	proto.RegisterType((*ConfigurationResponse)(nil), "proto.ConfigurationResponse")
+	proto.RegisterType((*StartRenumberRequest)(nil), "proto.StartRenumberRequest")
+	proto.RegisterType((*RenumberStatusRequest)(nil), "proto.RenumberStatusRequest")
+	proto.RegisterType((*FinishRenumberRequest)(nil), "proto.FinishRenumberRequest")
+	proto.RegisterType((*AbortRenumberRequest)(nil), "proto.AbortRenumberRequest")
+	proto.RegisterType((*AcknowledgeRenumberRequest)(nil), "proto.AcknowledgeRenumberRequest")
+	proto.RegisterType((*AcknowledgeRenumberResponse)(nil), "proto.AcknowledgeRenumberResponse")
+	proto.RegisterType((*RenumberedLease)(nil), "proto.RenumberedLease")
+	proto.RegisterType((*RenumberStatus)(nil), "proto.RenumberStatus")
+	proto.RegisterType((*RenumberNetworkResponse)(nil), "proto.RenumberNetworkResponse")
+	proto.RegisterType((*PeerReport)(nil), "proto.PeerReport")
+	proto.RegisterType((*ReportStatusRequest)(nil), "proto.ReportStatusRequest")
+	proto.RegisterType((*ReportStatusResponse)(nil), "proto.ReportStatusResponse")
+	proto.RegisterType((*NetworkHealthRequest)(nil), "proto.NetworkHealthRequest")
+	proto.RegisterType((*PeerHealth)(nil), "proto.PeerHealth")
+	proto.RegisterType((*NetworkHealthResponse)(nil), "proto.NetworkHealthResponse")
+	proto.RegisterType((*RotateLeaseKeyRequest)(nil), "proto.RotateLeaseKeyRequest")
+	proto.RegisterType((*RotateLeaseKeyResponse)(nil), "proto.RotateLeaseKeyResponse")
+	proto.RegisterType((*ControllerKeyResponse)(nil), "proto.ControllerKeyResponse")
+	proto.RegisterType((*Route)(nil), "proto.Route")
+	proto.RegisterType((*ListRoutesRequest)(nil), "proto.ListRoutesRequest")
+	proto.RegisterType((*ListRoutesResponse)(nil), "proto.ListRoutesResponse")
+	proto.RegisterType((*RouteRequest)(nil), "proto.RouteRequest")
+	proto.RegisterType((*RouteResponse)(nil), "proto.RouteResponse")
+}
+
+func init() { proto.RegisterFile("api.proto", fileDescriptor_00212fb1f9d3bf1c) }
+
+var fileDescriptor_00212fb1f9d3bf1c = []byte{
+	// 2284 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x59, 0xdd, 0x6e, 0xdb, 0xc8,
+	0x15, 0x0e, 0x25, 0xcb, 0x96, 0x8e, 0x24, 0xdb, 0x19, 0xcb, 0x8e, 0x4c, 0x69, 0x5b, 0x2f, 0xdb,
+	0xec, 0x66, 0x53, 0xc4, 0x8b, 0xf5, 0xa2, 0xdb, 0x22, 0xe9, 0x0f, 0x94, 0xc8, 0xd9, 0xa4, 0x36,
+	0x54, 0x97, 0xda, 0xa0, 0x7f, 0xd8, 0x15, 0x68, 0x73, 0x2c, 0x13, 0x96, 0x49, 0x2e, 0x39, 0x4c,
+	0xac, 0x07, 0x28, 0x50, 0xa0, 0x37, 0x6d, 0xaf, 0x0a, 0xf4, 0x19, 0xfa, 0x0e, 0xbd, 0xeb, 0x13,
+	0xf4, 0x0d, 0x7a, 0xd7, 0x3e, 0x44, 0x31, 0xbf, 0xe4, 0x50, 0xd4, 0x8f, 0x73, 0x65, 0xcd, 0x39,
+	0xc3, 0x6f, 0xce, 0x9c, 0xff, 0x39, 0x86, 0x9a, 0x13, 0x7a, 0x87, 0x61, 0x14, 0x90, 0x00, 0x55,
+	0xd8, 0x1f, 0xb3, 0x33, 0x0e, 0x82, 0xf1, 0x04, 0x7f, 0xca, 0x56, 0xe7, 0xc9, 0xe5, 0xa7, 0xf8,
+	0x26, 0x24, 0x53, 0xbe, 0xc7, 0x7a, 0x0e, 0xad, 0x53, 0x2f, 0x26, 0x03, 0x4c, 0xde, 0x05, 0xd1,
+	0x75, 0x6c, 0xe3, 0x38, 0x0c, 0xfc, 0x18, 0xa3, 0xc7, 0x50, 0xf5, 0x05, 0xad, 0x6d, 0x1c, 0x94,
+	0x1f, 0xd5, 0x8f, 0x36, 0xf9, 0x17, 0x87, 0x62, 0xab, 0xad, 0xf8, 0xd6, 0xc7, 0x70, 0xff, 0x4b,
+	0x2c, 0x21, 0x6c, 0xfc, 0x6d, 0x82, 0x63, 0x82, 0x10, 0xac, 0xf9, 0xce, 0x0d, 0x6e, 0x1b, 0x07,
+	0xc6, 0xa3, 0x9a, 0xcd, 0x7e, 0x5b, 0x3f, 0x03, 0x94, 0xdd, 0x28, 0x8e, 0x7a, 0x04, 0x1b, 0x02,
+	0x8a, 0x6d, 0x9e, 0x3d, 0x49, 0xb2, 0xad, 0xc7, 0xd0, 0xea, 0xe3, 0x09, 0x26, 0x78, 0x85, 0xb3,
+	0x7e, 0x00, 0xbb, 0xb9, 0xbd, 0xe2, 0xb8, 0xa2, 0xcd, 0x8f, 0x00, 0xf1, 0xcd, 0xa7, 0xd8, 0x89,
+	0x71, 0x06, 0x36, 0x49, 0x3c, 0x57, 0xee, 0xa4, 0xbf, 0xad, 0x4f, 0x60, 0x47, 0xdb, 0x99, 0x82,
+	0xce, 0x6c, 0xfd, 0x9f, 0x01, 0x1b, 0xe2, 0xf0, 0xa2, 0x43, 0x51, 0x1b, 0x36, 0x1c, 0xd7, 0x8d,
+	0x70, 0x1c, 0xb7, 0x4b, 0x8c, 0x2c, 0x97, 0x94, 0x13, 0x27, 0xe7, 0x3e, 0x26, 0x71, 0xbb, 0x7c,
+	0x50, 0xa6, 0x1c, 0xb1, 0x44, 0xdf, 0x85, 0xba, 0x9f, 0xdc, 0x8c, 0x24, 0x77, 0xed, 0xc0, 0x78,
+	0x54, 0xb1, 0xc1, 0x4f, 0x6e, 0x86, 0x62, 0xc3, 0x87, 0xd0, 0xf0, 0xf1, 0x2d, 0x19, 0x49, 0xe4,
+	0x0a, 0x43, 0xae, 0x53, 0x5a, 0x4f, 0xa0, 0xcb, 0x2d, 0x12, 0x64, 0xfd, 0xa0, 0x2c, 0xb7, 0x48,
+	0x94, 0x23, 0xa8, 0xc6, 0x98, 0x10, 0xcf, 0x1f, 0xc7, 0xed, 0x0d, 0x66, 0x93, 0x3d, 0xdd, 0x26,
+	0x43, 0xc1, 0xb5, 0xd5, 0x3e, 0xeb, 0x6f, 0x25, 0xd8, 0xca, 0x71, 0xd1, 0x36, 0x94, 0x6f, 0x48,
+	0xc2, 0x6e, 0x5d, 0xb1, 0xe9, 0x4f, 0xf4, 0x19, 0xb4, 0x42, 0x1c, 0xc5, 0x5e, 0x4c, 0xb0, 0x4f,
+	0x46, 0xd7, 0x18, 0x87, 0xce, 0xc4, 0x7b, 0x8b, 0x99, 0x06, 0x2a, 0xf6, 0x4e, 0xca, 0x3b, 0x91,
+	0x2c, 0x7a, 0xe7, 0x09, 0xa3, 0x8d, 0xc2, 0x20, 0x22, 0xed, 0x32, 0xbf, 0x33, 0x27, 0x9d, 0x05,
+	0x11, 0x41, 0x0f, 0x61, 0x73, 0x42, 0xad, 0x31, 0x72, 0x93, 0xc8, 0x21, 0x5e, 0xe0, 0x33, 0xbd,
+	0x94, 0xed, 0x26, 0xa3, 0xf6, 0x05, 0x11, 0x3d, 0x83, 0xcd, 0x30, 0xc2, 0xf1, 0x95, 0x13, 0x61,
+	0x77, 0x74, 0x8d, 0xa7, 0x5c, 0x39, 0x9b, 0x47, 0x2d, 0x71, 0xb5, 0x33, 0xc9, 0x3c, 0xc1, 0xd3,
+	0xd8, 0x6e, 0x86, 0xd9, 0x25, 0xfa, 0x00, 0xc0, 0xf5, 0xe3, 0x91, 0x1b, 0xdc, 0x38, 0x9e, 0xdf,
+	0x5e, 0x67, 0x5a, 0xad, 0xb9, 0x7e, 0xdc, 0x67, 0x04, 0xd4, 0x85, 0xb2, 0xeb, 0x73, 0x5d, 0x6d,
+	0x1e, 0x81, 0x00, 0xec, 0x0f, 0x86, 0x36, 0x25, 0x5b, 0x7f, 0x35, 0xa0, 0xf5, 0x22, 0xc2, 0xce,
+	0x2a, 0x8e, 0xbb, 0xaa, 0x5b, 0x50, 0x25, 0x6c, 0xc4, 0x05, 0xf6, 0x5a, 0x5b, 0xd1, 0x5e, 0xdf,
+	0x40, 0xeb, 0x4d, 0xe8, 0xae, 0x26, 0x53, 0x16, 0xbf, 0xb4, 0x22, 0x7e, 0x0f, 0x76, 0x73, 0xf8,
+	0x77, 0x8e, 0xf7, 0x1e, 0xec, 0xe6, 0xd4, 0x76, 0x67, 0x88, 0x3f, 0x19, 0xd0, 0x78, 0x7d, 0x43,
+	0x1d, 0x07, 0xbb, 0x67, 0x18, 0x47, 0xa8, 0x03, 0x35, 0x3f, 0x70, 0xf1, 0x28, 0x73, 0xc7, 0x2a,
+	0x25, 0x0c, 0xe8, 0x3d, 0x3f, 0x00, 0x08, 0x93, 0xf3, 0x89, 0x77, 0x41, 0xfd, 0x43, 0xa8, 0xbf,
+	0xc6, 0x29, 0x27, 0x78, 0x9a, 0x35, 0x4d, 0x59, 0x37, 0xcd, 0x43, 0x58, 0x0b, 0x31, 0x8e, 0x84,
+	0xf2, 0xef, 0x4b, 0x8f, 0x62, 0x5f, 0xd2, 0x63, 0x6d, 0xc6, 0xb6, 0xfe, 0x62, 0x40, 0x8b, 0x4b,
+	0x93, 0x53, 0xfa, 0x0f, 0xf3, 0x17, 0xea, 0x08, 0x88, 0x22, 0xb7, 0x51, 0xb7, 0x43, 0x9f, 0x40,
+	0x85, 0xe2, 0x52, 0xa3, 0xd0, 0x14, 0xbd, 0x23, 0x3e, 0xca, 0x5e, 0xd8, 0xe6, 0x3b, 0xd0, 0x03,
+	0xd8, 0x70, 0xa3, 0xe9, 0x28, 0x4a, 0x7c, 0x26, 0x7b, 0xd5, 0x5e, 0x77, 0xa3, 0xa9, 0x9d, 0xf8,
+	0xd6, 0x1f, 0x0c, 0xd8, 0xcd, 0xc9, 0x74, 0x57, 0x2d, 0xa3, 0xef, 0xc3, 0x3a, 0x8b, 0x35, 0x29,
+	0x48, 0x43, 0x6c, 0xe4, 0x49, 0x52, 0xf0, 0x50, 0x17, 0x6a, 0x17, 0x81, 0x7f, 0x39, 0xf1, 0x2e,
+	0x54, 0x62, 0x4b, 0x09, 0xd6, 0x53, 0x80, 0x54, 0x5f, 0x59, 0x55, 0x1b, 0xba, 0xaa, 0x11, 0xac,
+	0xb1, 0x3c, 0xc0, 0x33, 0x06, 0xfb, 0x6d, 0xfd, 0xb9, 0x04, 0xd5, 0x63, 0xdf, 0x0d, 0x03, 0xcf,
+	0x27, 0xca, 0x16, 0xc6, 0x42, 0x5b, 0x2c, 0xb3, 0xb5, 0x99, 0x29, 0x80, 0x5c, 0x56, 0xb5, 0xd6,
+	0x7d, 0x68, 0x2d, 0xe7, 0x43, 0x1f, 0xc1, 0x16, 0x4b, 0xaf, 0x19, 0x70, 0x9e, 0x84, 0x9b, 0x94,
+	0x7c, 0xa6, 0x0e, 0xe8, 0x40, 0x2d, 0x0a, 0x88, 0x43, 0xf0, 0xc8, 0x21, 0x2c, 0xa1, 0x94, 0xed,
+	0x2a, 0x27, 0xf4, 0x08, 0xfa, 0x1e, 0x34, 0xb5, 0x5c, 0xc5, 0x32, 0x4b, 0xcd, 0x6e, 0x64, 0x93,
+	0x12, 0x45, 0xc0, 0xb7, 0x1e, 0x19, 0xd1, 0xa3, 0xdb, 0x55, 0x66, 0xd4, 0x2a, 0x25, 0x0c, 0x02,
+	0x17, 0x5b, 0xff, 0x32, 0xe0, 0xbe, 0xb0, 0x53, 0x1f, 0x5f, 0x7a, 0xbe, 0xc7, 0x72, 0xe0, 0xdd,
+	0x12, 0xce, 0x13, 0xa8, 0x61, 0xa1, 0x55, 0xae, 0x84, 0xfa, 0xd1, 0x96, 0x50, 0xa7, 0xd4, 0xb6,
+	0x9d, 0xee, 0x98, 0xa9, 0x3d, 0x6b, 0xb3, 0xb5, 0x27, 0x9b, 0x48, 0x2a, 0x2b, 0x26, 0x92, 0xbf,
+	0x97, 0x60, 0xa7, 0x77, 0xf1, 0x6d, 0xe2, 0x45, 0x7a, 0x79, 0x5e, 0x18, 0xc9, 0x4c, 0x16, 0x86,
+	0xc8, 0xf9, 0x25, 0x29, 0x0b, 0xa3, 0x15, 0x04, 0x7b, 0x39, 0xef, 0x00, 0xab, 0x85, 0x34, 0xda,
+	0x83, 0xf5, 0x28, 0x48, 0x08, 0xa6, 0xf7, 0xa1, 0x5e, 0x22, 0x56, 0x54, 0xd3, 0xc4, 0x19, 0xcb,
+	0xea, 0xca, 0x7e, 0x53, 0xa1, 0xc6, 0xd8, 0xc7, 0x11, 0x35, 0xba, 0x34, 0x6a, 0xd5, 0xae, 0x4b,
+	0x1a, 0x3d, 0x75, 0x0f, 0xd6, 0x63, 0xe2, 0x10, 0xef, 0x42, 0x18, 0x54, 0xac, 0xb2, 0xe1, 0x5b,
+	0xd3, 0xc2, 0x77, 0x08, 0xf7, 0x6d, 0xec, 0xe3, 0x77, 0xcb, 0x3a, 0x97, 0x8c, 0xa0, 0xa5, 0x42,
+	0x41, 0xcb, 0xa9, 0xa0, 0xd6, 0x8f, 0x01, 0x65, 0x41, 0x45, 0x3e, 0xb0, 0xa0, 0xc2, 0x22, 0x59,
+	0x44, 0x96, 0x1e, 0xe4, 0x9c, 0x45, 0xfb, 0x23, 0x1b, 0xb3, 0x9f, 0x4b, 0x5b, 0xa9, 0xc7, 0xd0,
+	0xd2, 0xb7, 0x2e, 0xe8, 0xa5, 0xfe, 0x5d, 0x82, 0x0a, 0xdb, 0x85, 0xf6, 0xa1, 0xea, 0x85, 0xa3,
+	0xc8, 0xf1, 0xc7, 0xd2, 0xe8, 0x1b, 0x5e, 0x68, 0xd3, 0x25, 0x75, 0x64, 0x99, 0xaf, 0x84, 0x23,
+	0x8b, 0x25, 0xe5, 0xe0, 0xdb, 0xd0, 0x8b, 0x30, 0x4f, 0xdc, 0x65, 0x5b, 0x2e, 0xd5, 0x61, 0x6b,
+	0x19, 0x4d, 0xe9, 0x8e, 0x51, 0x29, 0xa8, 0x02, 0xfc, 0x6b, 0x97, 0x85, 0x6d, 0x55, 0x82, 0xb9,
+	0xc8, 0x02, 0x16, 0xe3, 0x23, 0x25, 0xe0, 0x46, 0x1a, 0x01, 0xaf, 0x85, 0x90, 0x9a, 0xd7, 0x56,
+	0x97, 0xe7, 0x8e, 0xda, 0xd2, 0xdc, 0x01, 0xb9, 0xdc, 0x21, 0x0d, 0x5a, 0xcf, 0x78, 0x5e, 0xea,
+	0x56, 0x8d, 0xac, 0x5b, 0x59, 0xbf, 0x87, 0x96, 0x1e, 0x5a, 0xab, 0x9b, 0x9a, 0xf6, 0x65, 0x61,
+	0xe4, 0xbd, 0x95, 0xce, 0xcc, 0x55, 0x0e, 0x82, 0x74, 0x82, 0xa7, 0xd6, 0x43, 0xd8, 0xfa, 0x12,
+	0x93, 0xa5, 0x7e, 0xf0, 0x05, 0x6c, 0xa7, 0xdb, 0xee, 0xe0, 0x6a, 0x4f, 0x01, 0xd1, 0xa7, 0x0b,
+	0xa3, 0xa5, 0x0f, 0x97, 0xb4, 0x14, 0x19, 0xf3, 0x4b, 0x91, 0xf5, 0x1b, 0x68, 0xbd, 0x08, 0xfc,
+	0x4b, 0x6f, 0x2c, 0x9a, 0x43, 0x29, 0x5f, 0x3e, 0x6d, 0x18, 0x85, 0x69, 0x83, 0x77, 0x9b, 0xec,
+	0x22, 0xa2, 0x6e, 0x30, 0xca, 0x1b, 0x7a, 0x9b, 0x13, 0xd8, 0xcd, 0x21, 0x0b, 0xc1, 0x8e, 0xf2,
+	0xd5, 0xb4, 0xad, 0x67, 0xbe, 0x34, 0x4b, 0xa7, 0xdd, 0x4b, 0x1f, 0x5a, 0x43, 0xe2, 0x44, 0xc4,
+	0xc6, 0x7e, 0x72, 0x73, 0x8e, 0xa3, 0xf7, 0xea, 0x1b, 0xe9, 0x53, 0x48, 0x02, 0x0c, 0x89, 0x43,
+	0x92, 0x78, 0xd1, 0xbb, 0xa9, 0x07, 0xbb, 0x2f, 0x3d, 0xdf, 0x8b, 0xaf, 0x56, 0x39, 0xb3, 0x05,
+	0x95, 0xcb, 0x20, 0xba, 0xe0, 0xe9, 0xb5, 0x6a, 0xf3, 0x05, 0x0d, 0xec, 0xde, 0x79, 0xb0, 0x92,
+	0xd4, 0xd6, 0x57, 0x60, 0xf6, 0x2e, 0xae, 0xfd, 0xe0, 0xdd, 0x04, 0xbb, 0x63, 0x5c, 0xf0, 0xc5,
+	0x4c, 0x1e, 0x9b, 0x09, 0xb2, 0xd2, 0x4c, 0x90, 0x59, 0x9f, 0x41, 0xa7, 0x10, 0x75, 0x41, 0x86,
+	0xf9, 0xa3, 0x01, 0x5b, 0x72, 0x23, 0x76, 0x79, 0xae, 0x29, 0x3a, 0x3e, 0x9b, 0x7f, 0x4a, 0x7a,
+	0xfe, 0x99, 0x91, 0xac, 0x3c, 0x1b, 0xfe, 0x16, 0x34, 0x9c, 0x54, 0x32, 0x9e, 0x77, 0xaa, 0xb6,
+	0x46, 0xb3, 0xfe, 0x6b, 0xc0, 0xa6, 0x6e, 0xb0, 0x6c, 0x6a, 0x33, 0x66, 0x52, 0xdb, 0x9c, 0xea,
+	0x9d, 0x2f, 0xc7, 0xe5, 0xd9, 0x72, 0x4c, 0x5f, 0x14, 0xd4, 0xbf, 0x84, 0x20, 0x65, 0x5b, 0x2e,
+	0xd1, 0xa1, 0x0a, 0xa3, 0xca, 0x41, 0x39, 0x53, 0xa6, 0x73, 0x2a, 0x52, 0xbd, 0x5d, 0xfe, 0x5e,
+	0xeb, 0xac, 0x3b, 0xd3, 0x68, 0xd4, 0x5b, 0x48, 0x40, 0x9c, 0x09, 0x4b, 0x8b, 0x15, 0x9b, 0x2f,
+	0xac, 0x57, 0xf0, 0x40, 0x82, 0xe6, 0x1b, 0xd0, 0x27, 0x3c, 0x6b, 0x25, 0xb1, 0x88, 0x98, 0xdd,
+	0x9c, 0x10, 0xc2, 0x9b, 0xc5, 0x26, 0xeb, 0x9f, 0x06, 0x00, 0xab, 0xcc, 0x38, 0x0c, 0x22, 0x92,
+	0x4b, 0xe3, 0x46, 0x3e, 0x8d, 0x77, 0xa1, 0x76, 0xe5, 0xf8, 0x6e, 0x7c, 0xe5, 0x5c, 0x4b, 0xff,
+	0x4d, 0x09, 0xb4, 0x01, 0x53, 0x8b, 0x91, 0x23, 0x6c, 0x59, 0xb6, 0x1b, 0x8a, 0xd8, 0x1b, 0xb3,
+	0x5a, 0x14, 0xdd, 0x8e, 0xce, 0xa7, 0xb4, 0xa8, 0x0a, 0xfd, 0x45, 0xb7, 0xcf, 0xe9, 0x92, 0xb2,
+	0x88, 0x64, 0x55, 0x38, 0x8b, 0x08, 0x96, 0x09, 0x55, 0xd9, 0x33, 0x89, 0x87, 0xa4, 0x5a, 0x5b,
+	0x5f, 0xc3, 0x0e, 0x97, 0x5e, 0x0f, 0x54, 0x3d, 0xe7, 0x18, 0xb9, 0x9c, 0x83, 0x3e, 0xd6, 0x9f,
+	0x01, 0xaa, 0x57, 0x51, 0xba, 0x10, 0x8f, 0x00, 0x6b, 0x0f, 0x5a, 0x9c, 0x20, 0xe1, 0xb9, 0xa2,
+	0x69, 0xc4, 0x0a, 0xdd, 0xbf, 0xc2, 0xce, 0x84, 0x5c, 0x2d, 0x8a, 0xd8, 0xff, 0x94, 0xb8, 0x96,
+	0xf9, 0xce, 0x65, 0xa2, 0x2d, 0x08, 0x97, 0x8f, 0x60, 0x8b, 0x4a, 0x35, 0xca, 0x7c, 0xce, 0x5d,
+	0xb4, 0x49, 0xc9, 0xa7, 0x0a, 0xc2, 0x02, 0x46, 0x48, 0xc3, 0x4a, 0xf4, 0x95, 0x94, 0x28, 0xc3,
+	0xca, 0x84, 0x6a, 0x84, 0xf9, 0xa3, 0x87, 0xa9, 0xbb, 0x6a, 0xab, 0xb5, 0x6e, 0xe8, 0xf5, 0xa5,
+	0x86, 0xde, 0x58, 0x62, 0xe8, 0xea, 0x7c, 0x43, 0xd7, 0xe6, 0x1b, 0x1a, 0x74, 0x43, 0xd3, 0xe2,
+	0x29, 0x05, 0xa4, 0x35, 0xbc, 0xce, 0xbe, 0x04, 0x49, 0xea, 0x11, 0xeb, 0x16, 0x76, 0x73, 0x26,
+	0x11, 0x41, 0x31, 0x3f, 0x15, 0x2c, 0x70, 0x03, 0x81, 0xc1, 0xf9, 0x54, 0x23, 0x89, 0x7f, 0xc5,
+	0x48, 0x53, 0x31, 0x4a, 0x48, 0x09, 0x96, 0x07, 0xbb, 0x36, 0xeb, 0x25, 0x98, 0x09, 0x4e, 0xf0,
+	0x74, 0x51, 0x36, 0x2e, 0xe8, 0x58, 0x4a, 0x45, 0x1d, 0x4b, 0x0b, 0xe8, 0x34, 0x32, 0xb8, 0x64,
+	0xc7, 0x35, 0x6c, 0xbe, 0xb0, 0x7e, 0x02, 0x7b, 0xf9, 0xa3, 0xee, 0xd0, 0x00, 0x7c, 0xc1, 0x4a,
+	0x2d, 0x89, 0x82, 0xc9, 0x04, 0x47, 0xd9, 0x8f, 0x17, 0x47, 0x3e, 0x7d, 0x85, 0x57, 0xec, 0x20,
+	0x21, 0x8b, 0x74, 0xa9, 0xb5, 0x69, 0xa5, 0x5c, 0x9b, 0xb6, 0x07, 0xeb, 0x61, 0x84, 0x2f, 0xbd,
+	0x5b, 0xe1, 0xb0, 0x62, 0x45, 0x0d, 0xee, 0x84, 0x61, 0x14, 0xbc, 0x55, 0x89, 0x5d, 0xad, 0x73,
+	0x71, 0x52, 0xc9, 0xb7, 0x0d, 0x4f, 0xe0, 0x3e, 0x6d, 0x66, 0x98, 0x58, 0x2a, 0xec, 0xe7, 0x8a,
+	0x27, 0x7b, 0x1f, 0xb9, 0x3d, 0xed, 0x7d, 0x44, 0x8b, 0xaf, 0xf7, 0x3e, 0x6c, 0x9b, 0x6c, 0xf8,
+	0xad, 0xaf, 0xa1, 0xc1, 0x09, 0xcb, 0x4e, 0x79, 0x2f, 0x25, 0x58, 0x9f, 0x43, 0x53, 0xc0, 0xa7,
+	0xa6, 0x64, 0x27, 0xe7, 0x4c, 0xc9, 0x37, 0x71, 0xd6, 0xe3, 0x1f, 0x41, 0xb9, 0x3f, 0x18, 0xa2,
+	0x26, 0xd4, 0xfa, 0x83, 0xe1, 0xe8, 0xcd, 0x60, 0x78, 0xfc, 0xd5, 0xf6, 0x3d, 0xb4, 0x05, 0x75,
+	0xba, 0x3c, 0x1e, 0xf4, 0x9e, 0x9f, 0x1e, 0xf7, 0xb7, 0x0d, 0xb4, 0x0d, 0x0d, 0x4a, 0xe8, 0xbf,
+	0x1e, 0x72, 0x4a, 0xe9, 0xf1, 0x39, 0x34, 0xb5, 0xb9, 0x1d, 0x6a, 0x43, 0xeb, 0xcc, 0x3e, 0x1e,
+	0xbe, 0xea, 0xd9, 0xc7, 0xfd, 0xd1, 0xc9, 0xf1, 0x6f, 0x53, 0x34, 0x13, 0xf6, 0x72, 0x9c, 0x14,
+	0xb8, 0x03, 0x0f, 0x72, 0xbc, 0xf4, 0x8c, 0xa3, 0x7f, 0x6c, 0xc1, 0xf6, 0xaf, 0xbd, 0x08, 0x8f,
+	0x13, 0x27, 0x72, 0x87, 0x38, 0x7a, 0xeb, 0x5d, 0x60, 0x74, 0x0a, 0x4d, 0x6d, 0x36, 0x83, 0x16,
+	0x4d, 0x6c, 0xcc, 0x6e, 0x31, 0x53, 0xa4, 0xdf, 0x7b, 0xe8, 0x18, 0x1a, 0xd9, 0x31, 0x3c, 0xda,
+	0x3b, 0xe4, 0x43, 0xfb, 0x43, 0x39, 0xb4, 0x3f, 0x3c, 0xa6, 0x43, 0x7b, 0x53, 0x1e, 0x52, 0x34,
+	0xb3, 0xb7, 0xee, 0xa1, 0x17, 0x00, 0xe9, 0x80, 0x1d, 0xc9, 0x06, 0x73, 0x66, 0x38, 0x6f, 0xee,
+	0x17, 0x70, 0x14, 0xc8, 0x29, 0x34, 0xb5, 0xc9, 0xb9, 0xba, 0x59, 0xd1, 0xec, 0xdd, 0xec, 0x16,
+	0x33, 0xb3, 0x68, 0xda, 0x18, 0x50, 0xa1, 0x15, 0x0d, 0x1f, 0xcd, 0x6e, 0x31, 0x33, 0x8b, 0xa6,
+	0xcd, 0xaa, 0x14, 0x5a, 0xd1, 0x54, 0xcd, 0xec, 0x16, 0x33, 0x15, 0xda, 0x00, 0x9a, 0x5a, 0x7b,
+	0xad, 0xd0, 0x8a, 0x9a, 0x6e, 0xf3, 0x3b, 0xb9, 0xee, 0x63, 0x16, 0x6f, 0xc8, 0xfe, 0x11, 0x92,
+	0x6b, 0xdd, 0xba, 0xc5, 0x4d, 0xcb, 0xca, 0xa0, 0x67, 0xb0, 0xa9, 0x37, 0xe4, 0x0a, 0xb1, 0xb0,
+	0x4f, 0x5f, 0x01, 0x71, 0x00, 0x4d, 0xad, 0x3f, 0x57, 0xd7, 0x2e, 0xea, 0xda, 0x57, 0xc0, 0xfb,
+	0x86, 0xce, 0x67, 0x66, 0xba, 0x6d, 0xf4, 0xa1, 0x44, 0x9d, 0xdb, 0xdf, 0x9b, 0xd6, 0xa2, 0x2d,
+	0x0a, 0xff, 0x35, 0x34, 0xb2, 0x8f, 0x54, 0x64, 0xaa, 0xaf, 0x66, 0x86, 0x42, 0x66, 0xa7, 0x90,
+	0xa7, 0xa0, 0x7a, 0x00, 0xe9, 0x9b, 0x71, 0x6e, 0x94, 0xed, 0x67, 0xa2, 0x4c, 0x7f, 0x5e, 0x5a,
+	0xf7, 0xd0, 0x4f, 0xa1, 0x2a, 0x9f, 0xab, 0x68, 0x2f, 0x8d, 0x23, 0x4d, 0x8a, 0x07, 0x33, 0x74,
+	0xf5, 0xf9, 0x4b, 0xa8, 0x67, 0xfe, 0x81, 0x84, 0xf6, 0xb5, 0xf0, 0xd1, 0x40, 0xcc, 0x22, 0x56,
+	0x36, 0xd4, 0xd3, 0x11, 0x8d, 0x0a, 0xf5, 0x99, 0x51, 0x90, 0xb9, 0x5f, 0xc0, 0xc9, 0x6a, 0x36,
+	0x3b, 0x82, 0x51, 0x9a, 0x2d, 0x18, 0xe1, 0x98, 0x9d, 0x42, 0x9e, 0x82, 0xfa, 0x25, 0x6c, 0xea,
+	0xa5, 0x3c, 0x75, 0xfc, 0xa2, 0x66, 0xc2, 0xfc, 0x60, 0x0e, 0x57, 0x01, 0xfe, 0x82, 0x8d, 0x05,
+	0xb4, 0x02, 0x3f, 0xd7, 0x60, 0x2a, 0xbd, 0x16, 0xb5, 0x03, 0x5c, 0x59, 0x69, 0xb9, 0x54, 0xca,
+	0x9a, 0x29, 0xb8, 0xe6, 0x7e, 0x01, 0x47, 0x81, 0x3c, 0x83, 0x46, 0x8f, 0x57, 0x73, 0xc6, 0x42,
+	0x3b, 0x5a, 0x21, 0x13, 0x08, 0x2d, 0x9d, 0xa8, 0x3e, 0x7e, 0x0a, 0x75, 0x1b, 0xbf, 0x0d, 0xae,
+	0xdf, 0xe7, 0xdb, 0x9f, 0x43, 0xfd, 0x2c, 0x89, 0xc6, 0x78, 0x89, 0xd7, 0xce, 0xa1, 0x5b, 0xf7,
+	0xd0, 0xaf, 0x00, 0xbd, 0xc4, 0xe4, 0xe2, 0x4a, 0x1b, 0x4c, 0xa4, 0x05, 0xab, 0x60, 0x10, 0x62,
+	0x76, 0x8b, 0x99, 0xba, 0xe7, 0xa4, 0x2f, 0x89, 0x8c, 0xe7, 0xcc, 0xbc, 0x5e, 0xcc, 0x4e, 0x21,
+	0x2f, 0xe3, 0x39, 0xdb, 0x69, 0x1d, 0x12, 0xaf, 0x8a, 0x8e, 0x3e, 0x1b, 0xd1, 0x5e, 0x25, 0x66,
+	0xb7, 0x98, 0x29, 0x01, 0x9f, 0xd7, 0x7e, 0xb7, 0x71, 0xf8, 0x8c, 0x2b, 0x61, 0x9d, 0xfd, 0xf9,
+	0xfc, 0xff, 0x03, 0x00, 0x09, 0x78, 0xa5, 0x24, 0x16, 0x1f, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// WireguardServiceClient is the client API for WireguardService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type WireguardServiceClient interface {
+	CreateNetwork(ctx context.Context, in *CreateNetworkRequest, opts ...grpc.CallOption) (*CreateNetworkResponse, error)
+	ListNetworks(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListNetworksResponse, error)
+	GetNetwork(ctx context.Context, in *GetNetworkRequest, opts ...grpc.CallOption) (*GetNetworkResponse, error)
+	DeleteNetwork(ctx context.Context, in *DeleteNetworkRequest, opts ...grpc.CallOption) (*DeleteNetworkResponse, error)
+	UpdateNetwork(ctx context.Context, in *UpdateNetworkRequest, opts ...grpc.CallOption) (*UpdateNetworkResponse, error)
+	ImportNetwork(ctx context.Context, in *ImportNetworkRequest, opts ...grpc.CallOption) (*ImportNetworkResponse, error)
+	StartRenumber(ctx context.Context, in *StartRenumberRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error)
+	GetRenumberStatus(ctx context.Context, in *RenumberStatusRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error)
+	FinishRenumber(ctx context.Context, in *FinishRenumberRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error)
+	AbortRenumber(ctx context.Context, in *AbortRenumberRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error)
+	AcknowledgeRenumber(ctx context.Context, in *AcknowledgeRenumberRequest, opts ...grpc.CallOption) (*AcknowledgeRenumberResponse, error)
+	AcquireLease(ctx context.Context, in *AcquireLeaseRequest, opts ...grpc.CallOption) (*AcquireLeaseResponse, error)
+	ListLeases(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListLeasesResponse, error)
+	GetLease(ctx context.Context, in *GetLeaseRequest, opts ...grpc.CallOption) (*GetLeaseResponse, error)
+	DeleteLease(ctx context.Context, in *DeleteLeaseRequest, opts ...grpc.CallOption) (*DeleteLeaseResponse, error)
+	RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error)
+	ReleaseLease(ctx context.Context, in *ReleaseLeaseRequest, opts ...grpc.CallOption) (*ReleaseLeaseResponse, error)
+	RotateLeaseKey(ctx context.Context, in *RotateLeaseKeyRequest, opts ...grpc.CallOption) (*RotateLeaseKeyResponse, error)
+	GetControllerKey(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ControllerKeyResponse, error)
+	ListRoutes(ctx context.Context, in *ListRoutesRequest, opts ...grpc.CallOption) (*ListRoutesResponse, error)
+	ApproveRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*RouteResponse, error)
+	RevokeRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*RouteResponse, error)
+	PurgeLeases(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	FetchConfiguration(ctx context.Context, in *ConfigurationRequest, opts ...grpc.CallOption) (*ConfigurationResponse, error)
+	ReportStatus(ctx context.Context, in *ReportStatusRequest, opts ...grpc.CallOption) (*ReportStatusResponse, error)
+	GetNetworkHealth(ctx context.Context, in *NetworkHealthRequest, opts ...grpc.CallOption) (*NetworkHealthResponse, error)
+}
+
+type wireguardServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWireguardServiceClient(cc grpc.ClientConnInterface) WireguardServiceClient {
+	return &wireguardServiceClient{cc}
+}
+
+func (c *wireguardServiceClient) CreateNetwork(ctx context.Context, in *CreateNetworkRequest, opts ...grpc.CallOption) (*CreateNetworkResponse, error) {
+	out := new(CreateNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/CreateNetwork", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
 	return out, nil
@@ -1233,6 +3321,69 @@ func (c *wireguardServiceClient) DeleteNetwork(ctx context.Context, in *DeleteNe
 	return out, nil
 }
 
+func (c *wireguardServiceClient) UpdateNetwork(ctx context.Context, in *UpdateNetworkRequest, opts ...grpc.CallOption) (*UpdateNetworkResponse, error) {
+	out := new(UpdateNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/UpdateNetwork", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) ImportNetwork(ctx context.Context, in *ImportNetworkRequest, opts ...grpc.CallOption) (*ImportNetworkResponse, error) {
+	out := new(ImportNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/ImportNetwork", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) StartRenumber(ctx context.Context, in *StartRenumberRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error) {
+	out := new(RenumberNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/StartRenumber", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) GetRenumberStatus(ctx context.Context, in *RenumberStatusRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error) {
+	out := new(RenumberNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/GetRenumberStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) FinishRenumber(ctx context.Context, in *FinishRenumberRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error) {
+	out := new(RenumberNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/FinishRenumber", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) AbortRenumber(ctx context.Context, in *AbortRenumberRequest, opts ...grpc.CallOption) (*RenumberNetworkResponse, error) {
+	out := new(RenumberNetworkResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/AbortRenumber", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) AcknowledgeRenumber(ctx context.Context, in *AcknowledgeRenumberRequest, opts ...grpc.CallOption) (*AcknowledgeRenumberResponse, error) {
+	out := new(AcknowledgeRenumberResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/AcknowledgeRenumber", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *wireguardServiceClient) AcquireLease(ctx context.Context, in *AcquireLeaseRequest, opts ...grpc.CallOption) (*AcquireLeaseResponse, error) {
 	out := new(AcquireLeaseResponse)
 	err := c.cc.Invoke(ctx, "/proto.WireguardService/AcquireLease", in, out, opts...)
@@ -1269,9 +3420,63 @@ func (c *wireguardServiceClient) DeleteLease(ctx context.Context, in *DeleteLeas
 	return out, nil
 }
 
-func (c *wireguardServiceClient) RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error) {
-	out := new(RenewLeaseResponse)
-	err := c.cc.Invoke(ctx, "/proto.WireguardService/RenewLease", in, out, opts...)
+func (c *wireguardServiceClient) RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error) {
+	out := new(RenewLeaseResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/RenewLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) ReleaseLease(ctx context.Context, in *ReleaseLeaseRequest, opts ...grpc.CallOption) (*ReleaseLeaseResponse, error) {
+	out := new(ReleaseLeaseResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/ReleaseLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) RotateLeaseKey(ctx context.Context, in *RotateLeaseKeyRequest, opts ...grpc.CallOption) (*RotateLeaseKeyResponse, error) {
+	out := new(RotateLeaseKeyResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/RotateLeaseKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) GetControllerKey(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ControllerKeyResponse, error) {
+	out := new(ControllerKeyResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/GetControllerKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) ListRoutes(ctx context.Context, in *ListRoutesRequest, opts ...grpc.CallOption) (*ListRoutesResponse, error) {
+	out := new(ListRoutesResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/ListRoutes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) ApproveRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*RouteResponse, error) {
+	out := new(RouteResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/ApproveRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) RevokeRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*RouteResponse, error) {
+	out := new(RouteResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/RevokeRoute", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1296,19 +3501,52 @@ func (c *wireguardServiceClient) FetchConfiguration(ctx context.Context, in *Con
 	return out, nil
 }
 
+func (c *wireguardServiceClient) ReportStatus(ctx context.Context, in *ReportStatusRequest, opts ...grpc.CallOption) (*ReportStatusResponse, error) {
+	out := new(ReportStatusResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/ReportStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireguardServiceClient) GetNetworkHealth(ctx context.Context, in *NetworkHealthRequest, opts ...grpc.CallOption) (*NetworkHealthResponse, error) {
+	out := new(NetworkHealthResponse)
+	err := c.cc.Invoke(ctx, "/proto.WireguardService/GetNetworkHealth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WireguardServiceServer is the server API for WireguardService service.
 type WireguardServiceServer interface {
 	CreateNetwork(context.Context, *CreateNetworkRequest) (*CreateNetworkResponse, error)
 	ListNetworks(context.Context, *empty.Empty) (*ListNetworksResponse, error)
 	GetNetwork(context.Context, *GetNetworkRequest) (*GetNetworkResponse, error)
 	DeleteNetwork(context.Context, *DeleteNetworkRequest) (*DeleteNetworkResponse, error)
+	UpdateNetwork(context.Context, *UpdateNetworkRequest) (*UpdateNetworkResponse, error)
+	ImportNetwork(context.Context, *ImportNetworkRequest) (*ImportNetworkResponse, error)
+	StartRenumber(context.Context, *StartRenumberRequest) (*RenumberNetworkResponse, error)
+	GetRenumberStatus(context.Context, *RenumberStatusRequest) (*RenumberNetworkResponse, error)
+	FinishRenumber(context.Context, *FinishRenumberRequest) (*RenumberNetworkResponse, error)
+	AbortRenumber(context.Context, *AbortRenumberRequest) (*RenumberNetworkResponse, error)
+	AcknowledgeRenumber(context.Context, *AcknowledgeRenumberRequest) (*AcknowledgeRenumberResponse, error)
 	AcquireLease(context.Context, *AcquireLeaseRequest) (*AcquireLeaseResponse, error)
 	ListLeases(context.Context, *empty.Empty) (*ListLeasesResponse, error)
 	GetLease(context.Context, *GetLeaseRequest) (*GetLeaseResponse, error)
 	DeleteLease(context.Context, *DeleteLeaseRequest) (*DeleteLeaseResponse, error)
 	RenewLease(context.Context, *RenewLeaseRequest) (*RenewLeaseResponse, error)
+	ReleaseLease(context.Context, *ReleaseLeaseRequest) (*ReleaseLeaseResponse, error)
+	RotateLeaseKey(context.Context, *RotateLeaseKeyRequest) (*RotateLeaseKeyResponse, error)
+	GetControllerKey(context.Context, *empty.Empty) (*ControllerKeyResponse, error)
+	ListRoutes(context.Context, *ListRoutesRequest) (*ListRoutesResponse, error)
+	ApproveRoute(context.Context, *RouteRequest) (*RouteResponse, error)
+	RevokeRoute(context.Context, *RouteRequest) (*RouteResponse, error)
 	PurgeLeases(context.Context, *empty.Empty) (*empty.Empty, error)
 	FetchConfiguration(context.Context, *ConfigurationRequest) (*ConfigurationResponse, error)
+	ReportStatus(context.Context, *ReportStatusRequest) (*ReportStatusResponse, error)
+	GetNetworkHealth(context.Context, *NetworkHealthRequest) (*NetworkHealthResponse, error)
 }
 
 // UnimplementedWireguardServiceServer can be embedded to have forward compatible implementations.
@@ -1327,6 +3565,27 @@ func (*UnimplementedWireguardServiceServer) GetNetwork(ctx context.Context, req
 func (*UnimplementedWireguardServiceServer) DeleteNetwork(ctx context.Context, req *DeleteNetworkRequest) (*DeleteNetworkResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteNetwork not implemented")
 }
+func (*UnimplementedWireguardServiceServer) UpdateNetwork(ctx context.Context, req *UpdateNetworkRequest) (*UpdateNetworkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateNetwork not implemented")
+}
+func (*UnimplementedWireguardServiceServer) ImportNetwork(ctx context.Context, req *ImportNetworkRequest) (*ImportNetworkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportNetwork not implemented")
+}
+func (*UnimplementedWireguardServiceServer) StartRenumber(ctx context.Context, req *StartRenumberRequest) (*RenumberNetworkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartRenumber not implemented")
+}
+func (*UnimplementedWireguardServiceServer) GetRenumberStatus(ctx context.Context, req *RenumberStatusRequest) (*RenumberNetworkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRenumberStatus not implemented")
+}
+func (*UnimplementedWireguardServiceServer) FinishRenumber(ctx context.Context, req *FinishRenumberRequest) (*RenumberNetworkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishRenumber not implemented")
+}
+func (*UnimplementedWireguardServiceServer) AbortRenumber(ctx context.Context, req *AbortRenumberRequest) (*RenumberNetworkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AbortRenumber not implemented")
+}
+func (*UnimplementedWireguardServiceServer) AcknowledgeRenumber(ctx context.Context, req *AcknowledgeRenumberRequest) (*AcknowledgeRenumberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcknowledgeRenumber not implemented")
+}
 func (*UnimplementedWireguardServiceServer) AcquireLease(ctx context.Context, req *AcquireLeaseRequest) (*AcquireLeaseResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AcquireLease not implemented")
 }
@@ -1342,12 +3601,36 @@ func (*UnimplementedWireguardServiceServer) DeleteLease(ctx context.Context, req
 func (*UnimplementedWireguardServiceServer) RenewLease(ctx context.Context, req *RenewLeaseRequest) (*RenewLeaseResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RenewLease not implemented")
 }
+func (*UnimplementedWireguardServiceServer) ReleaseLease(ctx context.Context, req *ReleaseLeaseRequest) (*ReleaseLeaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseLease not implemented")
+}
+func (*UnimplementedWireguardServiceServer) RotateLeaseKey(ctx context.Context, req *RotateLeaseKeyRequest) (*RotateLeaseKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateLeaseKey not implemented")
+}
+func (*UnimplementedWireguardServiceServer) GetControllerKey(ctx context.Context, req *empty.Empty) (*ControllerKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetControllerKey not implemented")
+}
+func (*UnimplementedWireguardServiceServer) ListRoutes(ctx context.Context, req *ListRoutesRequest) (*ListRoutesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoutes not implemented")
+}
+func (*UnimplementedWireguardServiceServer) ApproveRoute(ctx context.Context, req *RouteRequest) (*RouteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveRoute not implemented")
+}
+func (*UnimplementedWireguardServiceServer) RevokeRoute(ctx context.Context, req *RouteRequest) (*RouteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeRoute not implemented")
+}
 func (*UnimplementedWireguardServiceServer) PurgeLeases(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PurgeLeases not implemented")
 }
 func (*UnimplementedWireguardServiceServer) FetchConfiguration(ctx context.Context, req *ConfigurationRequest) (*ConfigurationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FetchConfiguration not implemented")
 }
+func (*UnimplementedWireguardServiceServer) ReportStatus(ctx context.Context, req *ReportStatusRequest) (*ReportStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportStatus not implemented")
+}
+func (*UnimplementedWireguardServiceServer) GetNetworkHealth(ctx context.Context, req *NetworkHealthRequest) (*NetworkHealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNetworkHealth not implemented")
+}
 
 func RegisterWireguardServiceServer(s *grpc.Server, srv WireguardServiceServer) {
 	s.RegisterService(&_WireguardService_serviceDesc, srv)
@@ -1425,6 +3708,132 @@ func _WireguardService_DeleteNetwork_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireguardService_UpdateNetwork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateNetworkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).UpdateNetwork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/UpdateNetwork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).UpdateNetwork(ctx, req.(*UpdateNetworkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_ImportNetwork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportNetworkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).ImportNetwork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/ImportNetwork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).ImportNetwork(ctx, req.(*ImportNetworkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_StartRenumber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRenumberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).StartRenumber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/StartRenumber",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).StartRenumber(ctx, req.(*StartRenumberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_GetRenumberStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenumberStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).GetRenumberStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/GetRenumberStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).GetRenumberStatus(ctx, req.(*RenumberStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_FinishRenumber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishRenumberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).FinishRenumber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/FinishRenumber",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).FinishRenumber(ctx, req.(*FinishRenumberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_AbortRenumber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AbortRenumberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).AbortRenumber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/AbortRenumber",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).AbortRenumber(ctx, req.(*AbortRenumberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_AcknowledgeRenumber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcknowledgeRenumberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).AcknowledgeRenumber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/AcknowledgeRenumber",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).AcknowledgeRenumber(ctx, req.(*AcknowledgeRenumberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _WireguardService_AcquireLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AcquireLeaseRequest)
 	if err := dec(in); err != nil {
@@ -1515,6 +3924,114 @@ func _WireguardService_RenewLease_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireguardService_ReleaseLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).ReleaseLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/ReleaseLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).ReleaseLease(ctx, req.(*ReleaseLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_RotateLeaseKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateLeaseKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).RotateLeaseKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/RotateLeaseKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).RotateLeaseKey(ctx, req.(*RotateLeaseKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_GetControllerKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).GetControllerKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/GetControllerKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).GetControllerKey(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_ListRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).ListRoutes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/ListRoutes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).ListRoutes(ctx, req.(*ListRoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_ApproveRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).ApproveRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/ApproveRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).ApproveRoute(ctx, req.(*RouteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_RevokeRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).RevokeRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/RevokeRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).RevokeRoute(ctx, req.(*RouteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _WireguardService_PurgeLeases_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(empty.Empty)
 	if err := dec(in); err != nil {
@@ -1551,6 +4068,42 @@ func _WireguardService_FetchConfiguration_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireguardService_ReportStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).ReportStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/ReportStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).ReportStatus(ctx, req.(*ReportStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireguardService_GetNetworkHealth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NetworkHealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireguardServiceServer).GetNetworkHealth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.WireguardService/GetNetworkHealth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireguardServiceServer).GetNetworkHealth(ctx, req.(*NetworkHealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _WireguardService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.WireguardService",
 	HandlerType: (*WireguardServiceServer)(nil),
@@ -1571,6 +4124,34 @@ var _WireguardService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteNetwork",
 			Handler:    _WireguardService_DeleteNetwork_Handler,
 		},
+		{
+			MethodName: "UpdateNetwork",
+			Handler:    _WireguardService_UpdateNetwork_Handler,
+		},
+		{
+			MethodName: "ImportNetwork",
+			Handler:    _WireguardService_ImportNetwork_Handler,
+		},
+		{
+			MethodName: "StartRenumber",
+			Handler:    _WireguardService_StartRenumber_Handler,
+		},
+		{
+			MethodName: "GetRenumberStatus",
+			Handler:    _WireguardService_GetRenumberStatus_Handler,
+		},
+		{
+			MethodName: "FinishRenumber",
+			Handler:    _WireguardService_FinishRenumber_Handler,
+		},
+		{
+			MethodName: "AbortRenumber",
+			Handler:    _WireguardService_AbortRenumber_Handler,
+		},
+		{
+			MethodName: "AcknowledgeRenumber",
+			Handler:    _WireguardService_AcknowledgeRenumber_Handler,
+		},
 		{
 			MethodName: "AcquireLease",
 			Handler:    _WireguardService_AcquireLease_Handler,
@@ -1591,6 +4172,30 @@ var _WireguardService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RenewLease",
 			Handler:    _WireguardService_RenewLease_Handler,
 		},
+		{
+			MethodName: "ReleaseLease",
+			Handler:    _WireguardService_ReleaseLease_Handler,
+		},
+		{
+			MethodName: "RotateLeaseKey",
+			Handler:    _WireguardService_RotateLeaseKey_Handler,
+		},
+		{
+			MethodName: "GetControllerKey",
+			Handler:    _WireguardService_GetControllerKey_Handler,
+		},
+		{
+			MethodName: "ListRoutes",
+			Handler:    _WireguardService_ListRoutes_Handler,
+		},
+		{
+			MethodName: "ApproveRoute",
+			Handler:    _WireguardService_ApproveRoute_Handler,
+		},
+		{
+			MethodName: "RevokeRoute",
+			Handler:    _WireguardService_RevokeRoute_Handler,
+		},
 		{
 			MethodName: "PurgeLeases",
 			Handler:    _WireguardService_PurgeLeases_Handler,
@@ -1599,6 +4204,14 @@ var _WireguardService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FetchConfiguration",
 			Handler:    _WireguardService_FetchConfiguration_Handler,
 		},
+		{
+			MethodName: "ReportStatus",
+			Handler:    _WireguardService_ReportStatus_Handler,
+		},
+		{
+			MethodName: "GetNetworkHealth",
+			Handler:    _WireguardService_GetNetworkHealth_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
@@ -0,0 +1,31 @@
+package proto
+
+import "fmt"
+
+// MarshalText writes the name of the setting, so that the CLI output
+// and the state of the agents are readable
+func (x PresharedKeys) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+func (x *PresharedKeys) UnmarshalText(b []byte) error {
+	v, ok := PresharedKeys_value[string(b)]
+	if !ok {
+		return fmt.Errorf("unknown preshared keys setting %s", string(b))
+	}
+	*x = PresharedKeys(v)
+	return nil
+}
+
+func (x DNS) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+func (x *DNS) UnmarshalText(b []byte) error {
+	v, ok := DNS_value[string(b)]
+	if !ok {
+		return fmt.Errorf("unknown DNS setting %s", string(b))
+	}
+	*x = DNS(v)
+	return nil
+}
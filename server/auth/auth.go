@@ -5,6 +5,8 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -20,6 +22,13 @@ func extractTokenFromMetadata(md metadata.MD) (string, bool) {
 	return r[0], true
 }
 
+// hashToken returns the hash of the token, the way -hashed-token is given
+func hashToken(token string) string {
+	h := sha512.New()
+	io.WriteString(h, token)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
 func NewAuthFunction(accessTokenHash string) func(context.Context) (context.Context, error) {
 	return func(ctx context.Context) (context.Context, error) {
 		if accessTokenHash == "" {
@@ -35,14 +44,26 @@ func NewAuthFunction(accessTokenHash string) func(context.Context) (context.Cont
 			return nil, grpc.Errorf(codes.Unauthenticated, "unauthenticated")
 		}
 
-		h := sha512.New()
-		io.WriteString(h, token)
-		hashedKey := fmt.Sprintf("%x", h.Sum(nil))
-
-		if hashedKey == accessTokenHash {
+		if hashToken(token) == accessTokenHash {
 			return ctx, nil
 		}
 
 		return nil, grpc.Errorf(codes.Unauthenticated, "invalid auth token")
 	}
 }
+
+// NewHTTPHandler only lets the requests that carry the auth token through to
+// the handler, as an "Authorization: Bearer <token>" header the way
+// Prometheus sends its credentials
+func NewHTTPHandler(accessTokenHash string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accessTokenHash != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || hashToken(token) != accessTokenHash {
+				http.Error(w, "unauthenticated", http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHTTPHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		hash   string
+		header string
+		status int
+	}{
+		{name: "no token configured", hash: "", header: "", status: http.StatusOK},
+		{name: "valid token", hash: hashToken("secret"), header: "Bearer secret", status: http.StatusOK},
+		{name: "missing token", hash: hashToken("secret"), header: "", status: http.StatusUnauthorized},
+		{name: "wrong token", hash: hashToken("secret"), header: "Bearer other", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sd", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			NewHTTPHandler(tt.hash, ok).ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
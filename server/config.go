@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	proto "github.com/thomas-maurice/wgnw/proto"
+)
+
+// networkConfig is a network declared in the configuration file
+type networkConfig struct {
+	Name          string `yaml:"name"`
+	Address       string `yaml:"address"`
+	Subnets       int32  `yaml:"subnets"`
+	MTU           int32  `yaml:"mtu"`
+	Keepalive     int32  `yaml:"keepalive"`
+	ListenPort    int32  `yaml:"listen_port"`
+	LeaseDuration int64  `yaml:"lease_duration"`
+	PresharedKeys *bool  `yaml:"preshared_keys"`
+	DNSDomain     string `yaml:"dns_domain"`
+	DNS           *bool  `yaml:"dns"`
+}
+
+// fileConfig holds the settings of the configuration file that are not flags
+type fileConfig struct {
+	Networks []networkConfig `yaml:"networks"`
+}
+
+func (n networkConfig) settings() *proto.NetworkSettings {
+	settings := &proto.NetworkSettings{
+		Mtu:                 n.MTU,
+		PersistentKeepalive: n.Keepalive,
+		ListenPort:          n.ListenPort,
+		LeaseDuration:       n.LeaseDuration,
+		DnsDomain:           n.DNSDomain,
+	}
+	if n.PresharedKeys != nil && *n.PresharedKeys {
+		settings.PresharedKeys = proto.PresharedKeys_PRESHARED_KEYS_ENABLED
+	} else if n.PresharedKeys != nil {
+		settings.PresharedKeys = proto.PresharedKeys_PRESHARED_KEYS_DISABLED
+	}
+	if n.DNS != nil && *n.DNS {
+		settings.Dns = proto.DNS_DNS_ENABLED
+	} else if n.DNS != nil {
+		settings.Dns = proto.DNS_DNS_DISABLED
+	}
+	return settings
+}
+
+// ensureNetworks creates the networks of the configuration file that do not
+// exist yet and updates the settings of the others. The address of an existing
+// network is never changed, that is what renumbering is for.
+func ensureNetworks(s *WireguardServer, networks []networkConfig) error {
+	for _, n := range networks {
+		logger := logrus.WithField("network", n.Name)
+		existing, err := s.wgService.GetNetwork(n.Name)
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if existing == nil {
+			_, err = s.CreateNetwork(context.Background(), &proto.CreateNetworkRequest{
+				Name:     n.Name,
+				Address:  n.Address,
+				Subnets:  n.Subnets,
+				Settings: n.settings(),
+			})
+			if err != nil {
+				return err
+			}
+			logger.Info("Created network from the configuration")
+			continue
+		}
+
+		address, _, err := splitNetwork(n.Address, n.Subnets)
+		if err != nil {
+			return err
+		}
+		if address.String() != existing.Address || n.Subnets != existing.NumSubnets {
+			logger.Warningf("Network is %s with %d subnets, the configuration wants %s with %d, renumber it to change its address",
+				existing.Address, existing.NumSubnets, address.String(), n.Subnets)
+		}
+
+		_, err = s.wgService.UpdateNetwork(n.Name, n.settings())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMasterKey reads the key the preshared keys are encrypted with,
+// there is none if no file is given
+func readMasterKey(filename string) ([]byte, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the master key should be 32 bytes long, got %d", len(key))
+	}
+	return key, nil
+}
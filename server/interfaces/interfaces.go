@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"github.com/thomas-maurice/wgnw/common"
 	proto "github.com/thomas-maurice/wgnw/proto"
 )
 
@@ -9,13 +10,33 @@ type WireguardService interface {
 	ListNetworks() ([]*proto.Network, error)
 	GetNetwork(string) (*proto.Network, error)
 	DeleteNetwork(string) error
+	UpdateNetwork(string, *proto.NetworkSettings) (*proto.Network, error)
+	ImportNetwork(*proto.Network, []*proto.ImportedPeer, bool) (*proto.ImportNetworkResponse, error)
+
+	StartRenumber(string, string, []string) (*proto.RenumberStatus, error)
+	GetRenumberStatus(string) (*proto.RenumberStatus, error)
+	FinishRenumber(string, bool) (*proto.RenumberStatus, error)
+	AbortRenumber(string) (*proto.RenumberStatus, error)
+	AcknowledgeRenumber(string, string) error
 
 	AcquireLease(*proto.AcquireLeaseRequest) (*proto.Lease, error)
 	ListLeases() ([]*proto.Lease, error)
 	GetLease(string) (*proto.Lease, error)
 	DeleteLease(string) error
-	RenewLease(string) (*proto.Lease, error)
+	RenewLease(string, []string, []string) (*proto.Lease, error)
+	ReleaseLease(string) error
 	PurgeLeases() error
+	RotateLeaseKey(string, string, []byte) (*proto.Lease, error)
+	GetControllerKey() (string, error)
+
+	ListRoutes(string) ([]*proto.Route, error)
+	ApproveRoute(string, string, string) (*proto.Route, error)
+	RevokeRoute(string, string, string) (*proto.Route, error)
+
+	FetchConfiguration(string, string) (*proto.ConfigurationResponse, error)
+
+	ReportStatus(string, []*proto.PeerReport) error
+	GetNetworkHealth(string) (*proto.NetworkHealthResponse, error)
 
-	FetchConfiguration(string) (*proto.ConfigurationResponse, error)
+	DNSZones() ([]common.DNSZone, error)
 }
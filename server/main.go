@@ -27,36 +27,50 @@ import (
 var (
 	sqlDriver          string
 	sqlConnString      string
+	masterKeyFile      string
 	listenAddress      string
 	promListenAddress  string
+	dnsListenAddress   string
 	hashedAccessToken  string
 	debug              bool
 	leaseDuration      int64
+	keyRotationOverlap time.Duration
 	useTLS             bool
 	insecureSkipVerify bool
 	caCert             string
 	certFile           string
 	keyFile            string
+	configFile         string
 )
 
 func init() {
 	flag.BoolVar(&debug, "debug", false, "Enable debug mode")
 	flag.StringVar(&sqlDriver, "sql-driver", "sqlite3", "SQL driver name, can be 'sqlite3' 'mysql' or 'postgres'")
 	flag.StringVar(&listenAddress, "listen", "0.0.0.0:10000", "Address to listen on")
-	flag.StringVar(&promListenAddress, "listen-prometheus", "0.0.0.0:10001", "Address to listen on for prometheus")
+	flag.StringVar(&promListenAddress, "listen-prometheus", "0.0.0.0:10001", "Address to serve the prometheus metrics and service discovery on")
+	flag.StringVar(&dnsListenAddress, "listen-dns", "", "Address to answer the names of the nodes of the networks with DNS enabled on, disabled if empty")
 	flag.StringVar(&sqlConnString, "sql-string", "db.sqlite3", "SQL driver connstring")
 	flag.StringVar(&hashedAccessToken, "hashed-token", "", "Auth token used to identify")
-	flag.Int64Var(&leaseDuration, "lease-duration", 3600, "Lease duration")
+	flag.Int64Var(&leaseDuration, "lease-duration", 3600, "Lease duration for the networks that do not set one")
+	flag.DurationVar(&keyRotationOverlap, "key-rotation-overlap", time.Minute, "How long the peers keep using the old key of a lease once every peer fetched its next key")
 	flag.BoolVar(&useTLS, "tls", false, "Use TLS or not")
 	flag.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Skip CA verification")
 	flag.StringVar(&caCert, "ca", "", "CA cert file")
 	flag.StringVar(&certFile, "cert", "", "Cert file to use")
 	flag.StringVar(&keyFile, "key", "", "Key file to use")
+	flag.StringVar(&masterKeyFile, "master-key-file", "", "File holding the base64 encoded 32 bytes key the preshared keys are encrypted with")
+	flag.StringVar(&configFile, "config", "", "YAML configuration file, its keys are the names of the flags")
 }
 
 func main() {
 	flag.Parse()
 
+	var fc fileConfig
+	err := common.NewConfig(flag.CommandLine, configFile, "WGNW_SERVER_").Load(&fc)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not load the configuration")
+	}
+
 	if hashedAccessToken == "" {
 		logrus.Warning("Running without an auth token, anyone can access the API")
 	}
@@ -64,6 +78,7 @@ func main() {
 	entry := logrus.NewEntry(logrus.New())
 	grpc_logrus.ReplaceGrpcLogger(entry)
 	s := grpc.NewServer(
+		grpc.KeepaliveEnforcementPolicy(common.ServerKeepalive),
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 			grpc_ctxtags.StreamServerInterceptor(),
 			grpc_logrus.StreamServerInterceptor(entry,
@@ -86,7 +101,12 @@ func main() {
 
 	grpc_prometheus.EnableHandlingTimeHistogram()
 
-	wgService, err := sql.NewSQLWireguardService(sqlDriver, sqlConnString, debug, time.Duration(leaseDuration)*time.Second)
+	masterKey, err := readMasterKey(masterKeyFile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not read the master key")
+	}
+
+	wgService, err := sql.NewSQLWireguardService(sqlDriver, sqlConnString, debug, time.Duration(leaseDuration)*time.Second, keyRotationOverlap, masterKey)
 	if err != nil {
 		logrus.WithError(err).Fatal("Could not create wireguard service")
 	}
@@ -95,6 +115,11 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Fatal("Could not create wireguard server")
 	}
+	err = ensureNetworks(wgServer, fc.Networks)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not create the networks of the configuration")
+	}
+
 	proto.RegisterWireguardServiceServer(s, wgServer)
 	grpc_prometheus.Register(s)
 
@@ -120,7 +145,19 @@ func main() {
 		}
 	}
 
+	if dnsListenAddress != "" {
+		pc, l, err := common.ListenDNS(dnsListenAddress)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not listen for DNS queries")
+		}
+		common.ServeDNS(pc, l, &common.DNSHandler{Zones: wgService.DNSZones})
+		logrus.Infof("Answering DNS queries on %s", dnsListenAddress)
+	}
+
 	http.Handle("/metrics", promhttp.Handler())
+	// The service discovery lists the node names, addresses and public keys
+	// of the leases, it needs the token like the API
+	http.Handle("/sd", auth.NewHTTPHandler(hashedAccessToken, serviceDiscovery(wgService)))
 	go func() {
 		logrus.Fatal(http.ListenAndServe(promListenAddress, nil))
 	}()
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/thomas-maurice/wgnw/server/interfaces"
+)
+
+// sdDefaultPort is the port of the targets when the query does not set
+// one, the one of the node exporter
+const sdDefaultPort = 9100
+
+// sdTargetGroup is a target group of the Prometheus HTTP service discovery
+type sdTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// serviceDiscovery lists the mesh address of every active lease in the
+// format of the Prometheus HTTP service discovery. The network query
+// parameter restricts the list to a network and port sets the port of
+// the targets.
+func serviceDiscovery(wgService interfaces.WireguardService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		port := sdDefaultPort
+		if p := r.URL.Query().Get("port"); p != "" {
+			var err error
+			port, err = strconv.Atoi(p)
+			if err != nil || port <= 0 || port > 65535 {
+				http.Error(w, "invalid port "+p, http.StatusBadRequest)
+				return
+			}
+		}
+		network := r.URL.Query().Get("network")
+
+		leases, err := wgService.ListLeases()
+		if err != nil {
+			logrus.WithError(err).Error("Could not list the leases for service discovery")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sort.Slice(leases, func(i, j int) bool {
+			if leases[i].Network != leases[j].Network {
+				return leases[i].Network < leases[j].Network
+			}
+			return leases[i].NodeName < leases[j].NodeName
+		})
+
+		groups := []sdTargetGroup{}
+		now := time.Now().Unix()
+		for _, lease := range leases {
+			if lease.Expires < now || (network != "" && lease.Network != network) {
+				continue
+			}
+			ip, _, err := net.ParseCIDR(lease.IpRange)
+			if err != nil {
+				continue
+			}
+
+			labels := map[string]string{
+				"__meta_wgnw_network":    lease.Network,
+				"__meta_wgnw_node_name":  lease.NodeName,
+				"__meta_wgnw_lease_uuid": lease.Uuid,
+				"__meta_wgnw_public_key": lease.PublicKey,
+				"__meta_wgnw_address":    ip.String(),
+			}
+			// Surrounded by commas like the Consul tags, so that relabeling
+			// can match ",<tag>,"
+			if len(lease.Tags) != 0 {
+				labels["__meta_wgnw_tags"] = "," + strings.Join(lease.Tags, ",") + ","
+			}
+			groups = append(groups, sdTargetGroup{
+				Targets: []string{net.JoinHostPort(ip.String(), strconv.Itoa(port))},
+				Labels:  labels,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(groups)
+		if err != nil {
+			logrus.WithError(err).Warning("Could not write the service discovery response")
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/apparentlymart/go-cidr/cidr"
 	"github.com/golang/protobuf/ptypes/empty"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
 	proto "github.com/thomas-maurice/wgnw/proto"
 	"github.com/thomas-maurice/wgnw/server/interfaces"
@@ -23,10 +24,28 @@ func NewWireguardServer(wgService interfaces.WireguardService) (*WireguardServer
 }
 
 func (s *WireguardServer) AcquireLease(ctx context.Context, leaseRequest *proto.AcquireLeaseRequest) (*proto.AcquireLeaseResponse, error) {
+	// The generated private key is only ever sent back to the caller
+	var privateKey string
+	if leaseRequest.GenerateKey {
+		if leaseRequest.PublicKey != "" {
+			return nil, fmt.Errorf("a lease cannot have both a public key and a generated key")
+		}
+		key, err := wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		leaseRequest.PublicKey = key.PublicKey().String()
+		privateKey = key.String()
+	}
+
 	lease, err := s.wgService.AcquireLease(leaseRequest)
+	if err != nil {
+		return &proto.AcquireLeaseResponse{}, err
+	}
 	return &proto.AcquireLeaseResponse{
-		Lease: lease,
-	}, err
+		Lease:      lease,
+		PrivateKey: privateKey,
+	}, nil
 }
 
 func (s *WireguardServer) GetLease(ctx context.Context, l *proto.GetLeaseRequest) (*proto.GetLeaseResponse, error) {
@@ -51,34 +70,98 @@ func (s *WireguardServer) DeleteLease(ctx context.Context, l *proto.DeleteLeaseR
 }
 
 func (s *WireguardServer) RenewLease(ctx context.Context, l *proto.RenewLeaseRequest) (*proto.RenewLeaseResponse, error) {
-	lease, err := s.wgService.RenewLease(l.Uuid)
+	lease, err := s.wgService.RenewLease(l.Uuid, l.Routes, l.Tags)
 	return &proto.RenewLeaseResponse{
 		Lease: lease,
 	}, err
 }
 
+func (s *WireguardServer) ReleaseLease(ctx context.Context, l *proto.ReleaseLeaseRequest) (*proto.ReleaseLeaseResponse, error) {
+	err := s.wgService.ReleaseLease(l.Uuid)
+	return &proto.ReleaseLeaseResponse{
+		Uuid: l.Uuid,
+	}, err
+}
+
+func (s *WireguardServer) RotateLeaseKey(ctx context.Context, r *proto.RotateLeaseKeyRequest) (*proto.RotateLeaseKeyResponse, error) {
+	lease, err := s.wgService.RotateLeaseKey(r.Uuid, r.NextPublicKey, r.Proof)
+	return &proto.RotateLeaseKeyResponse{
+		Lease: lease,
+	}, err
+}
+
+func (s *WireguardServer) GetControllerKey(ctx context.Context, nothing *empty.Empty) (*proto.ControllerKeyResponse, error) {
+	key, err := s.wgService.GetControllerKey()
+	return &proto.ControllerKeyResponse{
+		PublicKey: key,
+	}, err
+}
+
+func (s *WireguardServer) ListRoutes(ctx context.Context, r *proto.ListRoutesRequest) (*proto.ListRoutesResponse, error) {
+	routes, err := s.wgService.ListRoutes(r.Network)
+	return &proto.ListRoutesResponse{
+		Routes: routes,
+	}, err
+}
+
+func (s *WireguardServer) ApproveRoute(ctx context.Context, r *proto.RouteRequest) (*proto.RouteResponse, error) {
+	route, err := s.wgService.ApproveRoute(r.Network, r.NodeName, r.Prefix)
+	return &proto.RouteResponse{
+		Route: route,
+	}, err
+}
+
+func (s *WireguardServer) RevokeRoute(ctx context.Context, r *proto.RouteRequest) (*proto.RouteResponse, error) {
+	route, err := s.wgService.RevokeRoute(r.Network, r.NodeName, r.Prefix)
+	return &proto.RouteResponse{
+		Route: route,
+	}, err
+}
+
 func (s *WireguardServer) FetchConfiguration(ctx context.Context, cfg *proto.ConfigurationRequest) (*proto.ConfigurationResponse, error) {
-	c, err := s.wgService.FetchConfiguration(cfg.NetworkName)
+	c, err := s.wgService.FetchConfiguration(cfg.NetworkName, cfg.LeaseUuid)
 	return c, err
 }
 
-func (s *WireguardServer) CreateNetwork(ctx context.Context, spec *proto.CreateNetworkRequest) (*proto.CreateNetworkResponse, error) {
-	_, network, err := net.ParseCIDR(spec.Address)
-	extraBits := len(fmt.Sprintf("%b", spec.Subnets-1))
+func (s *WireguardServer) ReportStatus(ctx context.Context, report *proto.ReportStatusRequest) (*proto.ReportStatusResponse, error) {
+	return &proto.ReportStatusResponse{}, s.wgService.ReportStatus(report.LeaseUuid, report.Peers)
+}
+
+func (s *WireguardServer) GetNetworkHealth(ctx context.Context, spec *proto.NetworkHealthRequest) (*proto.NetworkHealthResponse, error) {
+	health, err := s.wgService.GetNetworkHealth(spec.Name)
+	if err != nil {
+		return &proto.NetworkHealthResponse{}, err
+	}
+	return health, nil
+}
+
+// splitNetwork splits the address range into numSubnets subnets
+func splitNetwork(address string, numSubnets int32) (*net.IPNet, []string, error) {
+	_, network, err := net.ParseCIDR(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	extraBits := len(fmt.Sprintf("%b", numSubnets-1))
 
 	// If we want only one network then whatever mate
-	if spec.Subnets == 1 {
+	if numSubnets == 1 {
 		extraBits = 0
 	}
 
 	var subnets []string
-	for i := int32(0); i < spec.Subnets; i++ {
+	for i := int32(0); i < numSubnets; i++ {
 		subnet, err := cidr.Subnet(network, extraBits, int(i))
 		if err != nil {
-			return &proto.CreateNetworkResponse{}, err
+			return nil, nil, err
 		}
 		subnets = append(subnets, subnet.String())
 	}
+
+	return network, subnets, nil
+}
+
+func (s *WireguardServer) CreateNetwork(ctx context.Context, spec *proto.CreateNetworkRequest) (*proto.CreateNetworkResponse, error) {
+	network, subnets, err := splitNetwork(spec.Address, spec.Subnets)
 	if err != nil {
 		return &proto.CreateNetworkResponse{}, err
 	}
@@ -88,6 +171,7 @@ func (s *WireguardServer) CreateNetwork(ctx context.Context, spec *proto.CreateN
 		Address:    network.String(),
 		Subnets:    subnets,
 		NumSubnets: spec.Subnets,
+		Settings:   spec.Settings,
 	})
 
 	if err != nil {
@@ -100,6 +184,7 @@ func (s *WireguardServer) CreateNetwork(ctx context.Context, spec *proto.CreateN
 			Address:    network.String(),
 			Subnets:    subnets,
 			NumSubnets: spec.Subnets,
+			Settings:   spec.Settings,
 		},
 	}, nil
 }
@@ -137,6 +222,81 @@ func (s *WireguardServer) DeleteNetwork(ctx context.Context, spec *proto.DeleteN
 	}, nil
 }
 
+func (s *WireguardServer) UpdateNetwork(ctx context.Context, spec *proto.UpdateNetworkRequest) (*proto.UpdateNetworkResponse, error) {
+	network, err := s.wgService.UpdateNetwork(spec.Name, spec.Settings)
+	if err != nil {
+		return &proto.UpdateNetworkResponse{}, err
+	}
+
+	return &proto.UpdateNetworkResponse{
+		Network: network,
+	}, nil
+}
+
+func (s *WireguardServer) ImportNetwork(ctx context.Context, spec *proto.ImportNetworkRequest) (*proto.ImportNetworkResponse, error) {
+	if spec.Network == nil {
+		return &proto.ImportNetworkResponse{}, fmt.Errorf("the network to create is missing")
+	}
+
+	network, subnets, err := splitNetwork(spec.Network.Address, spec.Network.Subnets)
+	if err != nil {
+		return &proto.ImportNetworkResponse{}, err
+	}
+
+	return s.wgService.ImportNetwork(&proto.Network{
+		Name:       spec.Network.Name,
+		Address:    network.String(),
+		Subnets:    subnets,
+		NumSubnets: spec.Network.Subnets,
+		Settings:   spec.Network.Settings,
+	}, spec.Peers, spec.DryRun)
+}
+
+func (s *WireguardServer) StartRenumber(ctx context.Context, spec *proto.StartRenumberRequest) (*proto.RenumberNetworkResponse, error) {
+	network, err := s.wgService.GetNetwork(spec.Name)
+	if err != nil {
+		return &proto.RenumberNetworkResponse{}, err
+	}
+
+	nextNetwork, subnets, err := splitNetwork(spec.Address, network.NumSubnets)
+	if err != nil {
+		return &proto.RenumberNetworkResponse{}, err
+	}
+
+	status, err := s.wgService.StartRenumber(spec.Name, nextNetwork.String(), subnets)
+	return &proto.RenumberNetworkResponse{
+		Status: status,
+	}, err
+}
+
+func (s *WireguardServer) GetRenumberStatus(ctx context.Context, spec *proto.RenumberStatusRequest) (*proto.RenumberNetworkResponse, error) {
+	status, err := s.wgService.GetRenumberStatus(spec.Name)
+	return &proto.RenumberNetworkResponse{
+		Status: status,
+	}, err
+}
+
+func (s *WireguardServer) FinishRenumber(ctx context.Context, spec *proto.FinishRenumberRequest) (*proto.RenumberNetworkResponse, error) {
+	status, err := s.wgService.FinishRenumber(spec.Name, spec.Force)
+	return &proto.RenumberNetworkResponse{
+		Status: status,
+	}, err
+}
+
+func (s *WireguardServer) AbortRenumber(ctx context.Context, spec *proto.AbortRenumberRequest) (*proto.RenumberNetworkResponse, error) {
+	status, err := s.wgService.AbortRenumber(spec.Name)
+	return &proto.RenumberNetworkResponse{
+		Status: status,
+	}, err
+}
+
+func (s *WireguardServer) AcknowledgeRenumber(ctx context.Context, spec *proto.AcknowledgeRenumberRequest) (*proto.AcknowledgeRenumberResponse, error) {
+	err := s.wgService.AcknowledgeRenumber(spec.Uuid, spec.NextIpRange)
+	return &proto.AcknowledgeRenumberResponse{
+		Uuid: spec.Uuid,
+	}, err
+}
+
 func (s *WireguardServer) PurgeLeases(ctx context.Context, nothing *empty.Empty) (*empty.Empty, error) {
 	return &empty.Empty{}, s.wgService.PurgeLeases()
 }
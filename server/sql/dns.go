@@ -0,0 +1,54 @@
+package sql
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/thomas-maurice/wgnw/common"
+)
+
+// checkDNSDomain rejects the domains the names of the nodes cannot be in
+func checkDNSDomain(domain string) error {
+	if domain == "" {
+		return nil
+	}
+	if _, ok := dns.IsDomainName(domain); !ok {
+		return fmt.Errorf("%s is not a valid DNS domain", domain)
+	}
+	return nil
+}
+
+// DNSZones returns the names of the nodes of the networks that have DNS
+// enabled, with the addresses of their leases
+func (s *SQLWireguardService) DNSZones() ([]common.DNSZone, error) {
+	var networks []Network
+	err := s.db.Where("dns = ?", true).Find(&networks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []common.DNSZone
+	for _, network := range networks {
+		var leases []Lease
+		err = s.db.Where("expires > ? AND parent = ?", time.Now().Unix(), network.Name).Find(&leases).Error
+		if err != nil {
+			return nil, err
+		}
+
+		zone := common.NewDNSZone(common.NetworkDomain(network.Name, network.DNSDomain))
+		for _, lease := range leases {
+			for _, address := range []string{lease.Address, lease.NextAddress} {
+				ip, _, err := net.ParseCIDR(address)
+				if err == nil {
+					zone.Add(lease.NodeName, ip)
+				}
+			}
+		}
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
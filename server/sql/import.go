@@ -0,0 +1,201 @@
+package sql
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/google/uuid"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	proto "github.com/thomas-maurice/wgnw/proto"
+)
+
+// leaseSubnet returns the subnet a lease address is in, imported leases keep
+// the address of their peer in the host part
+func leaseSubnet(address string) string {
+	_, subnet, err := net.ParseCIDR(address)
+	if err != nil {
+		return address
+	}
+	return subnet.String()
+}
+
+// importedLeases checks the peers against the network and returns the leases
+// they would get, keyed by subnet, along with every conflict found
+func importedLeases(n *proto.Network, peers []*proto.ImportedPeer) (map[string]*Lease, []string) {
+	var conflicts []string
+	_, network, err := net.ParseCIDR(n.Address)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("invalid network address %s: %s", n.Address, err)}
+	}
+
+	var subnets []*net.IPNet
+	for _, address := range n.Subnets {
+		_, subnet, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, []string{fmt.Sprintf("invalid subnet %s: %s", address, err)}
+		}
+		subnets = append(subnets, subnet)
+	}
+
+	leases := make(map[string]*Lease)
+	names := make(map[string]string)
+	keys := make(map[string]string)
+	for _, peer := range peers {
+		if peer.NodeName == "" {
+			conflicts = append(conflicts, fmt.Sprintf("peer %s has no node name", peer.PublicKey))
+			continue
+		}
+		if other, ok := names[peer.NodeName]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("node name %s is used by both %s and %s", peer.NodeName, other, peer.PublicKey))
+		}
+		names[peer.NodeName] = peer.PublicKey
+
+		if _, err := wgtypes.ParseKey(peer.PublicKey); err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("node %s has an invalid public key %q", peer.NodeName, peer.PublicKey))
+		} else if other, ok := keys[peer.PublicKey]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("nodes %s and %s have the same public key", other, peer.NodeName))
+		}
+		keys[peer.PublicKey] = peer.NodeName
+
+		if peer.Peer != nil && (peer.Peer.Address == "" || peer.Peer.Port <= 0 || peer.Peer.Port > 65535) {
+			conflicts = append(conflicts, fmt.Sprintf("node %s has an invalid endpoint %s:%d", peer.NodeName, peer.Peer.Address, peer.Peer.Port))
+		}
+
+		ip := net.ParseIP(peer.Address).To4()
+		if ip == nil {
+			conflicts = append(conflicts, fmt.Sprintf("node %s has an invalid address %q", peer.NodeName, peer.Address))
+			continue
+		}
+		if !network.Contains(ip) {
+			conflicts = append(conflicts, fmt.Sprintf("address %s of node %s is not in %s", ip, peer.NodeName, network))
+			continue
+		}
+
+		var subnet *net.IPNet
+		for _, sn := range subnets {
+			if sn.Contains(ip) {
+				subnet = sn
+				break
+			}
+		}
+		if subnet == nil {
+			conflicts = append(conflicts, fmt.Sprintf("address %s of node %s is in none of the subnets", ip, peer.NodeName))
+			continue
+		}
+		if other, ok := leases[subnet.String()]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("nodes %s and %s are both in subnet %s, use more subnets", other.NodeName, peer.NodeName, subnet))
+			continue
+		}
+
+		ones, _ := subnet.Mask.Size()
+		lease := &Lease{
+			Parent:    n.Name,
+			Expires:   staticLeaseExpires,
+			Address:   fmt.Sprintf("%s/%d", ip, ones),
+			PublicKey: peer.PublicKey,
+			NodeName:  peer.NodeName,
+			Static:    true,
+		}
+		if peer.Peer != nil {
+			lease.PeerAddress = &peer.Peer.Address
+			lease.PeerPort = peer.Peer.Port
+		}
+		leases[subnet.String()] = lease
+	}
+
+	return leases, conflicts
+}
+
+// ImportNetwork creates a network along with static leases that keep the
+// addresses and keys of the peers of an existing wireguard network. Nothing
+// is created if there is any conflict, or on a dry run.
+func (s *SQLWireguardService) ImportNetwork(n *proto.Network, peers []*proto.ImportedPeer, dryRun bool) (*proto.ImportNetworkResponse, error) {
+	network, err := s.networkModel(n)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	var existing Network
+	err = s.db.Where(&Network{Name: n.Name}).First(&existing).Error
+	if err == nil {
+		conflicts = append(conflicts, fmt.Sprintf("network %s already exists", n.Name))
+	}
+
+	leases, leaseConflicts := importedLeases(n, peers)
+	conflicts = append(conflicts, leaseConflicts...)
+
+	resp := &proto.ImportNetworkResponse{
+		Network: &proto.Network{
+			Name:       n.Name,
+			Address:    n.Address,
+			Subnets:    n.Subnets,
+			NumSubnets: n.NumSubnets,
+			Settings:   n.Settings,
+		},
+		Conflicts: conflicts,
+	}
+	if len(conflicts) != 0 || dryRun {
+		for _, sn := range n.Subnets {
+			if lease, ok := leases[sn]; ok {
+				resp.Leases = append(resp.Leases, importedLease(lease))
+			}
+		}
+		return resp, nil
+	}
+
+	tx := s.db.Begin()
+	err = tx.Create(network).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, sn := range n.Subnets {
+		subnet := SubNetwork{
+			Parent:  n.Name,
+			Address: sn,
+		}
+		lease, used := leases[sn]
+		if used {
+			subnet.Free = staticLeaseExpires
+		}
+		err = tx.Create(&subnet).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !used {
+			continue
+		}
+
+		lease.UUID = uuid.New().String()
+		err = tx.Create(lease).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		resp.Leases = append(resp.Leases, importedLease(lease))
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func importedLease(lease *Lease) *proto.Lease {
+	return &proto.Lease{
+		Uuid:      lease.UUID,
+		Expires:   lease.Expires,
+		PublicKey: lease.PublicKey,
+		Network:   lease.Parent,
+		IpRange:   lease.Address,
+		NodeName:  lease.NodeName,
+		Static:    lease.Static,
+	}
+}
@@ -0,0 +1,163 @@
+package sql
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/common"
+	proto "github.com/thomas-maurice/wgnw/proto"
+)
+
+// getControllerKey loads the controller key, or generates it on the
+// first start. Controllers sharing a database share the key.
+func getControllerKey(db *gorm.DB) (wgtypes.Key, error) {
+	var stored ControllerKey
+	err := db.Where(&ControllerKey{ID: 1}).First(&stored).Error
+	if gorm.IsRecordNotFoundError(err) {
+		key, err := wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return wgtypes.Key{}, err
+		}
+
+		err = db.Create(&ControllerKey{ID: 1, PrivateKey: key.String()}).Error
+		if err == nil {
+			logrus.Info("Generated a new controller key")
+			return key, nil
+		}
+
+		// Another controller may have created it in the meantime
+		err = db.Where(&ControllerKey{ID: 1}).First(&stored).Error
+	}
+	if err != nil {
+		return wgtypes.Key{}, err
+	}
+
+	return wgtypes.ParseKey(stored.PrivateKey)
+}
+
+// rotationDue tells if the overlap of the key rotation of the lease is over
+func rotationDue(lease Lease) bool {
+	return lease.NextPublicKey != "" && lease.RotateAt != 0 && lease.RotateAt <= time.Now().Unix()
+}
+
+// scheduleRotations schedules the pending key rotations of the leases once
+// every other lease fetched the configuration since the rotation started,
+// so that no peer is left with only the old key. Static leases never fetch
+// it, rotations only start in networks without them.
+func (s *SQLWireguardService) scheduleRotations(leases []Lease) error {
+	for i, lease := range leases {
+		if lease.NextPublicKey == "" || lease.RotateAt != 0 {
+			continue
+		}
+
+		var waiting []string
+		for _, peer := range leases {
+			if peer.UUID != lease.UUID && !peer.Static && peer.FetchedAt <= lease.RotationStart {
+				waiting = append(waiting, peer.NodeName)
+			}
+		}
+		if len(waiting) != 0 {
+			logrus.Debugf("Lease %s waits for %s to fetch its next key", lease.UUID, strings.Join(waiting, ", "))
+			continue
+		}
+
+		rotateAt := time.Now().Add(s.rotationOverlap).Unix()
+		err := s.db.Model(&lease).Update("rotate_at", rotateAt).Error
+		if err != nil {
+			return err
+		}
+		leases[i].RotateAt = rotateAt
+		logrus.Infof("Every peer fetched the next key of lease %s, it rotates to %s at %s", lease.UUID, lease.NextPublicKey, time.Unix(rotateAt, 0))
+	}
+	return nil
+}
+
+func (s *SQLWireguardService) GetControllerKey() (string, error) {
+	return s.controllerKey.PublicKey().String(), nil
+}
+
+// RotateLeaseKey publishes the next key of the lease, provided the proof was
+// made with the current key. The switch is scheduled once every peer has it.
+func (s *SQLWireguardService) RotateLeaseKey(id string, nextPublicKey string, proof []byte) (*proto.Lease, error) {
+	next, err := wgtypes.ParseKey(nextPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next public key: %s", err)
+	}
+
+	var lease Lease
+	err = s.db.Where(&Lease{UUID: id}).First(&lease).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if lease.Expires-time.Now().Unix() < 0 {
+		return nil, fmt.Errorf("lease %s is expired", id)
+	}
+
+	current, err := wgtypes.ParseKey(lease.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := common.KeyRotationProof(s.controllerKey, current, lease.UUID, next.String())
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(expected, proof) {
+		return nil, fmt.Errorf("invalid proof for lease %s", id)
+	}
+
+	// Static peers have no agent to fetch the next key, their wg-quick files
+	// would keep the old one and lose the tunnel at the switch
+	var statics []Lease
+	err = s.db.Where("parent = ? AND static = ? AND expires > ?", lease.Parent, true, time.Now().Unix()).Find(&statics).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(statics) != 0 {
+		var names []string
+		for _, static := range statics {
+			names = append(names, static.NodeName)
+		}
+		return nil, fmt.Errorf("network %s has static peers that would keep the old key: %s. Delete their leases, add them back once the rotation is over and re-export their peer files", lease.Parent, strings.Join(names, ", "))
+	}
+
+	var count int
+	err = s.db.Model(&Lease{}).Where("parent = ? AND lease_uuid <> ? AND (public_key = ? OR next_public_key = ?)", lease.Parent, lease.UUID, next.String(), next.String()).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("key %s is already used in network %s", next.String(), lease.Parent)
+	}
+
+	err = s.db.Model(&lease).Updates(map[string]interface{}{
+		"next_public_key": next.String(),
+		"rotate_at":       0,
+		"rotation_start":  time.Now().Unix(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Lease %s rotates its key to %s once every peer has it", lease.UUID, next.String())
+
+	return &proto.Lease{
+		Uuid:          lease.UUID,
+		Expires:       lease.Expires,
+		PublicKey:     lease.PublicKey,
+		Network:       lease.Parent,
+		IpRange:       lease.Address,
+		NextIpRange:   lease.NextAddress,
+		NodeName:      lease.NodeName,
+		NextPublicKey: next.String(),
+		Tags:          splitTags(lease.Tags),
+		Static:        lease.Static,
+	}, nil
+}
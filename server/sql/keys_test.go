@@ -0,0 +1,56 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/thomas-maurice/wgnw/common"
+	proto "github.com/thomas-maurice/wgnw/proto"
+)
+
+func TestRotateLeaseKeyRefusesStaticPeers(t *testing.T) {
+	s := newTestService(t)
+	current, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease, err := s.AcquireLease(&proto.AcquireLeaseRequest{NetworkName: "lab", NodeName: "node1", PublicKey: current.PublicKey().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := s.AcquireLease(&proto.AcquireLeaseRequest{NetworkName: "lab", NodeName: "phone", GenerateKey: true, Static: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := common.KeyRotationProof(current, s.controllerKey.PublicKey(), lease.Uuid, next.PublicKey().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RotateLeaseKey(lease.Uuid, next.PublicKey().String(), proof)
+	if err == nil {
+		t.Fatal("expected the rotation to be refused")
+	}
+	if !strings.Contains(err.Error(), "phone") {
+		t.Errorf("expected the error to name the static peer, got %q", err)
+	}
+
+	err = s.DeleteLease(phone.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := s.RotateLeaseKey(lease.Uuid, next.PublicKey().String(), proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.NextPublicKey != next.PublicKey().String() {
+		t.Errorf("expected the lease to rotate to %s, got %q", next.PublicKey(), rotated.NextPublicKey)
+	}
+}
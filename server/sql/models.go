@@ -5,6 +5,18 @@ type Network struct {
 	Name       string `gorm:"column:name;type:varchar(128);unique;primary_key"`
 	Address    string `gorm:"column:address;type:varchar(64)"`
 	NumSubnets int32  `gorm:"column:subnets;type:integer"`
+	// Set while the network is being renumbered
+	NextAddress     string `gorm:"column:next_address;type:varchar(64)"`
+	RenumberStarted int64  `gorm:"column:renumber_started;type:bigint"`
+	// Per network settings, 0 means default
+	MTU                 int32 `gorm:"column:mtu;type:integer"`
+	PersistentKeepalive int32 `gorm:"column:persistent_keepalive;type:integer"`
+	ListenPort          int32 `gorm:"column:listen_port;type:integer"`
+	LeaseDuration       int64 `gorm:"column:lease_duration;type:bigint"`
+	PresharedKeys       bool  `gorm:"column:preshared_keys"`
+	// Empty means <network>.wgnw
+	DNSDomain string `gorm:"column:dns_domain"`
+	DNS       bool   `gorm:"column:dns"`
 }
 
 func (t Network) TableName() string {
@@ -16,6 +28,8 @@ type SubNetwork struct {
 	Address string `gorm:"column:address;type:varchar(64)"`
 	Parent  string `gorm:"column:parent;type:varchar(128) references network(name) on delete cascade on update no action"`
 	Free    int64  `gorm:"column:free;type:bigint"`
+	// Pending subnets belong to the range the network is being renumbered to
+	Pending bool `gorm:"column:pending"`
 }
 
 func (t SubNetwork) TableName() string {
@@ -31,8 +45,80 @@ type Lease struct {
 	PeerAddress *string `gorm:"column:peer_address"`
 	PeerPort    int32   `gorm:"column:peer_port"`
 	UUID        string  `gorm:"column:lease_uuid;not null"`
+	NextAddress string  `gorm:"column:next_address"`
+	RenumberAck bool    `gorm:"column:renumber_ack"`
+	NodeName    string  `gorm:"column:node_name"`
+	// Set while the key of the lease is being rotated, the rotation is only
+	// scheduled at rotate_at once every peer fetched the next key
+	NextPublicKey string `gorm:"column:next_public_key"`
+	RotateAt      int64  `gorm:"column:rotate_at;type:bigint"`
+	RotationStart int64  `gorm:"column:rotation_start;type:bigint"`
+	// Last time the holder fetched the configuration of the network
+	FetchedAt int64 `gorm:"column:fetched_at;type:bigint"`
+	// Comma separated tags of the node
+	Tags string `gorm:"column:tags"`
+	// Static leases never expire, they belong to peers without an agent
+	Static bool `gorm:"column:static"`
 }
 
 func (t Lease) TableName() string {
 	return "lease"
 }
+
+// PeerReport is what a lease last reported about one of its peers
+type PeerReport struct {
+	ID           int64  `gorm:"column:id;auto_increment"`
+	LeaseUUID    string `gorm:"column:lease_uuid;not null;index"`
+	PublicKey    string `gorm:"column:public_key;not null"`
+	Handshake    bool   `gorm:"column:handshake"`
+	HandshakeAge int64  `gorm:"column:handshake_age;type:bigint"`
+	RxBytes      int64  `gorm:"column:rx_bytes;type:bigint"`
+	TxBytes      int64  `gorm:"column:tx_bytes;type:bigint"`
+	Endpoint     string `gorm:"column:endpoint"`
+	ReportedAt   int64  `gorm:"column:reported_at;type:bigint"`
+}
+
+func (t PeerReport) TableName() string {
+	return "peer_report"
+}
+
+// ControllerKey is the X25519 key the agents prove the ownership
+// of their lease key against when they rotate it
+type ControllerKey struct {
+	ID         int64  `gorm:"column:id;primary_key"`
+	PrivateKey string `gorm:"column:private_key;not null"`
+}
+
+func (t ControllerKey) TableName() string {
+	return "controller_key"
+}
+
+// PresharedKey is the preshared key of a pair of leases, LeaseA being
+// the lowest uuid, encrypted with the master key of the controller
+type PresharedKey struct {
+	ID     int64  `gorm:"column:id;auto_increment"`
+	Parent string `gorm:"column:parent;type:varchar(128) references network(name) on delete cascade on update no action"`
+	LeaseA string `gorm:"column:lease_a;not null"`
+	LeaseB string `gorm:"column:lease_b;not null"`
+	Key    string `gorm:"column:key;not null"`
+}
+
+func (t PresharedKey) TableName() string {
+	return "preshared_key"
+}
+
+// Route is a prefix a lease advertises, approved ones are reachable
+// through it
+type Route struct {
+	ID       int64  `gorm:"column:id;auto_increment"`
+	Parent   string `gorm:"column:parent;type:varchar(128) references network(name) on delete cascade on update no action"`
+	NodeName string `gorm:"column:node_name;not null"`
+	Prefix   string `gorm:"column:prefix;not null"`
+	Approved bool   `gorm:"column:approved"`
+	// Lease advertising the prefix, the route goes away with it
+	LeaseUUID string `gorm:"column:lease_uuid;index"`
+}
+
+func (t Route) TableName() string {
+	return "route"
+}
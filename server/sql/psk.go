@@ -0,0 +1,142 @@
+package sql
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// errNoMasterKey is returned when preshared keys are needed but the
+// controller has no master key to encrypt them with
+var errNoMasterKey = errors.New("preshared keys need the controller to be started with a master key")
+
+// sealPresharedKey encrypts the preshared key with the master key
+func (s *SQLWireguardService) sealPresharedKey(key wgtypes.Key) (string, error) {
+	gcm, err := s.masterCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, key[:], nil)), nil
+}
+
+// openPresharedKey decrypts a preshared key sealed with the master key
+func (s *SQLWireguardService) openPresharedKey(sealed string) (wgtypes.Key, error) {
+	gcm, err := s.masterCipher()
+	if err != nil {
+		return wgtypes.Key{}, err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return wgtypes.Key{}, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return wgtypes.Key{}, errors.New("sealed preshared key is too short")
+	}
+
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return wgtypes.Key{}, fmt.Errorf("could not decrypt preshared key, is it the right master key? %s", err)
+	}
+	return wgtypes.NewKey(plain)
+}
+
+func (s *SQLWireguardService) masterCipher() (cipher.AEAD, error) {
+	if s.masterKey == nil {
+		return nil, errNoMasterKey
+	}
+
+	block, err := aes.NewCipher(s.masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// leasePair orders the uuids of a pair of leases the way they are stored
+func leasePair(a string, b string) (string, string) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+// presharedKeys returns the preshared keys the lease shares with each of the
+// peers, by peer lease uuid, and generates the missing ones
+func (s *SQLWireguardService) presharedKeys(network string, id string, peers []Lease) (map[string]string, error) {
+	var stored []PresharedKey
+	err := s.db.Where("parent = ? AND (lease_a = ? OR lease_b = ?)", network, id, id).Find(&stored).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make(map[string]string)
+	for _, psk := range stored {
+		sealed[psk.LeaseA+psk.LeaseB] = psk.Key
+	}
+
+	keys := make(map[string]string)
+	for _, peer := range peers {
+		if peer.UUID == id {
+			continue
+		}
+
+		a, b := leasePair(id, peer.UUID)
+		if sealed[a+b] == "" {
+			sealed[a+b], err = s.createPresharedKey(network, a, b)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		key, err := s.openPresharedKey(sealed[a+b])
+		if err != nil {
+			return nil, err
+		}
+		keys[peer.UUID] = key.String()
+	}
+
+	return keys, nil
+}
+
+// createPresharedKey generates the preshared key of the pair, unless the
+// other lease of the pair did in the meantime
+func (s *SQLWireguardService) createPresharedKey(network string, a string, b string) (string, error) {
+	key, err := wgtypes.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := s.sealPresharedKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Create(&PresharedKey{Parent: network, LeaseA: a, LeaseB: b, Key: sealed}).Error
+	if err == nil {
+		return sealed, nil
+	}
+
+	var existing PresharedKey
+	if s.db.Where(&PresharedKey{LeaseA: a, LeaseB: b}).First(&existing).Error != nil {
+		return "", err
+	}
+	return existing.Key, nil
+}
+
+// deletePresharedKeys deletes the preshared keys of the lease
+func deletePresharedKeys(tx *gorm.DB, id string) error {
+	return tx.Where("lease_a = ? OR lease_b = ?", id, id).Delete(&PresharedKey{}).Error
+}
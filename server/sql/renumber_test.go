@@ -0,0 +1,148 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	proto "github.com/thomas-maurice/wgnw/proto"
+)
+
+// newTestService returns a service on a throwaway sqlite database with
+// network lab split in four subnets
+func newTestService(t *testing.T) *SQLWireguardService {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "wgnw-sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	service, err := NewSQLWireguardService("sqlite3", filepath.Join(dir, "db.sqlite3"), false, time.Hour, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := service.(*SQLWireguardService)
+	t.Cleanup(func() { s.db.Close() })
+
+	err = s.CreateNetwork(&proto.Network{
+		Name:       "lab",
+		Address:    "10.60.0.0/16",
+		NumSubnets: 4,
+		Subnets:    []string{"10.60.0.0/18", "10.60.64.0/18", "10.60.128.0/18", "10.60.192.0/18"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+var nextSubnets = []string{"10.70.0.0/18", "10.70.64.0/18", "10.70.128.0/18", "10.70.192.0/18"}
+
+func TestStartRenumberRefusesStaticPeers(t *testing.T) {
+	s := newTestService(t)
+	for _, name := range []string{"phone", "router"} {
+		_, err := s.AcquireLease(&proto.AcquireLeaseRequest{NetworkName: "lab", NodeName: name, GenerateKey: true, Static: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := s.StartRenumber("lab", "10.70.0.0/16", nextSubnets)
+	if err == nil {
+		t.Fatal("expected the renumbering to be refused")
+	}
+	if !strings.Contains(err.Error(), "phone, router") {
+		t.Errorf("expected the error to name the static peers, got %q", err)
+	}
+
+	var network Network
+	err = s.db.Where(&Network{Name: "lab"}).First(&network).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.NextAddress != "" {
+		t.Errorf("expected the network not to be renumbered, got %s", network.NextAddress)
+	}
+}
+
+func TestStartRenumber(t *testing.T) {
+	s := newTestService(t)
+	key := "0mXMn7BPCRKjOhUnkRgcPjDLXNHtYrf1Mf8tUaZLuFQ="
+	_, err := s.AcquireLease(&proto.AcquireLeaseRequest{NetworkName: "lab", NodeName: "node1", PublicKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.StartRenumber("lab", "10.70.0.0/16", nextSubnets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lease Lease
+	err = s.db.Where(&Lease{NodeName: "node1"}).First(&lease).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.NextAddress == "" {
+		t.Error("expected the lease to get a range in the next address space")
+	}
+}
+
+func TestFinishRenumberDropsLeasesWithoutNextRange(t *testing.T) {
+	s := newTestService(t)
+	var leases []*proto.Lease
+	for _, key := range []string{"0mXMn7BPCRKjOhUnkRgcPjDLXNHtYrf1Mf8tUaZLuFQ=", "ZmvUTfqYVLpA0lOOmGgxHDo7RGu8qLDIhUO+rsdVCXo="} {
+		lease, err := s.AcquireLease(&proto.AcquireLeaseRequest{NetworkName: "lab", PublicKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		leases = append(leases, lease)
+	}
+	kept, expired := leases[0].Uuid, leases[1].Uuid
+
+	// The expired lease does not get a next range
+	err := s.db.Model(&Lease{}).Where(&Lease{UUID: expired}).Updates(map[string]interface{}{"expires": time.Now().Add(-time.Minute).Unix()}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []interface{}{
+		&PeerReport{LeaseUUID: expired, PublicKey: leases[0].PublicKey},
+		&PeerReport{LeaseUUID: kept, PublicKey: leases[1].PublicKey},
+		&PresharedKey{Parent: "lab", LeaseA: kept, LeaseB: expired, Key: "psk"},
+	} {
+		err = s.db.Create(row).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = s.StartRenumber("lab", "10.70.0.0/16", nextSubnets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.FinishRenumber("lab", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	s.db.Model(&Lease{}).Where(&Lease{UUID: expired}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the lease without a next range to be deleted")
+	}
+	s.db.Model(&PeerReport{}).Where(&PeerReport{LeaseUUID: expired}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the peer reports of the deleted lease to be gone, got %d", count)
+	}
+	s.db.Model(&PeerReport{}).Where(&PeerReport{LeaseUUID: kept}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected the peer reports of the other lease to be kept, got %d", count)
+	}
+	s.db.Model(&PresharedKey{}).Where("lease_a = ? OR lease_b = ?", expired, expired).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the preshared keys of the deleted lease to be gone, got %d", count)
+	}
+}
@@ -0,0 +1,227 @@
+package sql
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	proto "github.com/thomas-maurice/wgnw/proto"
+)
+
+// normalizeRoutes parses the advertised prefixes and drops the duplicates
+func normalizeRoutes(prefixes []string) ([]string, error) {
+	var routes []string
+	seen := make(map[string]bool)
+	for _, prefix := range prefixes {
+		_, route, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %s: %s", prefix, err)
+		}
+		if !seen[route.String()] {
+			seen[route.String()] = true
+			routes = append(routes, route.String())
+		}
+	}
+	return routes, nil
+}
+
+// isDefaultRoute tells if the prefix is 0.0.0.0/0 or ::/0, the routes
+// of the exit nodes, they overlap with everything on purpose
+func isDefaultRoute(prefix string) bool {
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return false
+	}
+	ones, _ := n.Mask.Size()
+	return ones == 0
+}
+
+// advertiseRoutes records the prefixes the lease advertises, the ones it
+// stopped advertising are forgotten along with their approval
+func advertiseRoutes(tx *gorm.DB, lease Lease, prefixes []string) error {
+	var existing []Route
+	err := tx.Where(&Route{Parent: lease.Parent, LeaseUUID: lease.UUID}).Find(&existing).Error
+	if err != nil {
+		return err
+	}
+
+	advertised := make(map[string]bool)
+	for _, prefix := range prefixes {
+		advertised[prefix] = true
+	}
+
+	known := make(map[string]bool)
+	for _, route := range existing {
+		known[route.Prefix] = true
+		if !advertised[route.Prefix] {
+			err = tx.Delete(&route).Error
+			if err != nil {
+				return err
+			}
+			logrus.WithField("network", lease.Parent).Infof("Node %s no longer advertises %s", lease.NodeName, route.Prefix)
+		}
+	}
+
+	for _, prefix := range prefixes {
+		if known[prefix] {
+			continue
+		}
+		err = tx.Create(&Route{
+			Parent:    lease.Parent,
+			NodeName:  lease.NodeName,
+			Prefix:    prefix,
+			LeaseUUID: lease.UUID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		logrus.WithField("network", lease.Parent).Infof("Node %s advertises %s, waiting for approval", lease.NodeName, prefix)
+	}
+
+	return nil
+}
+
+// deleteRoutes deletes the routes the lease advertises
+func deleteRoutes(tx *gorm.DB, id string) error {
+	return tx.Where(&Route{LeaseUUID: id}).Delete(&Route{}).Error
+}
+
+// approvedRoutes returns the approved prefixes of the network by lease
+// uuid, and the leases that have an approved default route
+func (s *SQLWireguardService) approvedRoutes(network string) (map[string][]string, map[string]bool, error) {
+	var routes []Route
+	err := s.db.Where("parent = ? AND approved = ?", network, true).Order("prefix").Find(&routes).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byLease := make(map[string][]string)
+	exits := make(map[string]bool)
+	for _, route := range routes {
+		// Default routes only go to the nodes that opted in
+		if isDefaultRoute(route.Prefix) {
+			exits[route.LeaseUUID] = true
+			continue
+		}
+		byLease[route.LeaseUUID] = append(byLease[route.LeaseUUID], route.Prefix)
+	}
+	return byLease, exits, nil
+}
+
+func protoRoute(route Route) *proto.Route {
+	return &proto.Route{
+		Network:   route.Parent,
+		NodeName:  route.NodeName,
+		Prefix:    route.Prefix,
+		Approved:  route.Approved,
+		LeaseUuid: route.LeaseUUID,
+	}
+}
+
+func (s *SQLWireguardService) ListRoutes(network string) ([]*proto.Route, error) {
+	query := s.db.Order("parent, node_name, prefix")
+	if network != "" {
+		query = query.Where(&Route{Parent: network})
+	}
+
+	var routes []Route
+	err := query.Find(&routes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var protoRoutes []*proto.Route
+	for _, route := range routes {
+		protoRoutes = append(protoRoutes, protoRoute(route))
+	}
+	return protoRoutes, nil
+}
+
+// getRoute returns the route of the prefix advertised by a lease, given by
+// its uuid or by its node name if only one lease of that name advertises it
+func (s *SQLWireguardService) getRoute(network string, node string, prefix string) (Route, error) {
+	prefixes, err := normalizeRoutes([]string{prefix})
+	if err != nil {
+		return Route{}, err
+	}
+
+	var routes []Route
+	err = s.db.Where("parent = ? AND prefix = ? AND (lease_uuid = ? OR node_name = ?)", network, prefixes[0], node, node).Find(&routes).Error
+	if err != nil {
+		return Route{}, err
+	}
+	switch len(routes) {
+	case 0:
+		return Route{}, fmt.Errorf("node %s does not advertise %s in network %s", node, prefixes[0], network)
+	case 1:
+		return routes[0], nil
+	default:
+		return Route{}, fmt.Errorf("%d leases of node %s advertise %s in network %s, use the uuid of the lease", len(routes), node, prefixes[0], network)
+	}
+}
+
+// ApproveRoute makes the prefix reachable through the node, provided it
+// does not overlap with the network or with another approved route.
+// Default routes make the node an exit node, there can be several.
+func (s *SQLWireguardService) ApproveRoute(network string, node string, prefix string) (*proto.Route, error) {
+	route, err := s.getRoute(network, node, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if isDefaultRoute(route.Prefix) {
+		return s.approveRoute(route)
+	}
+
+	var n Network
+	err = s.db.Where(&Network{Name: network}).First(&n).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range []string{n.Address, n.NextAddress} {
+		if address != "" && overlaps(route.Prefix, address) {
+			return nil, fmt.Errorf("%s overlaps with the range %s of network %s", route.Prefix, address, network)
+		}
+	}
+
+	var approved []Route
+	err = s.db.Where("parent = ? AND approved = ? AND id <> ?", network, true, route.ID).Find(&approved).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range approved {
+		if !isDefaultRoute(other.Prefix) && overlaps(route.Prefix, other.Prefix) {
+			return nil, fmt.Errorf("%s overlaps with %s, approved for node %s", route.Prefix, other.Prefix, other.NodeName)
+		}
+	}
+
+	return s.approveRoute(route)
+}
+
+func (s *SQLWireguardService) approveRoute(route Route) (*proto.Route, error) {
+	err := s.db.Model(&route).Updates(&Route{Approved: true}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithField("network", route.Parent).Infof("Approved route %s through node %s", route.Prefix, route.NodeName)
+	return protoRoute(route), nil
+}
+
+// RevokeRoute stops giving the prefix to the peers, the node keeps
+// advertising it until it is approved again
+func (s *SQLWireguardService) RevokeRoute(network string, node string, prefix string) (*proto.Route, error) {
+	route, err := s.getRoute(network, node, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Model(&route).Updates(map[string]interface{}{"approved": false}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithField("network", network).Infof("Revoked route %s through node %s", route.Prefix, node)
+	return protoRoute(route), nil
+}
@@ -1,6 +1,9 @@
 package sql
 
 import (
+	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,7 +12,9 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
+	"github.com/thomas-maurice/wgnw/common"
 	proto "github.com/thomas-maurice/wgnw/proto"
 	"github.com/thomas-maurice/wgnw/server/interfaces"
 )
@@ -17,6 +22,11 @@ import (
 type SQLWireguardService struct {
 	db            *gorm.DB
 	leaseDuration time.Duration
+	// How long the old key of a lease stays in use after a rotation
+	rotationOverlap time.Duration
+	controllerKey   wgtypes.Key
+	// Key the preshared keys are encrypted with, nil if there is none
+	masterKey []byte
 }
 
 func getDatabase(driver string, connString string, verbose bool) (*gorm.DB, error) {
@@ -32,7 +42,7 @@ func getDatabase(driver string, connString string, verbose bool) (*gorm.DB, erro
 		}
 	}
 
-	err = db.AutoMigrate(Network{}, SubNetwork{}, Lease{}).Error
+	err = db.AutoMigrate(Network{}, SubNetwork{}, Lease{}, PeerReport{}, ControllerKey{}, PresharedKey{}, Route{}).Error
 	if err != nil {
 		return nil, err
 	}
@@ -40,30 +50,116 @@ func getDatabase(driver string, connString string, verbose bool) (*gorm.DB, erro
 	if err != nil {
 		return nil, err
 	}
+	err = db.Model(&PresharedKey{}).AddUniqueIndex("idx_preshared_key_pair", "lease_a", "lease_b").Error
+	if err != nil {
+		return nil, err
+	}
 
 	return db, err
 }
 
-func NewSQLWireguardService(driver string, connString string, verbose bool, leaseDuration time.Duration) (interfaces.WireguardService, error) {
+func NewSQLWireguardService(driver string, connString string, verbose bool, leaseDuration time.Duration, rotationOverlap time.Duration, masterKey []byte) (interfaces.WireguardService, error) {
 	db, err := getDatabase(driver, connString, verbose)
 	if err != nil {
 		return nil, err
 	}
 
+	key, err := getControllerKey(db)
+	if err != nil {
+		return nil, err
+	}
+
 	logrus.Infof("Lease duration: %s", leaseDuration)
+	logrus.Infof("Controller public key: %s", key.PublicKey().String())
 
 	return &SQLWireguardService{
-		db:            db,
-		leaseDuration: leaseDuration,
+		db:              db,
+		leaseDuration:   leaseDuration,
+		rotationOverlap: rotationOverlap,
+		controllerKey:   key,
+		masterKey:       masterKey,
+	}, nil
+}
+
+// networkSettings returns the settings of the network as they are stored
+func networkSettings(network Network) *proto.NetworkSettings {
+	return &proto.NetworkSettings{
+		Mtu:                 network.MTU,
+		PersistentKeepalive: network.PersistentKeepalive,
+		ListenPort:          network.ListenPort,
+		LeaseDuration:       network.LeaseDuration,
+		PresharedKeys:       presharedKeysSetting(network.PresharedKeys),
+		DnsDomain:           network.DNSDomain,
+		Dns:                 dnsSetting(network.DNS),
+	}
+}
+
+func presharedKeysSetting(enabled bool) proto.PresharedKeys {
+	if enabled {
+		return proto.PresharedKeys_PRESHARED_KEYS_ENABLED
+	}
+	return proto.PresharedKeys_PRESHARED_KEYS_DISABLED
+}
+
+func dnsSetting(enabled bool) proto.DNS {
+	if enabled {
+		return proto.DNS_DNS_ENABLED
+	}
+	return proto.DNS_DNS_DISABLED
+}
+
+// networkLeaseDuration returns the duration of the leases of the network in seconds
+func (s *SQLWireguardService) networkLeaseDuration(network Network) int64 {
+	if network.LeaseDuration > 0 {
+		return network.LeaseDuration
+	}
+	return int64(s.leaseDuration.Seconds())
+}
+
+// staticLeaseExpires is the expiry of the static leases, the end of the
+// year 9999, so that they are active for every query on the expiry
+const staticLeaseExpires int64 = 253402300799
+
+// leaseExpires returns when a lease of the network acquired or renewed now expires
+func (s *SQLWireguardService) leaseExpires(network Network, static bool) int64 {
+	if static {
+		return staticLeaseExpires
+	}
+	return time.Now().Unix() + s.networkLeaseDuration(network)
+}
+
+// networkModel validates the settings of a new network and returns its row
+func (s *SQLWireguardService) networkModel(n *proto.Network) (*Network, error) {
+	presharedKeys := n.GetSettings().GetPresharedKeys() == proto.PresharedKeys_PRESHARED_KEYS_ENABLED
+	if presharedKeys && s.masterKey == nil {
+		return nil, errNoMasterKey
+	}
+	err := checkDNSDomain(n.GetSettings().GetDnsDomain())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Network{
+		Name:                n.Name,
+		Address:             n.Address,
+		NumSubnets:          n.NumSubnets,
+		MTU:                 n.GetSettings().GetMtu(),
+		PersistentKeepalive: n.GetSettings().GetPersistentKeepalive(),
+		ListenPort:          n.GetSettings().GetListenPort(),
+		LeaseDuration:       n.GetSettings().GetLeaseDuration(),
+		PresharedKeys:       presharedKeys,
+		DNSDomain:           n.GetSettings().GetDnsDomain(),
+		DNS:                 n.GetSettings().GetDns() == proto.DNS_DNS_ENABLED,
 	}, nil
 }
 
 func (s *SQLWireguardService) CreateNetwork(n *proto.Network) error {
-	err := s.db.Create(&Network{
-		Name:       n.Name,
-		Address:    n.Address,
-		NumSubnets: n.NumSubnets,
-	}).Error
+	network, err := s.networkModel(n)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Create(network).Error
 
 	if err != nil {
 		return err
@@ -90,7 +186,13 @@ func (s *SQLWireguardService) ListNetworks() ([]*proto.Network, error) {
 
 	var protoNetworks []*proto.Network
 	for _, nw := range networks {
-		protoNetworks = append(protoNetworks, &proto.Network{Name: nw.Name, Address: nw.Address, NumSubnets: nw.NumSubnets})
+		protoNetworks = append(protoNetworks, &proto.Network{
+			Name:        nw.Name,
+			Address:     nw.Address,
+			NumSubnets:  nw.NumSubnets,
+			NextAddress: nw.NextAddress,
+			Settings:    networkSettings(nw),
+		})
 	}
 
 	return protoNetworks, nil
@@ -109,19 +211,71 @@ func (s *SQLWireguardService) GetNetwork(name string) (*proto.Network, error) {
 		return nil, err
 	}
 
-	var cidrs []string
+	var cidrs, nextCidrs []string
 	for _, sn := range subnets {
-		cidrs = append(cidrs, sn.Address)
+		if sn.Pending {
+			nextCidrs = append(nextCidrs, sn.Address)
+		} else {
+			cidrs = append(cidrs, sn.Address)
+		}
 	}
 
 	return &proto.Network{
-		Name:       network.Name,
-		NumSubnets: network.NumSubnets,
-		Address:    network.Address,
-		Subnets:    cidrs,
+		Name:        network.Name,
+		NumSubnets:  network.NumSubnets,
+		Address:     network.Address,
+		Subnets:     cidrs,
+		NextAddress: network.NextAddress,
+		NextSubnets: nextCidrs,
+		Settings:    networkSettings(network),
 	}, nil
 }
 
+func (s *SQLWireguardService) UpdateNetwork(name string, settings *proto.NetworkSettings) (*proto.Network, error) {
+	var network Network
+	err := s.db.Where(&Network{Name: name}).First(&network).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkDNSDomain(settings.GetDnsDomain())
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero values are ignored by the update
+	err = s.db.Model(&network).Updates(&Network{
+		MTU:                 settings.GetMtu(),
+		PersistentKeepalive: settings.GetPersistentKeepalive(),
+		ListenPort:          settings.GetListenPort(),
+		LeaseDuration:       settings.GetLeaseDuration(),
+		DNSDomain:           settings.GetDnsDomain(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.GetPresharedKeys() != proto.PresharedKeys_PRESHARED_KEYS_UNSET {
+		presharedKeys := settings.GetPresharedKeys() == proto.PresharedKeys_PRESHARED_KEYS_ENABLED
+		if presharedKeys && s.masterKey == nil {
+			return nil, errNoMasterKey
+		}
+		err = s.db.Model(&network).Updates(map[string]interface{}{"preshared_keys": presharedKeys}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if settings.GetDns() != proto.DNS_DNS_UNSET {
+		err = s.db.Model(&network).Updates(map[string]interface{}{"dns": settings.GetDns() == proto.DNS_DNS_ENABLED}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetNetwork(name)
+}
+
 func (s *SQLWireguardService) DeleteNetwork(name string) error {
 	var network Network
 	err := s.db.Where(&Network{Name: name}).First(&network).Error
@@ -138,17 +292,26 @@ func (s *SQLWireguardService) DeleteNetwork(name string) error {
 }
 
 func (s *SQLWireguardService) AcquireLease(leaseRequest *proto.AcquireLeaseRequest) (*proto.Lease, error) {
+	routes, err := normalizeRoutes(leaseRequest.Routes)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(leaseRequest.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	var network Network
-	err := s.db.Where(&Network{Name: leaseRequest.NetworkName}).First(&network).Error
+	err = s.db.Where(&Network{Name: leaseRequest.NetworkName}).First(&network).Error
 	if err != nil {
 		return nil, err
 	}
 
-	expires := time.Now().Unix() + int64(s.leaseDuration.Seconds())
+	expires := s.leaseExpires(network, leaseRequest.Static)
 
 	tx := s.db.Begin()
 	var subnet SubNetwork
-	err = tx.First(&subnet, "parent = ? AND free < ?", network.Name, time.Now().Unix()).Error
+	err = tx.First(&subnet, "parent = ? AND free < ? AND pending = ?", network.Name, time.Now().Unix(), false).Error
 
 	if err != nil {
 		tx.Rollback()
@@ -162,12 +325,34 @@ func (s *SQLWireguardService) AcquireLease(leaseRequest *proto.AcquireLeaseReque
 		return nil, err
 	}
 
+	// If the network is being renumbered the lease also needs a range
+	// in the next address space
+	var nextSubnet SubNetwork
+	if network.NextAddress != "" {
+		err = tx.First(&nextSubnet, "parent = ? AND free < ? AND pending = ?", network.Name, time.Now().Unix(), true).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		err = tx.Model(&nextSubnet).Updates(&SubNetwork{Free: expires}).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	lease := Lease{
 		Parent:    network.Name,
 		Expires:   expires,
 		PublicKey: leaseRequest.PublicKey,
 		Address:   subnet.Address,
 		UUID:      uuid.New().String(),
+		// The holder acknowledges it once it is configured
+		NextAddress: nextSubnet.Address,
+		NodeName:    leaseRequest.NodeName,
+		Tags:        joinTags(tags),
+		Static:      leaseRequest.Static,
 	}
 
 	if leaseRequest.Peer != nil {
@@ -177,15 +362,22 @@ func (s *SQLWireguardService) AcquireLease(leaseRequest *proto.AcquireLeaseReque
 
 	err = tx.Create(&lease).Error
 	if err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 
+	err = advertiseRoutes(tx, lease, routes)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	err = tx.Commit().Error
+	if leaseRequest.DryRun {
+		err = tx.Rollback().Error
+		lease.UUID = ""
+	} else {
+		err = tx.Commit().Error
+	}
 
 	if err != nil {
 		tx.Rollback()
@@ -193,11 +385,17 @@ func (s *SQLWireguardService) AcquireLease(leaseRequest *proto.AcquireLeaseReque
 	}
 
 	return &proto.Lease{
-		IpRange:   lease.Address,
-		Network:   lease.Parent,
-		Expires:   lease.Expires,
-		Uuid:      lease.UUID,
-		PublicKey: leaseRequest.PublicKey,
+		IpRange:       lease.Address,
+		Network:       lease.Parent,
+		Expires:       lease.Expires,
+		Uuid:          lease.UUID,
+		PublicKey:     leaseRequest.PublicKey,
+		NextIpRange:   lease.NextAddress,
+		NodeName:      lease.NodeName,
+		NextPublicKey: lease.NextPublicKey,
+		RotateAt:      lease.RotateAt,
+		Tags:          splitTags(lease.Tags),
+		Static:        lease.Static,
 	}, nil
 }
 
@@ -211,11 +409,17 @@ func (s *SQLWireguardService) ListLeases() ([]*proto.Lease, error) {
 	var protoLeases []*proto.Lease
 	for _, lease := range lease {
 		protoLeases = append(protoLeases, &proto.Lease{
-			Uuid:      lease.UUID,
-			Expires:   lease.Expires,
-			PublicKey: lease.PublicKey,
-			Network:   lease.Parent,
-			IpRange:   lease.Address,
+			Uuid:          lease.UUID,
+			Expires:       lease.Expires,
+			PublicKey:     lease.PublicKey,
+			Network:       lease.Parent,
+			IpRange:       lease.Address,
+			NextIpRange:   lease.NextAddress,
+			NodeName:      lease.NodeName,
+			NextPublicKey: lease.NextPublicKey,
+			RotateAt:      lease.RotateAt,
+			Tags:          splitTags(lease.Tags),
+			Static:        lease.Static,
 		})
 	}
 
@@ -230,18 +434,35 @@ func (s *SQLWireguardService) GetLease(id string) (*proto.Lease, error) {
 	}
 
 	return &proto.Lease{
-		Uuid:      lease.UUID,
-		Expires:   lease.Expires,
-		PublicKey: lease.PublicKey,
-		Network:   lease.Parent,
-		IpRange:   lease.Address,
-		Expired:   lease.Expires-time.Now().Unix() < 0,
+		Uuid:          lease.UUID,
+		Expires:       lease.Expires,
+		PublicKey:     lease.PublicKey,
+		Network:       lease.Parent,
+		IpRange:       lease.Address,
+		Expired:       lease.Expires-time.Now().Unix() < 0,
+		NextIpRange:   lease.NextAddress,
+		NodeName:      lease.NodeName,
+		NextPublicKey: lease.NextPublicKey,
+		RotateAt:      lease.RotateAt,
+		Tags:          splitTags(lease.Tags),
+		Static:        lease.Static,
 	}, nil
 }
 
-func (s *SQLWireguardService) RenewLease(id string) (*proto.Lease, error) {
+// RenewLease extends the lease, and replaces the routes its node advertises
+// and its tags
+func (s *SQLWireguardService) RenewLease(id string, routes []string, tags []string) (*proto.Lease, error) {
+	routes, err := normalizeRoutes(routes)
+	if err != nil {
+		return nil, err
+	}
+	tags, err = normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
 	var lease Lease
-	err := s.db.Where(&Lease{UUID: id}).First(&lease).Error
+	err = s.db.Where(&Lease{UUID: id}).First(&lease).Error
 	if err != nil {
 		if gorm.IsRecordNotFoundError(err) {
 			return &proto.Lease{
@@ -262,30 +483,81 @@ func (s *SQLWireguardService) RenewLease(id string) (*proto.Lease, error) {
 	}
 
 	var subnet SubNetwork
-	err = s.db.Where(&SubNetwork{Address: lease.Address}).First(&subnet).Error
+	err = s.db.Where(&SubNetwork{Address: leaseSubnet(lease.Address), Parent: lease.Parent}).First(&subnet).Error
 	if err != nil {
 		return nil, err
 	}
 
-	expires := time.Now().Unix() + int64(s.leaseDuration.Seconds())
+	var network Network
+	err = s.db.Where(&Network{Name: lease.Parent}).First(&network).Error
+	if err != nil {
+		return nil, err
+	}
+
+	expires := s.leaseExpires(network, lease.Static)
 
 	err = s.db.Model(&subnet).Updates(&SubNetwork{Free: expires}).Error
 	if err != nil {
 		return nil, err
 	}
 
+	if lease.NextAddress != "" {
+		var nextSubnet SubNetwork
+		err = s.db.Where(&SubNetwork{Address: lease.NextAddress, Parent: lease.Parent}).First(&nextSubnet).Error
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.db.Model(&nextSubnet).Updates(&SubNetwork{Free: expires}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = s.db.Model(&lease).Updates(&Lease{Expires: expires}).Error
 	if err != nil {
 		return nil, err
 	}
+	err = s.db.Model(&lease).Update("tags", joinTags(tags)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Once the overlap is over the next key becomes the key of the lease
+	if rotationDue(lease) {
+		next := lease.NextPublicKey
+		err = s.db.Model(&lease).Updates(map[string]interface{}{
+			"public_key":      next,
+			"next_public_key": "",
+			"rotate_at":       0,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Lease %s rotated its key to %s", lease.UUID, next)
+		lease.PublicKey = next
+		lease.NextPublicKey = ""
+		lease.RotateAt = 0
+	}
+
+	err = advertiseRoutes(s.db, lease, routes)
+	if err != nil {
+		return nil, err
+	}
 
 	return &proto.Lease{
-		Uuid:      lease.UUID,
-		Expires:   expires,
-		PublicKey: lease.PublicKey,
-		Network:   lease.Parent,
-		IpRange:   lease.Address,
-		Expired:   false,
+		Uuid:          lease.UUID,
+		Expires:       expires,
+		PublicKey:     lease.PublicKey,
+		Network:       lease.Parent,
+		IpRange:       lease.Address,
+		Expired:       false,
+		NextIpRange:   lease.NextAddress,
+		NodeName:      lease.NodeName,
+		NextPublicKey: lease.NextPublicKey,
+		RotateAt:      lease.RotateAt,
+		Tags:          splitTags(lease.Tags),
+		Static:        lease.Static,
 	}, nil
 }
 
@@ -295,14 +567,130 @@ func (s *SQLWireguardService) DeleteLease(id string) error {
 	if err != nil {
 		return err
 	}
+
+	err = s.db.Where(&PeerReport{LeaseUUID: id}).Delete(&PeerReport{}).Error
+	if err != nil {
+		return err
+	}
+	err = deletePresharedKeys(s.db, id)
+	if err != nil {
+		return err
+	}
+	err = deleteRoutes(s.db, id)
+	if err != nil {
+		return err
+	}
+
+	// The subnets of the other leases are free once they would have
+	// expired, static leases never do
+	if lease.Static {
+		err = s.db.Model(&SubNetwork{}).Where("parent = ? AND address IN (?)", lease.Parent, []string{leaseSubnet(lease.Address), lease.NextAddress}).Updates(map[string]interface{}{"free": 0}).Error
+		if err != nil {
+			return err
+		}
+	}
 	return s.db.Delete(&lease).Error
 }
 
+// ReleaseLease deletes the lease and frees its subnets right away
+// so they can be allocated again
+func (s *SQLWireguardService) ReleaseLease(id string) error {
+	var lease Lease
+	err := s.db.Where(&Lease{UUID: id}).First(&lease).Error
+	if err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	err = tx.Model(&SubNetwork{}).Where("parent = ? AND address IN (?)", lease.Parent, []string{leaseSubnet(lease.Address), lease.NextAddress}).Updates(map[string]interface{}{"free": 0}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Where(&PeerReport{LeaseUUID: id}).Delete(&PeerReport{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = deletePresharedKeys(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = deleteRoutes(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Delete(&lease).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
 func (s *SQLWireguardService) PurgeLeases() error {
+	expired := s.db.Table("lease").Select("lease_uuid").Where("expires < ?", time.Now().Unix()).QueryExpr()
+	err := s.db.Where("lease_uuid IN (?)", expired).Delete(&PeerReport{}).Error
+	if err != nil {
+		return err
+	}
+	err = s.db.Where("lease_a IN (?) OR lease_b IN (?)", expired, expired).Delete(&PresharedKey{}).Error
+	if err != nil {
+		return err
+	}
+	err = s.db.Where("lease_uuid IN (?)", expired).Delete(&Route{}).Error
+	if err != nil {
+		return err
+	}
 	return s.db.Where("expires < ?", time.Now().Unix()).Delete(&Lease{}).Error
 }
 
-func (s *SQLWireguardService) FetchConfiguration(name string) (*proto.ConfigurationResponse, error) {
+// ReportStatus replaces the last report of the lease
+func (s *SQLWireguardService) ReportStatus(id string, peers []*proto.PeerReport) error {
+	var lease Lease
+	err := s.db.Where(&Lease{UUID: id}).First(&lease).Error
+	if err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	err = tx.Where(&PeerReport{LeaseUUID: id}).Delete(&PeerReport{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, peer := range peers {
+		err = tx.Create(&PeerReport{
+			LeaseUUID:    id,
+			PublicKey:    peer.PublicKey,
+			Handshake:    peer.Handshake,
+			HandshakeAge: peer.HandshakeAge,
+			RxBytes:      peer.RxBytes,
+			TxBytes:      peer.TxBytes,
+			Endpoint:     peer.Endpoint,
+			ReportedAt:   now,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// GetNetworkHealth returns what every active lease of the network reported
+// about every other one, pairs that were not reported are included too
+func (s *SQLWireguardService) GetNetworkHealth(name string) (*proto.NetworkHealthResponse, error) {
 	var network Network
 	err := s.db.Where(&Network{Name: name}).First(&network).Error
 	if err != nil {
@@ -315,6 +703,123 @@ func (s *SQLWireguardService) FetchConfiguration(name string) (*proto.Configurat
 		return nil, err
 	}
 
+	var uuids []string
+	for _, lease := range leases {
+		uuids = append(uuids, lease.UUID)
+	}
+
+	var reports []PeerReport
+	err = s.db.Where("lease_uuid IN (?)", uuids).Find(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byLease := make(map[string]map[string]PeerReport)
+	for _, report := range reports {
+		if byLease[report.LeaseUUID] == nil {
+			byLease[report.LeaseUUID] = make(map[string]PeerReport)
+		}
+		byLease[report.LeaseUUID][report.PublicKey] = report
+	}
+
+	now := time.Now().Unix()
+	health := &proto.NetworkHealthResponse{Network: name}
+	for _, lease := range leases {
+		for _, peer := range leases {
+			if peer.UUID == lease.UUID {
+				continue
+			}
+
+			pair := &proto.PeerHealth{
+				LeaseUuid:     lease.UUID,
+				IpRange:       lease.Address,
+				PeerLeaseUuid: peer.UUID,
+				PeerIpRange:   peer.Address,
+			}
+			if report, ok := byLease[lease.UUID][peer.PublicKey]; ok {
+				pair.Reported = true
+				pair.Handshake = report.Handshake
+				pair.RxBytes = report.RxBytes
+				pair.TxBytes = report.TxBytes
+				pair.Endpoint = report.Endpoint
+				pair.ReportedAt = report.ReportedAt
+				if report.Handshake {
+					pair.HandshakeAge = report.HandshakeAge + now - report.ReportedAt
+				}
+			}
+			if !pair.Handshake {
+				health.Unhealthy++
+			}
+			health.Peers = append(health.Peers, pair)
+		}
+	}
+
+	return health, nil
+}
+
+// FetchConfiguration returns the configuration of the network, with the
+// preshared keys the lease shares with its peers if the network uses them
+func (s *SQLWireguardService) FetchConfiguration(name string, leaseUUID string) (*proto.ConfigurationResponse, error) {
+	var network Network
+	err := s.db.Where(&Network{Name: name}).First(&network).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first, agents keep the newest lease of a key held by several
+	var leases []Lease
+	err = s.db.Where("expires > ? AND parent = ?", time.Now().Unix(), name).Order("id").Find(&leases).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The holder now has the next keys of its peers
+	for i, lease := range leases {
+		if lease.UUID != leaseUUID {
+			continue
+		}
+		leases[i].FetchedAt = time.Now().Unix()
+		err = s.db.Model(&lease).Update("fetched_at", leases[i].FetchedAt).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = s.scheduleRotations(leases)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := networkSettings(network)
+	if settings.Mtu == 0 {
+		settings.Mtu = common.DefaultMTU
+	}
+	if settings.PersistentKeepalive == 0 {
+		settings.PersistentKeepalive = common.DefaultPersistentKeepalive
+	}
+	if settings.ListenPort == 0 {
+		settings.ListenPort = common.DefaultListenPort
+	}
+	settings.LeaseDuration = s.networkLeaseDuration(network)
+	settings.DnsDomain = common.NetworkDomain(network.Name, network.DNSDomain)
+
+	routes, exits, err := s.approvedRoutes(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var presharedKeys map[string]string
+	if network.PresharedKeys && leaseUUID != "" {
+		for _, lease := range leases {
+			if lease.UUID != leaseUUID {
+				continue
+			}
+			presharedKeys, err = s.presharedKeys(name, leaseUUID, leases)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	var endpoints []*proto.Endpoint
 	for _, lease := range leases {
 		var peer *proto.PublicPeer
@@ -323,20 +828,368 @@ func (s *SQLWireguardService) FetchConfiguration(name string) (*proto.Configurat
 				Address: *lease.PeerAddress,
 				Port:    lease.PeerPort,
 			}
+			// Peers that did not pick a port listen on the network's
+			if peer.Port == 0 {
+				peer.Port = settings.ListenPort
+			}
 		}
 
-		endpoints = append(endpoints, &proto.Endpoint{
-			Peer:      peer,
-			PublicKey: lease.PublicKey,
-			Networks:  []string{lease.Address},
-		})
+		networks := []string{lease.Address}
+		if lease.NextAddress != "" {
+			networks = append(networks, lease.NextAddress)
+		}
+		networks = append(networks, routes[lease.UUID]...)
+
+		endpoint := &proto.Endpoint{
+			Peer:          peer,
+			PublicKey:     lease.PublicKey,
+			Networks:      networks,
+			NodeName:      lease.NodeName,
+			NextPublicKey: lease.NextPublicKey,
+			RotateAt:      lease.RotateAt,
+			PresharedKey:  presharedKeys[lease.UUID],
+			ExitNode:      exits[lease.UUID],
+		}
+		// The holder may not have renewed since the end of the overlap
+		if rotationDue(lease) {
+			endpoint.PublicKey = lease.NextPublicKey
+			endpoint.NextPublicKey = ""
+			endpoint.RotateAt = 0
+		}
+		endpoints = append(endpoints, endpoint)
 	}
 
 	return &proto.ConfigurationResponse{
 		Network: &proto.NetworkDefinition{
-			Name:      network.Name,
-			Address:   network.Address,
-			Endpoints: endpoints,
+			Name:        network.Name,
+			Address:     network.Address,
+			Endpoints:   endpoints,
+			NextAddress: network.NextAddress,
+			Settings:    settings,
 		},
 	}, nil
 }
+
+func (s *SQLWireguardService) StartRenumber(name string, address string, subnets []string) (*proto.RenumberStatus, error) {
+	var network Network
+	err := s.db.Where(&Network{Name: name}).First(&network).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if network.NextAddress != "" {
+		return nil, fmt.Errorf("network %s is already being renumbered to %s", name, network.NextAddress)
+	}
+
+	var approved []Route
+	err = s.db.Where("parent = ? AND approved = ?", name, true).Find(&approved).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range approved {
+		if !isDefaultRoute(route.Prefix) && overlaps(address, route.Prefix) {
+			return nil, fmt.Errorf("%s overlaps with the route %s of node %s", address, route.Prefix, route.NodeName)
+		}
+	}
+
+	err = s.checkRenumber(network, address, subnets)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+
+	var leases []Lease
+	err = s.db.Where("expires > ? AND parent = ?", now, name).Find(&leases).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Static peers have no agent to move them to their next range, their
+	// wg-quick files would keep the old one
+	var static []string
+	for _, lease := range leases {
+		if lease.Static {
+			static = append(static, lease.NodeName)
+		}
+	}
+	if len(static) != 0 {
+		return nil, fmt.Errorf("network %s has static peers that cannot be renumbered: %s. Delete their leases, add them back once the renumbering is finished and re-export their peer files", name, strings.Join(static, ", "))
+	}
+
+	if len(leases) > len(subnets) {
+		return nil, fmt.Errorf("network %s has %d active leases but %s only has %d subnets", name, len(leases), address, len(subnets))
+	}
+
+	tx := s.db.Begin()
+	for i, sn := range subnets {
+		subnet := SubNetwork{
+			Parent:  name,
+			Address: sn,
+			Pending: true,
+		}
+		// Reserve a subnet for each active lease until it expires
+		if i < len(leases) {
+			subnet.Free = leases[i].Expires
+		}
+		err = tx.Create(&subnet).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	for i, lease := range leases {
+		err = tx.Model(&lease).Updates(map[string]interface{}{
+			"next_address": subnets[i],
+			"renumber_ack": false,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	err = tx.Model(&network).Updates(&Network{NextAddress: address, RenumberStarted: now}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return s.GetRenumberStatus(name)
+}
+
+// checkRenumber makes sure the next range of the network and its subnets
+// do not overlap with anything, subnets are looked up by address
+func (s *SQLWireguardService) checkRenumber(network Network, address string, subnets []string) error {
+	_, next, err := net.ParseCIDR(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %s", address, err)
+	}
+	if overlaps(address, network.Address) {
+		return fmt.Errorf("%s overlaps with the current range %s of network %s", address, network.Address, network.Name)
+	}
+
+	var networks []Network
+	err = s.db.Where("name <> ?", network.Name).Find(&networks).Error
+	if err != nil {
+		return err
+	}
+	for _, other := range networks {
+		for _, r := range []string{other.Address, other.NextAddress} {
+			if r != "" && overlaps(address, r) {
+				return fmt.Errorf("%s overlaps with the range %s of network %s", address, r, other.Name)
+			}
+		}
+	}
+
+	nextOnes, _ := next.Mask.Size()
+	for i, sn := range subnets {
+		_, subnet, err := net.ParseCIDR(sn)
+		if err != nil {
+			return fmt.Errorf("invalid subnet %s: %s", sn, err)
+		}
+		if ones, _ := subnet.Mask.Size(); ones < nextOnes || !next.Contains(subnet.IP) {
+			return fmt.Errorf("subnet %s is not in %s", sn, address)
+		}
+		for _, other := range subnets[:i] {
+			if overlaps(sn, other) {
+				return fmt.Errorf("subnets %s and %s overlap", other, sn)
+			}
+		}
+	}
+
+	return nil
+}
+
+// overlaps tells if two prefixes have addresses in common
+func overlaps(a string, b string) bool {
+	_, an, err := net.ParseCIDR(a)
+	if err != nil {
+		return false
+	}
+	_, bn, err := net.ParseCIDR(b)
+	if err != nil {
+		return false
+	}
+	return an.Contains(bn.IP) || bn.Contains(an.IP)
+}
+
+// AcknowledgeRenumber records that the holder of the lease configured its
+// next range, the renumbering can only finish once every lease did
+func (s *SQLWireguardService) AcknowledgeRenumber(id string, nextAddress string) error {
+	var lease Lease
+	err := s.db.Where(&Lease{UUID: id}).First(&lease).Error
+	if err != nil {
+		return err
+	}
+
+	if lease.NextAddress == "" || lease.NextAddress != nextAddress {
+		return fmt.Errorf("lease %s has no next range %s", id, nextAddress)
+	}
+
+	return s.db.Model(&lease).Updates(map[string]interface{}{"renumber_ack": true}).Error
+}
+
+func (s *SQLWireguardService) GetRenumberStatus(name string) (*proto.RenumberStatus, error) {
+	var network Network
+	err := s.db.Where(&Network{Name: name}).First(&network).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if network.NextAddress == "" {
+		return nil, fmt.Errorf("network %s is not being renumbered", name)
+	}
+
+	var leases []Lease
+	err = s.db.Where("expires > ? AND parent = ?", time.Now().Unix(), name).Find(&leases).Error
+	if err != nil {
+		return nil, err
+	}
+
+	status := &proto.RenumberStatus{
+		Network:     network.Name,
+		Address:     network.Address,
+		NextAddress: network.NextAddress,
+		Started:     network.RenumberStarted,
+	}
+
+	for _, lease := range leases {
+		status.Leases = append(status.Leases, &proto.RenumberedLease{
+			Uuid:         lease.UUID,
+			IpRange:      lease.Address,
+			NextIpRange:  lease.NextAddress,
+			Acknowledged: lease.RenumberAck,
+		})
+		if lease.RenumberAck {
+			status.Acknowledged++
+		}
+		status.Total++
+	}
+
+	return status, nil
+}
+
+func (s *SQLWireguardService) FinishRenumber(name string, force bool) (*proto.RenumberStatus, error) {
+	status, err := s.GetRenumberStatus(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !force && status.Acknowledged < status.Total {
+		return nil, fmt.Errorf("only %d out of %d leases acknowledged their next range", status.Acknowledged, status.Total)
+	}
+
+	tx := s.db.Begin()
+
+	// Leases that never got a next range cannot survive the cutover
+	dropped := tx.Table("lease").Select("lease_uuid").Where("parent = ? AND next_address = ?", name, "").QueryExpr()
+	err = tx.Where("lease_uuid IN (?)", dropped).Delete(&Route{}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Where("lease_uuid IN (?)", dropped).Delete(&PeerReport{}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Where("lease_a IN (?) OR lease_b IN (?)", dropped, dropped).Delete(&PresharedKey{}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Where("parent = ? AND next_address = ?", name, "").Delete(Lease{}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Model(&Lease{}).Where("parent = ?", name).Updates(map[string]interface{}{
+		"address":      gorm.Expr("next_address"),
+		"next_address": "",
+		"renumber_ack": false,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Where("parent = ? AND pending = ?", name, false).Delete(SubNetwork{}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Model(&SubNetwork{}).Where("parent = ?", name).Updates(map[string]interface{}{"pending": false}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Model(&Network{Name: name}).Updates(map[string]interface{}{
+		"address":          status.NextAddress,
+		"next_address":     "",
+		"renumber_started": 0,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (s *SQLWireguardService) AbortRenumber(name string) (*proto.RenumberStatus, error) {
+	status, err := s.GetRenumberStatus(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+
+	err = tx.Where("parent = ? AND pending = ?", name, true).Delete(SubNetwork{}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Model(&Lease{}).Where("parent = ?", name).Updates(map[string]interface{}{
+		"next_address": "",
+		"renumber_ack": false,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Model(&Network{Name: name}).Updates(map[string]interface{}{
+		"next_address":     "",
+		"renumber_started": 0,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return status, nil
+}
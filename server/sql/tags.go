@@ -0,0 +1,38 @@
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// normalizeTags trims, sorts and deduplicates the tags of a node, they are
+// stored comma separated so they cannot hold one
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("invalid tag %q, tags cannot contain a comma", tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func joinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}